@@ -0,0 +1,160 @@
+package alarm
+
+import (
+	"goutil/gb28181"
+	"goutil/gb28181/xml"
+	"strings"
+)
+
+// 报警方式
+const (
+	MethodPhone  = "1"
+	MethodDevice = "2"
+	MethodSMS    = "3"
+	MethodGPS    = "4"
+	MethodVideo  = "5"
+	MethodFault  = "6"
+	MethodOther  = "7"
+)
+
+// 报警方式为 MethodDevice 的报警类型
+const (
+	DeviceTypeVideoLost = "1"
+	DeviceTypeTamper    = "2"
+	DeviceTypeDiskFull  = "3"
+	DeviceTypeHighTemp  = "4"
+	DeviceTypeLowTemp   = "5"
+)
+
+// 报警方式为 MethodVideo 的报警类型
+const (
+	VideoTypeManual        = "1"
+	VideoTypeMotion        = "2"
+	VideoTypeLeftObject    = "3"
+	VideoTypeRemovedObject = "4"
+	VideoTypeTripwire      = "5"
+	VideoTypeIntrusion     = "6"
+	VideoTypeRetrograde    = "7"
+	VideoTypeLoitering     = "8"
+	VideoTypeFlow          = "9"
+	VideoTypeDensity       = "10"
+	VideoTypeAbnormal      = "11"
+	VideoTypeFastMoving    = "12"
+)
+
+// 报警方式为 MethodFault 的报警类型
+const (
+	FaultTypeDisk = "1"
+	FaultTypeFan  = "2"
+)
+
+var (
+	methodNames = map[string]string{
+		MethodPhone:  "电话报警",
+		MethodDevice: "设备报警",
+		MethodSMS:    "短信报警",
+		MethodGPS:    "GPS报警",
+		MethodVideo:  "视频报警",
+		MethodFault:  "设备故障报警",
+		MethodOther:  "其他报警",
+	}
+	typeNames = map[string]map[string]string{
+		MethodDevice: {
+			DeviceTypeVideoLost: "视频丢失报警",
+			DeviceTypeTamper:    "设备防拆报警",
+			DeviceTypeDiskFull:  "存储设备磁盘满报警",
+			DeviceTypeHighTemp:  "设备高温报警",
+			DeviceTypeLowTemp:   "设备低温报警",
+		},
+		MethodVideo: {
+			VideoTypeManual:        "人工视频报警",
+			VideoTypeMotion:        "运动目标检测报警",
+			VideoTypeLeftObject:    "遗留物检测报警",
+			VideoTypeRemovedObject: "物体移除检测报警",
+			VideoTypeTripwire:      "绊线检测报警",
+			VideoTypeIntrusion:     "入侵检测报警",
+			VideoTypeRetrograde:    "逆行检测报警",
+			VideoTypeLoitering:     "徘徊检测报警",
+			VideoTypeFlow:          "流量统计报警",
+			VideoTypeDensity:       "密度检测报警",
+			VideoTypeAbnormal:      "视频异常检测报警",
+			VideoTypeFastMoving:    "快速移动报警",
+		},
+		MethodFault: {
+			FaultTypeDisk: "存储设备磁盘故障报警",
+			FaultTypeFan:  "存储设备风扇故障报警",
+		},
+	}
+)
+
+// MethodName 返回报警方式的名称
+func MethodName(method string) string {
+	return methodNames[method]
+}
+
+// TypeName 返回报警类型的名称，没有类型返回报警方式的名称
+func TypeName(method, typ string) string {
+	if name := typeNames[method][typ]; name != "" {
+		return name
+	}
+	return methodNames[method]
+}
+
+// Alarm 表示一个报警事件
+type Alarm struct {
+	// 发送报警的设备编号
+	FromID string `json:"fromID"`
+	// 报警的设备/通道编号
+	DeviceID string `json:"deviceID"`
+	// 报警级别
+	// 1: 一级警情
+	// 2: 二级警情
+	// 3: 三级警情
+	// 4: 四级警情
+	Priority string `json:"priority"`
+	// 报警方式，MethodXXX
+	Method string `json:"method"`
+	// 报警类型，XXXTypeXXX
+	Type string `json:"type"`
+	// 入侵检测报警的事件类型
+	// 1: 进入区域
+	// 2: 离开区域
+	EventType string `json:"eventType,omitempty"`
+	// 报警时间，国标格式
+	Time string `json:"time"`
+	// 报警时间戳，解析失败是 0
+	Timestamp int64 `json:"timestamp"`
+	// 报警内容描述
+	Description string `json:"description,omitempty"`
+	// 经度
+	Longitude string `json:"longitude,omitempty"`
+	// 纬度
+	Latitude string `json:"latitude,omitempty"`
+	// 来源，MESSAGE/NOTIFY
+	Source string `json:"source"`
+}
+
+// Init 使用 msg 初始化
+func (a *Alarm) Init(fromID, source string, msg *xml.Message) {
+	a.FromID = fromID
+	a.DeviceID = msg.DeviceID
+	a.Priority = msg.AlarmPriority
+	a.Method = msg.AlarmMethod
+	a.Time = msg.AlarmTime
+	a.Timestamp = gb28181.Timestamp(msg.AlarmTime)
+	a.Description = msg.AlarmDescription
+	a.Longitude = msg.Longitude
+	a.Latitude = msg.Latitude
+	a.Source = source
+	if msg.Info != nil {
+		a.Type = msg.Info.AlarmType
+		if msg.Info.AlarmTypeParam != nil {
+			a.EventType = msg.Info.AlarmTypeParam.EventType
+		}
+	}
+}
+
+// key 返回去重用的标识，字段之间用分隔符隔开，避免不同的字段拼接成一样的
+func (a *Alarm) key() string {
+	return strings.Join([]string{a.FromID, a.DeviceID, a.Time, a.Method, a.Type, a.Priority}, "|")
+}
@@ -0,0 +1,165 @@
+package alarm
+
+import (
+	"bytes"
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/log"
+	"goutil/sip"
+	gsync "goutil/sync"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDedupDuration 是默认的去重时间窗口
+	DefaultDedupDuration = time.Minute
+	// resultOK 是 Response-Alarm 的结果
+	resultOK = "OK"
+)
+
+// HandlerOption 是 NewHandler 的参数
+type HandlerOption struct {
+	Ser *sip.Server
+	// 返回发送报警的设备，用于发送 Response-Alarm ，
+	// 返回 nil 表示不处理，不影响调用链后面的函数
+	GetDevice func(ctx *sip.Request, id string) request.Request
+	// 去重的时间窗口，设备可能同时使用 MESSAGE 和 NOTIFY 发送同一个报警，
+	// 默认 DefaultDedupDuration
+	DedupDuration time.Duration
+	// 报警的回调，在协程中执行
+	OnAlarm func(*Alarm)
+	// 通道的缓存大小，大于 0 则报警也会写入通道，满了就丢弃
+	ChanSize int
+}
+
+// Handler 处理设备的报警通知，
+// 自动响应 200 ，MESSAGE 的报警还会发送 Response-Alarm ，
+// 去重后通过回调和通道交给应用
+type Handler struct {
+	opt HandlerOption
+	// 通道
+	c *gsync.Chan[*Alarm]
+	// 去重
+	dedupLock sync.Mutex
+	dedup     map[string]time.Time
+	sweepTime time.Time
+}
+
+// NewHandler 返回新的 Handler
+func NewHandler(opt *HandlerOption) *Handler {
+	h := new(Handler)
+	h.opt = *opt
+	if h.opt.DedupDuration <= 0 {
+		h.opt.DedupDuration = DefaultDedupDuration
+	}
+	if h.opt.ChanSize > 0 {
+		h.c = gsync.NewChan[*Alarm](h.opt.ChanSize)
+	}
+	h.dedup = make(map[string]time.Time)
+	h.sweepTime = time.Now()
+	return h
+}
+
+// C 返回报警的通道，ChanSize 为 0 时返回 nil
+func (h *Handler) C() <-chan *Alarm {
+	if h.c == nil {
+		return nil
+	}
+	return h.c.C
+}
+
+// Close 关闭通道
+func (h *Handler) Close() {
+	if h.c != nil {
+		h.c.Close()
+	}
+}
+
+// HandleMessage 处理 MESSAGE 的 Notify-Alarm ，用于 sip.Server.RequestFunc
+func (h *Handler) HandleMessage(ctx *sip.Request) {
+	h.handle(ctx)
+}
+
+// HandleNotify 处理订阅后的 NOTIFY 报警，用于 sip.Server.RequestFunc
+func (h *Handler) HandleNotify(ctx *sip.Request) {
+	h.handle(ctx)
+}
+
+// handle 处理报警，不是报警直接返回
+func (h *Handler) handle(ctx *sip.Request) {
+	dev := h.opt.GetDevice(ctx, ctx.Header.From.URI.Name)
+	if dev == nil {
+		return
+	}
+	// 解析，不能影响后面的函数读取
+	var msg xml.Message
+	if err := xml.Decode(bytes.NewReader(ctx.Body.Bytes()), &msg); err != nil {
+		return
+	}
+	if msg.XMLName.Local != xml.TypeNotify || msg.CmdType != xml.CmdAlarm {
+		return
+	}
+	// 响应
+	ctx.ResponseStatus(sip.StatusOK)
+	// 报警
+	a := new(Alarm)
+	a.Init(dev.GetToID(), ctx.Header.CSeq.Method, &msg)
+	go h.routine(ctx.Trace(), dev, &msg, a)
+}
+
+// routine 在协程中通知，MESSAGE 的报警需要应答，
+// 订阅的 NOTIFY 只需要响应 200
+func (h *Handler) routine(trace string, dev request.Request, msg *xml.Message, a *Alarm) {
+	defer func() {
+		log.Recover(recover())
+	}()
+	// 去重
+	if !h.isDuplicate(a) {
+		// 通知
+		if h.opt.OnAlarm != nil {
+			h.opt.OnAlarm(a)
+		}
+		if h.c != nil {
+			h.c.Send(a)
+		}
+	}
+	if a.Source != sip.MethodMessage {
+		return
+	}
+	// 应答
+	var body xml.Message
+	body.XMLName.Local = xml.TypeResponse
+	body.CmdType = xml.CmdAlarm
+	body.SN = msg.SN
+	body.DeviceID = msg.DeviceID
+	body.Result = resultOK
+	if err := request.SendMessage(context.Background(), trace, h.opt.Ser, dev, &body, nil); err != nil {
+		log.Errorf(-1, trace, 0, "alarm response %s %v", dev.GetToID(), err)
+	}
+}
+
+// isDuplicate 返回 a 是否在时间窗口内重复
+func (h *Handler) isDuplicate(a *Alarm) bool {
+	now := time.Now()
+	key := a.key()
+	//
+	h.dedupLock.Lock()
+	defer h.dedupLock.Unlock()
+	// 清理过期的
+	if now.Sub(h.sweepTime) >= h.opt.DedupDuration {
+		for k, t := range h.dedup {
+			if now.After(t) {
+				delete(h.dedup, k)
+			}
+		}
+		h.sweepTime = now
+	}
+	// 检查
+	if t, ok := h.dedup[key]; ok && now.Before(t) {
+		return true
+	}
+	h.dedup[key] = now.Add(h.opt.DedupDuration)
+	return false
+}
@@ -0,0 +1,53 @@
+package alarm
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	subscribe "goutil/gb28181/request/subscribe/query"
+	"goutil/gb28181/subscription"
+	"time"
+)
+
+const (
+	// DefaultExpire 是默认的订阅有效期，单位秒
+	DefaultExpire = 3600
+	// DefaultRetryInterval 是订阅失败后重试的间隔
+	DefaultRetryInterval = subscription.DefaultRetryInterval
+)
+
+// Subscriber 维护设备的报警订阅，在过期之前刷新，
+// 需要使用 request.HandleDialogResponse 处理 SUBSCRIBE 的响应
+type Subscriber struct {
+	r *subscription.Refresher
+}
+
+// NewSubscriber 返回新的 Subscriber ，retry 是订阅失败后重试的间隔，
+// 默认 DefaultRetryInterval
+func NewSubscriber(retry time.Duration) *Subscriber {
+	return &Subscriber{r: subscription.NewRefresher(retry)}
+}
+
+// Subscribe 订阅，启动协程在过期之前刷新，已经存在则替换，
+// m.Expire 默认 DefaultExpire
+func (s *Subscriber) Subscribe(m *subscribe.Alarm) {
+	if m.Expire <= 0 {
+		m.Expire = DefaultExpire
+	}
+	s.r.Subscribe(m.Device.GetToID(), m.Expire, m.TraceID, func(ctx context.Context, dlg *request.Dialog, expire int64) error {
+		mm := *m
+		mm.Expire = expire
+		mm.Dialog = dlg
+		_, err := subscribe.SendAlarm(ctx, &mm)
+		return err
+	})
+}
+
+// Unsubscribe 取消订阅，会阻塞等待设备的响应
+func (s *Subscriber) Unsubscribe(ctx context.Context, deviceID string) error {
+	return s.r.Unsubscribe(ctx, deviceID)
+}
+
+// Has 返回是否订阅了设备
+func (s *Subscriber) Has(deviceID string) bool {
+	return s.r.Has(deviceID)
+}
@@ -0,0 +1,216 @@
+package cascade
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/request/message/notify"
+	"goutil/gb28181/request/message/response"
+	"goutil/gb28181/request/register"
+	"goutil/gb28181/xml"
+	"goutil/log"
+	"goutil/sdp"
+	"goutil/sip"
+	gsync "goutil/sync"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 默认值
+const (
+	DefaultExpires           = 3600
+	DefaultKeepaliveInterval = time.Minute
+	DefaultKeepaliveTimeout  = 3
+)
+
+// Platform 表示上级平台，
+// GetFromID 是本级的编号，GetToID 是上级的编号
+type Platform interface {
+	request.Request
+	// 注册的密码
+	GetPassword() string
+}
+
+// Relay 用于把上级的请求转发给下级的设备
+type Relay interface {
+	// Invite 处理上级的点播，offer 是上级的 sdp ，返回应答的 sdp
+	Invite(ctx *sip.Request, p Platform, channelID string, offer *sdp.Session) (*sdp.Session, error)
+	// Bye 结束点播
+	Bye(ctx *sip.Request, p Platform, channelID, callID string) error
+	// Info 转发回放控制，body 是 MANSRTSP
+	Info(ctx *sip.Request, p Platform, channelID, callID string, body []byte) error
+	// Control 转发设备控制，比如 PTZ/录像/布防
+	Control(ctx context.Context, p Platform, msg *xml.Message) error
+}
+
+// Option 是 NewCascade 的参数
+type Option struct {
+	Ser *sip.Server
+	// 注册的有效期，单位秒，默认 DefaultExpires
+	Expires int64
+	// 心跳间隔，默认 DefaultKeepaliveInterval
+	KeepaliveInterval time.Duration
+	// 心跳连续失败的次数，超过则重新注册，默认 DefaultKeepaliveTimeout
+	KeepaliveTimeout int
+	// 返回推送给上级的目录
+	Catalog func(ctx context.Context, p Platform) ([]*xml.Device, error)
+	// 返回设备信息，只需要设置 Manufacturer/Model/Firmware/Result
+	DeviceInfo func(ctx context.Context, p Platform, deviceID string) (*response.DeviceInfo, error)
+	// 返回录像查询的结果
+	RecordInfo func(ctx context.Context, p Platform, msg *xml.Message) ([]*xml.Record, error)
+	// 转发
+	Relay Relay
+	// 上级在线状态变化的回调
+	OnStatus func(p Platform, online bool)
+}
+
+// Cascade 用于把本级平台注册到上级平台，并处理上级的请求
+type Cascade struct {
+	opt Option
+	// 上级平台，key 是上级的编号
+	platforms gsync.Map[string, *platform]
+	// 上级点播的会话，key 是 Call-ID
+	sessions gsync.Map[string, *session]
+	// 上级的订阅，key 是 Call-ID
+	subscriptions gsync.Map[string, *Subscription]
+}
+
+// platform 封装上级平台的状态
+type platform struct {
+	Platform
+	// 是否在线
+	online int32
+	// 注册，刷新和注销复用同一个 Call-ID
+	regLock sync.Mutex
+	reg     register.Register
+	// 用于停止
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// session 表示上级的点播
+type session struct {
+	p         *platform
+	channelID string
+}
+
+// NewCascade 返回新的 Cascade
+func NewCascade(opt *Option) *Cascade {
+	c := new(Cascade)
+	c.opt = *opt
+	if c.opt.Expires <= 0 {
+		c.opt.Expires = DefaultExpires
+	}
+	if c.opt.KeepaliveInterval <= 0 {
+		c.opt.KeepaliveInterval = DefaultKeepaliveInterval
+	}
+	if c.opt.KeepaliveTimeout <= 0 {
+		c.opt.KeepaliveTimeout = DefaultKeepaliveTimeout
+	}
+	c.platforms.Init()
+	c.sessions.Init()
+	c.subscriptions.Init()
+	return c
+}
+
+// Add 添加上级平台，启动协程注册和心跳，已存在则先删除
+func (c *Cascade) Add(p Platform) {
+	c.Del(p.GetToID())
+	//
+	pp := &platform{Platform: p}
+	pp.ctx, pp.cancel = context.WithCancel(context.Background())
+	c.platforms.Set(p.GetToID(), pp)
+	go c.routine(pp)
+}
+
+// Del 删除上级平台，会阻塞等待注销
+func (c *Cascade) Del(id string) {
+	p := c.platforms.Take(id)
+	if p == nil {
+		return
+	}
+	p.cancel()
+	if atomic.LoadInt32(&p.online) == 1 {
+		c.register(context.Background(), p, 0)
+	}
+}
+
+// IsOnline 返回上级平台是否在线
+func (c *Cascade) IsOnline(id string) bool {
+	p := c.platforms.Get(id)
+	return p != nil && atomic.LoadInt32(&p.online) == 1
+}
+
+// setOnline 设置在线状态，变化时回调
+func (c *Cascade) setOnline(p *platform, online bool) {
+	var n int32
+	if online {
+		n = 1
+	}
+	if atomic.SwapInt32(&p.online, n) != n && c.opt.OnStatus != nil {
+		c.opt.OnStatus(p.Platform, online)
+	}
+}
+
+// routine 在协程中注册和心跳
+func (c *Cascade) routine(p *platform) {
+	defer func() {
+		log.Recover(recover())
+		c.setOnline(p, false)
+	}()
+	var registerTime time.Time
+	keepaliveTimeout := 0
+	refresh := time.Duration(c.opt.Expires) * time.Second * 2 / 3
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		timer.Reset(c.opt.KeepaliveInterval)
+		// 不在线或者快要过期了，注册
+		if atomic.LoadInt32(&p.online) == 0 || time.Since(registerTime) >= refresh {
+			if err := c.register(p.ctx, p, c.opt.Expires); err != nil {
+				log.Errorf(-1, "", 0, "cascade %s register %v", p.GetToID(), err)
+				c.setOnline(p, false)
+				continue
+			}
+			registerTime = time.Now()
+			keepaliveTimeout = 0
+			c.setOnline(p, true)
+			continue
+		}
+		// 心跳
+		err := notify.SendKeepalive(p.ctx, &notify.Keepalive{
+			Ser:     c.opt.Ser,
+			Cascade: p.Platform,
+		})
+		if err == nil {
+			keepaliveTimeout = 0
+			continue
+		}
+		keepaliveTimeout++
+		if keepaliveTimeout >= c.opt.KeepaliveTimeout {
+			c.setOnline(p, false)
+			timer.Reset(0)
+		}
+	}
+}
+
+// register 注册，expires 为 0 表示注销
+func (c *Cascade) register(ctx context.Context, p *platform, expires int64) error {
+	p.regLock.Lock()
+	defer p.regLock.Unlock()
+	p.reg.Ser = c.opt.Ser
+	p.reg.Cascade = p.Platform
+	p.reg.Expires = strconv.FormatInt(expires, 10)
+	return register.Login(ctx, &p.reg, p.GetPassword())
+}
+
+// getPlatform 返回请求来自的上级平台
+func (c *Cascade) getPlatform(ctx *sip.Request) *platform {
+	return c.platforms.Get(ctx.Header.From.URI.Name)
+}
@@ -0,0 +1,82 @@
+package cascade
+
+import (
+	"bytes"
+	"goutil/gb28181/request"
+	"goutil/log"
+	"goutil/sdp"
+	"goutil/sip"
+)
+
+// HandleInvite 处理上级的点播，用于 sip.Server.RequestFunc ，
+// 不是上级平台的请求直接返回
+func (c *Cascade) HandleInvite(ctx *sip.Request) {
+	p := c.getPlatform(ctx)
+	if p == nil {
+		return
+	}
+	if c.opt.Relay == nil {
+		ctx.ResponseStatus(sip.StatusNotImplemented)
+		return
+	}
+	// sdp
+	var offer sdp.Session
+	if err := offer.ParseFrom(bytes.NewReader(ctx.Body.Bytes())); err != nil {
+		ctx.ResponseStatus(sip.StatusBadRequest)
+		return
+	}
+	// 转发
+	channelID := ctx.Header.To.URI.Name
+	answer, err := c.opt.Relay.Invite(ctx, p.Platform, channelID, &offer)
+	if err != nil {
+		log.Errorf(-1, ctx.Trace(), 0, "cascade %s invite %s %v", p.GetToID(), channelID, err)
+		ctx.ResponseError(err)
+		return
+	}
+	// 会话
+	c.sessions.Set(ctx.Header.CallID, &session{p: p, channelID: channelID})
+	// 响应
+	msg := ctx.NewResponse(sip.StatusOK, sip.StatusPhrase(sip.StatusOK))
+	msg.Header.ResetOther()
+	msg.Header.ContentType = request.ContentTypeSDP
+	msg.Header.Contact.Scheme = sip.SIP
+	msg.Header.Contact.Name = channelID
+	msg.Header.Contact.Domain = p.GetContactAddress()
+	answer.FormatTo(&msg.Body)
+	ctx.Response(msg)
+}
+
+// HandleAck 处理上级的 ACK ，用于 sip.Server.RequestFunc
+func (c *Cascade) HandleAck(ctx *sip.Request) {
+	if c.sessions.Has(ctx.Header.CallID) {
+		ctx.Response(nil)
+	}
+}
+
+// HandleBye 处理上级的 BYE ，用于 sip.Server.RequestFunc ，
+// 不是上级点播的会话直接返回
+func (c *Cascade) HandleBye(ctx *sip.Request) {
+	s := c.sessions.Take(ctx.Header.CallID)
+	if s == nil {
+		return
+	}
+	if err := c.opt.Relay.Bye(ctx, s.p.Platform, s.channelID, ctx.Header.CallID); err != nil {
+		log.Errorf(-1, ctx.Trace(), 0, "cascade %s bye %s %v", s.p.GetToID(), s.channelID, err)
+	}
+	ctx.ResponseStatus(sip.StatusOK)
+}
+
+// HandleInfo 处理上级的回放控制，用于 sip.Server.RequestFunc ，
+// 不是上级点播的会话直接返回
+func (c *Cascade) HandleInfo(ctx *sip.Request) {
+	s := c.sessions.Get(ctx.Header.CallID)
+	if s == nil {
+		return
+	}
+	if err := c.opt.Relay.Info(ctx, s.p.Platform, s.channelID, ctx.Header.CallID, ctx.Body.Bytes()); err != nil {
+		log.Errorf(-1, ctx.Trace(), 0, "cascade %s info %s %v", s.p.GetToID(), s.channelID, err)
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseStatus(sip.StatusOK)
+}
@@ -0,0 +1,139 @@
+package cascade
+
+import (
+	"bytes"
+	"context"
+	"goutil/gb28181/request/message/response"
+	"goutil/gb28181/xml"
+	"goutil/log"
+	"goutil/sip"
+)
+
+// 设备控制的应答结果
+const (
+	ResultOK    = "OK"
+	ResultError = "ERROR"
+)
+
+// HandleMessage 处理上级的 MESSAGE 请求，用于 sip.Server.RequestFunc ，
+// 不是上级平台的请求直接返回，不影响调用链后面的函数
+func (c *Cascade) HandleMessage(ctx *sip.Request) {
+	p := c.getPlatform(ctx)
+	if p == nil {
+		return
+	}
+	// 解析，不能影响后面的函数读取
+	var msg xml.Message
+	if err := xml.Decode(bytes.NewReader(ctx.Body.Bytes()), &msg); err != nil {
+		ctx.ResponseStatus(sip.StatusBadRequest)
+		return
+	}
+	// 先响应，结果通过新的 MESSAGE 返回
+	ctx.ResponseStatus(sip.StatusOK)
+	trace := ctx.Trace()
+	switch msg.XMLName.Local {
+	case xml.TypeQuery:
+		go c.handleQuery(trace, p, &msg)
+	case xml.TypeControl:
+		go c.handleControl(trace, p, &msg)
+	}
+}
+
+// handleQuery 在协程中应答上级的查询
+func (c *Cascade) handleQuery(trace string, p *platform, msg *xml.Message) {
+	defer func() {
+		log.Recover(recover())
+	}()
+	var err error
+	switch msg.CmdType {
+	case xml.CmdCatalog:
+		err = c.handleQueryCatalog(trace, p, msg)
+	case xml.CmdDeviceInfo:
+		err = c.handleQueryDeviceInfo(trace, p, msg)
+	case xml.CmdRecordInfo:
+		err = c.handleQueryRecordInfo(trace, p, msg)
+	default:
+		return
+	}
+	if err != nil {
+		log.Errorf(-1, trace, 0, "cascade %s query %s %v", p.GetToID(), msg.CmdType, err)
+	}
+}
+
+// handleQueryCatalog 应答目录查询
+func (c *Cascade) handleQueryCatalog(trace string, p *platform, msg *xml.Message) error {
+	if c.opt.Catalog == nil {
+		return nil
+	}
+	items, err := c.opt.Catalog(p.ctx, p.Platform)
+	if err != nil {
+		return err
+	}
+	return response.SendCatalog(p.ctx, &response.Catalog{
+		Ser:      c.opt.Ser,
+		Cascade:  p.Platform,
+		SN:       msg.SN,
+		DeviceID: msg.DeviceID,
+		Items:    items,
+		TraceID:  trace,
+	})
+}
+
+// handleQueryDeviceInfo 应答设备信息查询
+func (c *Cascade) handleQueryDeviceInfo(trace string, p *platform, msg *xml.Message) error {
+	if c.opt.DeviceInfo == nil {
+		return nil
+	}
+	m, err := c.opt.DeviceInfo(p.ctx, p.Platform, msg.DeviceID)
+	if err != nil {
+		return err
+	}
+	m.Ser = c.opt.Ser
+	m.Cascade = p.Platform
+	m.SN = msg.SN
+	m.DeviceID = msg.DeviceID
+	m.TraceID = trace
+	if m.Result == "" {
+		m.Result = ResultOK
+	}
+	return response.SendDeviceInfo(p.ctx, m)
+}
+
+// handleQueryRecordInfo 应答录像查询
+func (c *Cascade) handleQueryRecordInfo(trace string, p *platform, msg *xml.Message) error {
+	if c.opt.RecordInfo == nil {
+		return nil
+	}
+	items, err := c.opt.RecordInfo(p.ctx, p.Platform, msg)
+	if err != nil {
+		return err
+	}
+	return response.SendRecordInfo(p.ctx, &response.RecordInfo{
+		Ser:      c.opt.Ser,
+		Cascade:  p.Platform,
+		SN:       msg.SN,
+		DeviceID: msg.DeviceID,
+		Items:    items,
+		TraceID:  trace,
+	})
+}
+
+// handleControl 在协程中转发上级的控制
+func (c *Cascade) handleControl(trace string, p *platform, msg *xml.Message) {
+	defer func() {
+		log.Recover(recover())
+	}()
+	if c.opt.Relay == nil {
+		return
+	}
+	result := ResultOK
+	if err := c.opt.Relay.Control(context.Background(), p.Platform, msg); err != nil {
+		log.Errorf(-1, trace, 0, "cascade %s control %s %v", p.GetToID(), msg.DeviceID, err)
+		result = ResultError
+	}
+	// PTZ 不需要应答
+	if msg.PTZCmd != "" {
+		return
+	}
+	response.SendResult(p.ctx, trace, c.opt.Ser, p.Platform, msg, result)
+}
@@ -0,0 +1,182 @@
+package cascade
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 消息头字段名称
+const (
+	StrEvent             = "Event"
+	StrSubscriptionState = "Subscription-State"
+)
+
+// Subscription 表示上级的订阅，保存之后不会再修改，
+// 刷新的时候替换成新的
+type Subscription struct {
+	// 上级的编号
+	PlatformID string
+	// 订阅的类型，Catalog/Alarm/MobilePosition
+	CmdType string
+	// 订阅的设备
+	DeviceID string
+	// 订阅的参数
+	Body *xml.Subscribe
+	// 过期时间
+	Deadline time.Time
+	// 对话
+	CallID  string
+	FromTag string
+	ToTag   string
+	Event   string
+	p       *platform
+	// 发送 NOTIFY 的 CSeq ，刷新之后共用
+	cseq *int64
+}
+
+// HandleSubscribe 处理上级的订阅，用于 sip.Server.RequestFunc ，
+// 不是上级平台的请求直接返回，Expires 为 0 表示取消订阅
+func (c *Cascade) HandleSubscribe(ctx *sip.Request) {
+	p := c.getPlatform(ctx)
+	if p == nil {
+		return
+	}
+	// 解析
+	var body xml.Subscribe
+	if err := xml.Decode(bytes.NewReader(ctx.Body.Bytes()), &body); err != nil {
+		ctx.ResponseStatus(sip.StatusBadRequest)
+		return
+	}
+	expires, err := strconv.ParseInt(ctx.Header.Expires, 10, 64)
+	if err != nil || expires < 0 {
+		ctx.ResponseStatus(sip.StatusBadRequest)
+		return
+	}
+	// 响应
+	msg := ctx.NewResponse(sip.StatusOK, sip.StatusPhrase(sip.StatusOK))
+	msg.Header.ResetOther()
+	msg.Header.ContentType = request.ContentTypeXML
+	var res xml.Message
+	res.XMLName.Local = xml.TypeResponse
+	res.CmdType = body.CmdType
+	res.SN = body.SN
+	res.DeviceID = body.DeviceID
+	res.Result = ResultOK
+	xml.Encode(&msg.Body, p.GetXMLEncoding(), &res)
+	// 取消
+	callID := ctx.Header.CallID
+	if expires == 0 {
+		c.subscriptions.Del(callID)
+		ctx.Response(msg)
+		return
+	}
+	// 保存，刷新的时候是同一个对话，复制一份再修改，
+	// 不影响正在使用旧的订阅发送 NOTIFY
+	s := new(Subscription)
+	if old := c.subscriptions.Get(callID); old != nil {
+		*s = *old
+	} else {
+		s.PlatformID = p.GetToID()
+		s.CallID = callID
+		s.FromTag = ctx.Header.From.Tag
+		s.ToTag = msg.Header.To.Tag
+		s.p = p
+		s.cseq = new(int64)
+	}
+	s.CmdType = body.CmdType
+	s.DeviceID = body.DeviceID
+	s.Body = &body
+	s.Event = ctx.Header.Get(strings.ToUpper(StrEvent))
+	s.Deadline = time.Now().Add(time.Duration(expires) * time.Second)
+	c.subscriptions.Set(callID, s)
+	//
+	ctx.Response(msg)
+}
+
+// Subscriptions 返回 cmdType 所有有效的订阅
+func (c *Cascade) Subscriptions(cmdType string) []*Subscription {
+	now := time.Now()
+	var expired []string
+	ss := c.subscriptions.Search(func(s *Subscription) bool {
+		if now.After(s.Deadline) || !c.platforms.Has(s.PlatformID) {
+			expired = append(expired, s.CallID)
+			return false
+		}
+		return s.CmdType == cmdType
+	})
+	c.subscriptions.BatchDel(expired)
+	return ss
+}
+
+// Notify 向订阅了 body.CmdType 的上级发送 NOTIFY ，
+// body 的 SN 和 DeviceID 没有设置的话，使用订阅的
+func (c *Cascade) Notify(ctx context.Context, trace string, body *xml.Notify) error {
+	var err error
+	for _, s := range c.Subscriptions(body.CmdType) {
+		b := *body
+		if b.SN == "" {
+			b.SN = sip.GetSNString()
+		}
+		if b.DeviceID == "" {
+			b.DeviceID = s.DeviceID
+		}
+		if e := c.notify(ctx, trace, s, &b); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// NotifyCatalog 向订阅了目录的上级发送目录变化，event 是 xml.NotifyEventXXX
+func (c *Cascade) NotifyCatalog(ctx context.Context, trace string, event string, items []*xml.Device) error {
+	var body xml.Notify
+	body.XMLName.Local = xml.TypeNotify
+	body.CmdType = xml.CmdCatalog
+	body.SumNum = int64(len(items))
+	body.DeviceList = new(xml.NotifyDeviceList)
+	// 一条一条的发送
+	body.DeviceList.Num = 1
+	body.DeviceList.Item = make([]*xml.NotifyDeviceListItem, 1)
+	for _, item := range items {
+		body.DeviceList.Item[0] = &xml.NotifyDeviceListItem{Device: *item, Event: event}
+		body.SN = ""
+		if err := c.Notify(ctx, trace, &body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notify 发送 NOTIFY
+func (c *Cascade) notify(ctx context.Context, trace string, s *Subscription, body *xml.Notify) error {
+	// 地址
+	addr, err := s.p.GetNetAddr()
+	if err != nil {
+		return err
+	}
+	// 消息，使用订阅的对话
+	msg := request.New(s.p, addr.Network(), "", sip.MethodNotify, request.ContentTypeXML)
+	msg.Header.CallID = s.CallID
+	msg.Header.From.Tag = s.ToTag
+	msg.Header.To.Tag = s.FromTag
+	msg.Header.CSeq.SN = strconv.FormatInt(atomic.AddInt64(s.cseq, 1), 10)
+	if s.Event != "" {
+		msg.Header.Set(StrEvent, s.Event)
+	}
+	expires := int64(time.Until(s.Deadline) / time.Second)
+	if expires < 0 {
+		expires = 0
+	}
+	msg.Header.Set(StrSubscriptionState, fmt.Sprintf("active;expires=%d", expires))
+	xml.Encode(&msg.Body, s.p.GetXMLEncoding(), body)
+	//
+	return c.opt.Ser.RequestWithContext(ctx, trace, msg, addr, s)
+}
@@ -0,0 +1,306 @@
+package catalog
+
+import (
+	"goutil/gb28181/xml"
+	gsync "goutil/sync"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 节点的类型
+const (
+	// 行政区域，编号是 2/4/6/8 位
+	KindCivil = "civil"
+	// 设备，也就是树的根
+	KindDevice = "device"
+	// 系统，类型码 200
+	KindSystem = "system"
+	// 业务分组，类型码 215
+	KindBusinessGroup = "businessGroup"
+	// 虚拟组织，类型码 216
+	KindVirtualOrg = "virtualOrg"
+	// 通道
+	KindChannel = "channel"
+)
+
+// ParentID 的多个编号的分隔符
+const parentIDSep = "/"
+
+// Kind 根据编号返回节点的类型
+func Kind(id string) string {
+	switch len(id) {
+	case 2, 4, 6, 8:
+		return KindCivil
+	case 20:
+		// 第 11~13 位是类型码
+		switch id[10:13] {
+		case "200":
+			return KindSystem
+		case "215":
+			return KindBusinessGroup
+		case "216":
+			return KindVirtualOrg
+		}
+	}
+	return KindChannel
+}
+
+// Node 是目录树的节点
+type Node struct {
+	*xml.Device
+	// 类型
+	Kind string
+	// 最后一次通知的事件
+	Event string
+	// 子节点
+	Children []*Node
+	// 计算出来的父节点
+	pid string
+}
+
+// 实现 gsync.TreeNode 接口
+func (n *Node) GetID() string {
+	return n.DeviceID
+}
+
+// 实现 gsync.TreeNode 接口
+func (n *Node) GetPID() string {
+	return n.pid
+}
+
+// 实现 gsync.TreeNode 接口
+func (n *Node) AddChild(c gsync.TreeNode[string]) {
+	n.Children = append(n.Children, c.(*Node))
+}
+
+// 实现 gsync.TreeNode 接口
+func (n *Node) GetChild() []gsync.TreeNode[string] {
+	cs := make([]gsync.TreeNode[string], 0, len(n.Children))
+	for _, c := range n.Children {
+		cs = append(cs, c)
+	}
+	return cs
+}
+
+// Tree 表示一个设备的目录树，
+// 行政区域/业务分组/虚拟组织作为目录，通道作为叶子
+type Tree struct {
+	lock sync.RWMutex
+	// 根节点，就是设备
+	root *Node
+	// 所有的节点，不包括根
+	nodes map[string]*Node
+}
+
+// NewTree 返回新的目录树，deviceID 是根节点
+func NewTree(deviceID string) *Tree {
+	t := new(Tree)
+	t.root = &Node{Device: &xml.Device{DeviceID: deviceID}, Kind: KindDevice}
+	t.nodes = make(map[string]*Node)
+	return t
+}
+
+// Reset 使用 items 重新构建
+func (t *Tree) Reset(items []*xml.Device) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	//
+	t.nodes = make(map[string]*Node)
+	for _, item := range items {
+		t.set(item)
+	}
+	t.build()
+}
+
+// Add 添加或者更新，然后重新构建
+func (t *Tree) Add(items ...*xml.Device) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	//
+	for _, item := range items {
+		t.set(item)
+	}
+	t.build()
+}
+
+// Del 删除，然后重新构建，目录下的节点会挂到其他能找到的父节点上
+func (t *Tree) Del(ids ...string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	//
+	for _, id := range ids {
+		delete(t.nodes, id)
+	}
+	t.build()
+}
+
+// SetStatus 设置状态，不需要重新构建
+func (t *Tree) SetStatus(status string, ids ...string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	//
+	for _, id := range ids {
+		if n := t.nodes[id]; n != nil {
+			n.Status = status
+		}
+	}
+}
+
+// Apply 应用 Notify-Catalog 的变更，返回是否有变更
+func (t *Tree) Apply(m *xml.Notify) bool {
+	if m.DeviceList == nil || len(m.DeviceList.Item) < 1 {
+		return false
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	//
+	rebuild, changed := false, false
+	for _, item := range m.DeviceList.Item {
+		if item.DeviceID == "" {
+			continue
+		}
+		switch item.Event {
+		case xml.NotifyEventADD, xml.NotifyEventUPDATE:
+			t.set(&item.Device)
+			rebuild = true
+		case xml.NotifyEventDEL:
+			delete(t.nodes, item.DeviceID)
+			rebuild = true
+		case xml.NotifyEventON:
+			changed = t.setEvent(item.DeviceID, item.Event, xml.NotifyEventON) || changed
+		case xml.NotifyEventOFF, xml.NotifyEventVLOST, xml.NotifyEventDEFECT:
+			changed = t.setEvent(item.DeviceID, item.Event, xml.NotifyEventOFF) || changed
+		}
+	}
+	if rebuild {
+		t.build()
+	}
+	return rebuild || changed
+}
+
+// setEvent 设置事件和状态
+func (t *Tree) setEvent(id, event, status string) bool {
+	n := t.nodes[id]
+	if n == nil {
+		return false
+	}
+	n.Event = event
+	n.Status = status
+	return true
+}
+
+// set 添加或者替换，保存的是 d 的副本
+func (t *Tree) set(d *xml.Device) {
+	if d.DeviceID == "" || d.DeviceID == t.root.DeviceID {
+		return
+	}
+	n := t.nodes[d.DeviceID]
+	if n == nil {
+		n = new(Node)
+		n.Kind = Kind(d.DeviceID)
+		t.nodes[d.DeviceID] = n
+	}
+	c := *d
+	n.Device = &c
+}
+
+// build 重新计算父节点并构建
+func (t *Tree) build() {
+	ns := make([]gsync.TreeNode[string], 0, len(t.nodes))
+	for _, n := range t.nodes {
+		n.Children = nil
+		n.pid = t.parentOf(n)
+		ns = append(ns, n)
+	}
+	t.root.Children = nil
+	gsync.InitTree[string](t.root, ns)
+	// 排序，保证每次的顺序一样
+	walk(t.root, 0, func(n *Node, depth int) bool {
+		sort.Slice(n.Children, func(i, j int) bool {
+			return n.Children[i].DeviceID < n.Children[j].DeviceID
+		})
+		return true
+	})
+}
+
+// parentOf 返回 n 的父节点
+func (t *Tree) parentOf(n *Node) string {
+	// ParentID 可能是 a/b 的格式，从后往前找
+	ids := strings.Split(n.ParentID, parentIDSep)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ids[i] != n.DeviceID && t.nodes[ids[i]] != nil {
+			return ids[i]
+		}
+	}
+	// 虚拟组织，挂在业务分组下
+	if n.Info != nil && n.Info.BusinessGroupID != "" && t.nodes[n.Info.BusinessGroupID] != nil {
+		return n.Info.BusinessGroupID
+	}
+	// 行政区域，挂在上一级
+	code := n.CivilCode
+	if n.Kind == KindCivil {
+		code = n.DeviceID[:len(n.DeviceID)-2]
+	}
+	for len(code) >= 2 {
+		if code != n.DeviceID && t.nodes[code] != nil {
+			return code
+		}
+		code = code[:len(code)-2]
+	}
+	// 找不到就挂在根下
+	return t.root.DeviceID
+}
+
+// Len 返回节点数，不包括根
+func (t *Tree) Len() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	//
+	return len(t.nodes)
+}
+
+// Get 返回 id 的目录项的副本，不存在返回 nil
+func (t *Tree) Get(id string) *xml.Device {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	//
+	if n := t.nodes[id]; n != nil {
+		d := *n.Device
+		return &d
+	}
+	return nil
+}
+
+// Channels 返回所有的通道的副本
+func (t *Tree) Channels() []*xml.Device {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	//
+	var ds []*xml.Device
+	for _, n := range t.nodes {
+		if n.Kind == KindChannel {
+			d := *n.Device
+			ds = append(ds, &d)
+		}
+	}
+	return ds
+}
+
+// Walk 从根开始深度优先遍历，fn 返回 false 不再遍历该节点的子节点
+// 遍历期间不能修改树
+func (t *Tree) Walk(fn func(n *Node, depth int) bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	//
+	walk(t.root, 0, fn)
+}
+
+func walk(n *Node, depth int, fn func(n *Node, depth int) bool) {
+	if !fn(n, depth) {
+		return
+	}
+	for _, c := range n.Children {
+		walk(c, depth+1, fn)
+	}
+}
@@ -0,0 +1,16 @@
+package catalog
+
+import (
+	"context"
+	"goutil/gb28181/request/message/query"
+)
+
+// Query 查询目录，等待所有的分包之后返回目录树，
+// 超时返回已经收到的部分和错误，
+// 需要使用 query.HandleResponse 处理设备的应答
+func Query(ctx context.Context, m *query.Catalog) (*Tree, error) {
+	items, err := query.SendCatalog(ctx, m)
+	t := NewTree(m.Device.GetToID())
+	t.Reset(items)
+	return t, err
+}
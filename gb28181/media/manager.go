@@ -0,0 +1,461 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"goutil/gb28181/request"
+	"goutil/gb28181/request/invite"
+	"goutil/gb28181/ssrc"
+	"goutil/gb28181/xml"
+	"goutil/log"
+	"goutil/sdp"
+	"goutil/sip"
+	gsync "goutil/sync"
+	"goutil/zlm"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// App 是 zlm 收流的流应用，openRtpServer 固定使用
+	App = "rtp"
+	// DefaultStreamTimeout 是等待设备推流的默认超时时间
+	DefaultStreamTimeout = 10 * time.Second
+	// DefaultStreamMode 是默认的传输模式
+	DefaultStreamMode = invite.StreamModePassive
+	// MediaStatusEnd 是 Notify-MediaStatus 表示历史媒体文件发送结束的类型
+	MediaStatusEnd = "121"
+)
+
+var (
+	// ErrStreamTimeout 表示等待设备推流超时
+	ErrStreamTimeout = errors.New("wait stream timeout")
+	// ErrStreamExists 表示媒体服务已经存在同名的流
+	ErrStreamExists = errors.New("stream already exists")
+	// ErrSessionClosed 表示会话已经结束
+	ErrSessionClosed = errors.New("session closed")
+)
+
+// Server 表示一个媒体服务
+type Server interface {
+	zlm.Server
+	// 收流的地址，用于 sdp
+	GetIP() string
+}
+
+// Option 是 NewManager 的参数
+type Option struct {
+	Ser *sip.Server
+	// 分配 ssrc
+	SSRC *ssrc.Allocator
+	// 返回收流的媒体服务
+	GetServer func(dev request.Request, channelID string) (Server, error)
+	// 传输模式，默认 DefaultStreamMode
+	StreamMode invite.StreamMode
+	// 等待设备推流的超时时间，默认 DefaultStreamTimeout
+	StreamTimeout time.Duration
+	// 回放和下载的媒体文件发送结束的回调，之后会结束会话
+	OnEnd func(s *Session)
+	// 会话结束的回调
+	OnClose func(s *Session)
+}
+
+// Manager 管理媒体会话，负责打开收流端口，发送 INVITE ，等待推流，
+// 以及在无人观看，设备 BYE 和设备离线时回收资源
+type Manager struct {
+	opt Option
+	// key 是 Session.Stream
+	sessions gsync.Map[string, *Session]
+}
+
+// NewManager 返回新的 Manager
+func NewManager(opt *Option) *Manager {
+	m := new(Manager)
+	m.opt = *opt
+	if m.opt.StreamMode == "" {
+		m.opt.StreamMode = DefaultStreamMode
+	}
+	if m.opt.StreamTimeout <= 0 {
+		m.opt.StreamTimeout = DefaultStreamTimeout
+	}
+	m.sessions.Init()
+	return m
+}
+
+// Play 实时播放，同一个通道的会话会复用
+func (m *Manager) Play(ctx context.Context, trace string, dev request.Request, channelID string) (*Session, error) {
+	s := newSession(invite.InvitePlay, dev, channelID)
+	s.Stream = dev.GetToID() + "_" + channelID
+	return m.open(ctx, trace, s)
+}
+
+// Playback 录像回放，每次都是新的会话
+func (m *Manager) Playback(ctx context.Context, trace string, dev request.Request, channelID string, startTime, endTime int64) (*Session, error) {
+	s := newSession(invite.InvitePlayback, dev, channelID)
+	s.StartTime = startTime
+	s.EndTime = endTime
+	return m.open(ctx, trace, s)
+}
+
+// Download 录像下载，每次都是新的会话
+func (m *Manager) Download(ctx context.Context, trace string, dev request.Request, channelID string, startTime, endTime int64, speed string) (*Session, error) {
+	s := newSession(invite.InviteDownload, dev, channelID)
+	s.StartTime = startTime
+	s.EndTime = endTime
+	s.Speed = speed
+	return m.open(ctx, trace, s)
+}
+
+// Stop 结束会话，发送 BYE 并关闭收流端口
+func (m *Manager) Stop(ctx context.Context, trace, stream string) {
+	s := m.sessions.Get(stream)
+	if s == nil {
+		return
+	}
+	m.close(ctx, trace, s, true)
+}
+
+// Get 返回会话
+func (m *Manager) Get(stream string) *Session {
+	return m.sessions.Get(stream)
+}
+
+// Sessions 返回所有的会话
+func (m *Manager) Sessions() []*Session {
+	return m.sessions.Values()
+}
+
+// DeviceOffline 设备离线，结束设备的所有会话，不会发送 BYE
+func (m *Manager) DeviceOffline(ctx context.Context, trace, deviceID string) {
+	for _, s := range m.sessions.Search(func(s *Session) bool {
+		return s.Device.GetToID() == deviceID
+	}) {
+		m.close(ctx, trace, s, false)
+	}
+}
+
+// open 打开会话，已经存在则等待并返回存在的会话
+func (m *Manager) open(ctx context.Context, trace string, s *Session) (*Session, error) {
+	// 复用
+	if s.Stream != "" {
+		for !m.sessions.TrySet(s.Stream, s) {
+			old := m.sessions.Get(s.Stream)
+			if old == nil {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-old.done:
+				if old.err != nil {
+					return nil, old.err
+				}
+				return old, nil
+			}
+		}
+	}
+	// 开始
+	err := m.start(ctx, trace, s)
+	if err != nil {
+		m.close(context.Background(), trace, s, s.CallID != "")
+	}
+	s.finish(err)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// start 打开收流端口，发送 INVITE 并等待推流
+func (m *Manager) start(ctx context.Context, trace string, s *Session) (err error) {
+	// 媒体服务
+	s.Server, err = m.opt.GetServer(s.Device, s.ChannelID)
+	if err != nil {
+		return err
+	}
+	s.StreamMode = m.opt.StreamMode
+	// ssrc
+	if s.Kind == invite.InvitePlay {
+		s.SSRC, err = m.opt.SSRC.Realtime(ctx)
+	} else {
+		s.SSRC, err = m.opt.SSRC.History(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	// 回放和下载使用 ssrc 区分
+	if s.Stream == "" {
+		s.Stream = s.Device.GetToID() + "_" + s.ChannelID + "_" + s.SSRC
+		m.sessions.Set(s.Stream, s)
+	}
+	// 收流端口
+	if err = m.openRTPServer(ctx, s); err != nil {
+		return err
+	}
+	// 请求
+	inv := &invite.Invite{
+		Ser:       m.opt.Ser,
+		Device:    s.Device,
+		ChannelID: s.ChannelID,
+		Invite:    s,
+		TraceID:   trace,
+	}
+	switch s.Kind {
+	case invite.InvitePlayback:
+		inv.SDPU = s.ChannelID + ":0"
+		err = invite.SendPlayback(ctx, inv, s.StartTime, s.EndTime)
+	case invite.InviteDownload:
+		inv.SDPU = s.ChannelID + ":0"
+		err = invite.SendDownload(ctx, inv, s.StartTime, s.EndTime, s.Speed)
+	default:
+		err = invite.SendPlay(ctx, inv)
+	}
+	if err != nil {
+		return err
+	}
+	if s.CallID == "" {
+		return ErrSessionClosed
+	}
+	// 绑定会话，其他实例收到 BYE 也可以释放
+	if err = m.opt.SSRC.Bind(ctx, s.CallID, s.SSRC); err != nil {
+		return err
+	}
+	// 主动连接设备
+	if s.StreamMode == invite.StreamModeActive {
+		var res zlm.ConnectRTPServerRes
+		err = zlm.ConnectRTPServer(ctx, s.Server, &zlm.ConnectRTPServerReq{
+			DstIP:   s.RemoteIP,
+			DstPort: s.RemotePort,
+			Stream:  s.Stream,
+		}, &res)
+		if err != nil {
+			return err
+		}
+	}
+	// 等待推流
+	timer := time.NewTimer(m.opt.StreamTimeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return ErrStreamTimeout
+	case <-s.streamC:
+		if s.IsClosed() {
+			return ErrSessionClosed
+		}
+		return nil
+	}
+}
+
+// openRTPServer 打开收流端口
+func (m *Manager) openRTPServer(ctx context.Context, s *Session) error {
+	req := zlm.OpenRTPServerReq{
+		Port:   "0",
+		Stream: s.Stream,
+		SSRC:   s.SSRC,
+	}
+	switch s.StreamMode {
+	case invite.StreamModePassive:
+		req.StreamMode = zlm.RTPStreamModelPassive
+	case invite.StreamModeActive:
+		req.StreamMode = zlm.RTPStreamModelActive
+	default:
+		req.StreamMode = zlm.RTPStreamModelUDP
+	}
+	var res zlm.OpenRTPServerRes
+	if err := zlm.OpenRTPServer(ctx, s.Server, &req, &res); err != nil {
+		return err
+	}
+	// 端口为 0 说明流已经存在
+	if res.Port == 0 {
+		return ErrStreamExists
+	}
+	s.rtpOpened = true
+	s.LocalPort = strconv.Itoa(res.Port)
+	return nil
+}
+
+// close 结束会话，bye 表示是否需要向设备发送 BYE
+func (m *Manager) close(ctx context.Context, trace string, s *Session, bye bool) {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return
+	}
+	if m.sessions.Get(s.Stream) == s {
+		m.sessions.Del(s.Stream)
+	}
+	// 唤醒等待推流
+	s.streamDone()
+	// BYE
+	if bye && s.CallID != "" {
+		if err := request.SendBye(ctx, trace, m.opt.Ser, s.Device, s.ChannelID, s, nil); err != nil {
+			log.Errorf(-1, trace, 0, "media %s bye %v", s.Stream, err)
+		}
+	}
+	// 收流端口
+	if s.rtpOpened {
+		var res zlm.CloseRTPServerRes
+		if err := zlm.CloseRTPServer(ctx, s.Server, &zlm.CloseRTPServerReq{Stream: s.Stream}, &res); err != nil {
+			log.Errorf(-1, trace, 0, "media %s close rtp server %v", s.Stream, err)
+		}
+	}
+	// ssrc
+	if s.SSRC != "" {
+		if err := m.opt.SSRC.Free(ctx, s.SSRC); err != nil {
+			log.Errorf(-1, trace, 0, "media %s free ssrc %v", s.Stream, err)
+		}
+	}
+	// 回调
+	if m.opt.OnClose != nil {
+		m.opt.OnClose(s)
+	}
+}
+
+// HandleResponse 处理 INVITE 的响应，用于 sip.Server.ResponseFunc ，
+// 成功则发送 ACK ，不是会话的 INVITE 直接返回
+func (m *Manager) HandleResponse(ctx *sip.Response) {
+	inv, ok := ctx.ReqData.(*invite.Invite)
+	if !ok {
+		return
+	}
+	s, ok := inv.Invite.(*Session)
+	if !ok {
+		return
+	}
+	// 失败
+	if ctx.Status() != sip.StatusOK {
+		ctx.Finish(&sip.ResponseError{Status: ctx.Status(), Phrase: ctx.Phrase()})
+		return
+	}
+	// 对话
+	s.CallID = ctx.Header.CallID
+	s.FromTag = ctx.Header.From.Tag
+	s.ToTag = ctx.Header.To.Tag
+	// ACK
+	ack := request.NewAck(s.Device, ctx.RemoteNetwork, s.ChannelID, s)
+	ack.Header.CSeq.SN = ctx.Header.CSeq.SN
+	if err := ctx.Write(ack); err != nil {
+		log.Errorf(-1, ctx.Trace(), 0, "media %s ack %v", s.Stream, err)
+	}
+	// 设备的地址
+	var answer sdp.Session
+	if err := answer.ParseFrom(bytes.NewReader(ctx.Body.Bytes())); err == nil {
+		if answer.C != nil {
+			s.RemoteIP = answer.C.Address
+		}
+		for _, mm := range answer.M {
+			if mm.Type == invite.InviteVideo {
+				s.RemotePort = mm.Port
+				if mm.C != nil {
+					s.RemoteIP = mm.C.Address
+				}
+				break
+			}
+		}
+	}
+	ctx.Finish(nil)
+}
+
+// HandleBye 处理设备的 BYE ，用于 sip.Server.RequestFunc ，
+// 不是会话的 BYE 直接返回
+func (m *Manager) HandleBye(ctx *sip.Request) {
+	callID := ctx.Header.CallID
+	s := m.sessions.SearchFirst(func(s *Session) bool {
+		return s.CallID == callID
+	})
+	if s == nil {
+		return
+	}
+	ctx.ResponseStatus(sip.StatusOK)
+	m.close(context.Background(), ctx.Trace(), s, false)
+}
+
+// HandleMediaStatus 处理设备的 Notify-MediaStatus ，用于 sip.Server.RequestFunc ，
+// 收到 "121" 后发送 BYE 结束会话，不是会话的通知直接返回
+func (m *Manager) HandleMediaStatus(ctx *sip.Request) {
+	// 解析，不能影响后面的函数读取
+	var msg xml.Message
+	if err := xml.Decode(bytes.NewReader(ctx.Body.Bytes()), &msg); err != nil {
+		return
+	}
+	if msg.XMLName.Local != xml.TypeNotify || msg.CmdType != xml.CmdMediaStatus {
+		return
+	}
+	// 有些设备在对话内发送，优先使用 Call-ID
+	callID := ctx.Header.CallID
+	deviceID := ctx.Header.From.URI.Name
+	s := m.sessions.SearchFirst(func(s *Session) bool {
+		return s.CallID == callID
+	})
+	if s == nil {
+		s = m.sessions.SearchFirst(func(s *Session) bool {
+			return s.Kind != invite.InvitePlay && !s.IsEnded() &&
+				s.ChannelID == msg.DeviceID && s.Device.GetToID() == deviceID
+		})
+	}
+	if s == nil {
+		return
+	}
+	ctx.ResponseStatus(sip.StatusOK)
+	if msg.NotifyType != MediaStatusEnd {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&s.ended, 0, 1) {
+		return
+	}
+	trace := ctx.Trace()
+	go func() {
+		defer func() {
+			log.Recover(recover())
+		}()
+		if m.opt.OnEnd != nil {
+			m.opt.OnEnd(s)
+		}
+		m.close(context.Background(), trace, s, true)
+	}()
+}
+
+// HandleStreamChanged 处理 zlm 的 on_stream_changed ，
+// 流注册时唤醒等待，流注销时结束会话
+func (m *Manager) HandleStreamChanged(ctx context.Context, req *zlm.OnStreamChangedReq) {
+	if req.App != App {
+		return
+	}
+	s := m.sessions.Get(req.Stream)
+	if s == nil {
+		return
+	}
+	if req.Regist {
+		s.streamDone()
+		return
+	}
+	go m.closeRoutine(req.TraceID, s)
+}
+
+// HandleStreamNoneReader 处理 zlm 的 on_stream_none_reader ，
+// 实时和回放的会话没有观看者时结束，下载的不结束
+func (m *Manager) HandleStreamNoneReader(ctx context.Context, req *zlm.OnStreamNoneReaderReq, res *zlm.OnStreamNoneReaderRes) {
+	if req.App != App {
+		return
+	}
+	s := m.sessions.Get(req.Stream)
+	if s == nil {
+		return
+	}
+	if s.Kind == invite.InviteDownload {
+		res.Close = false
+		return
+	}
+	res.Close = true
+	go m.closeRoutine(req.TraceID, s)
+}
+
+// closeRoutine 在协程中结束会话
+func (m *Manager) closeRoutine(trace string, s *Session) {
+	defer func() {
+		log.Recover(recover())
+	}()
+	m.close(context.Background(), trace, s, true)
+}
@@ -0,0 +1,203 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"goutil/gb28181/request/info"
+	"goutil/gb28181/request/invite"
+	"goutil/sip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Speeds 是标准允许的回放倍速
+var Speeds = []string{"0.25", "0.5", "1", "2", "4"}
+
+var (
+	// ErrSpeed 表示倍速不是标准允许的值
+	ErrSpeed = errors.New("invalid speed")
+	// ErrSeek 表示拖动的位置超出录像的时间范围
+	ErrSeek = errors.New("invalid seek position")
+	// ErrNotPlayback 表示会话不是回放
+	ErrNotPlayback = errors.New("not playback session")
+)
+
+// Playback 是回放会话的控制，保存当前的位置，倍速和暂停状态，
+// 并为 MANSRTSP 生成对话内递增的 CSeq
+type Playback struct {
+	s    *Session
+	ser  *sip.Server
+	lock sync.Mutex
+	// MANSRTSP 的 CSeq
+	cseq int64
+	// 倍速
+	scale    string
+	scaleNum float64
+	// 暂停
+	paused bool
+	// 在 posTime 时的位置，相对于开始时间的秒数
+	pos     float64
+	posTime time.Time
+}
+
+// NewPlayback 返回会话 s 的控制
+func NewPlayback(ser *sip.Server, s *Session) (*Playback, error) {
+	if s.Kind != invite.InvitePlayback {
+		return nil, ErrNotPlayback
+	}
+	p := new(Playback)
+	p.s = s
+	p.ser = ser
+	p.scale = "1"
+	p.scaleNum = 1
+	p.posTime = time.Now()
+	return p, nil
+}
+
+// Session 返回会话
+func (p *Playback) Session() *Session {
+	return p.s
+}
+
+// duration 返回录像的时长，单位秒
+func (p *Playback) duration() float64 {
+	return float64(p.s.EndTime - p.s.StartTime)
+}
+
+// position 返回当前的位置，需要在锁中调用
+func (p *Playback) position() float64 {
+	pos := p.pos
+	if !p.paused {
+		pos += time.Since(p.posTime).Seconds() * p.scaleNum
+	}
+	if d := p.duration(); pos > d {
+		pos = d
+	}
+	return pos
+}
+
+// Position 返回当前的位置，相对于开始时间的秒数
+func (p *Playback) Position() int64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return int64(p.position())
+}
+
+// Scale 返回当前的倍速
+func (p *Playback) Scale() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.scale
+}
+
+// IsPaused 返回是否暂停
+func (p *Playback) IsPaused() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.paused
+}
+
+// IsEnd 返回设备是否已经发送完毕
+func (p *Playback) IsEnd() bool {
+	return p.s.IsEnded()
+}
+
+// info 返回请求的参数，需要在锁中调用
+func (p *Playback) info(trace string) *info.Info {
+	p.cseq++
+	return &info.Info{
+		Ser:       p.ser,
+		Device:    p.s.Device,
+		ChannelID: p.s.ChannelID,
+		Invite:    p.s,
+		CSeq:      p.cseq,
+		TraceID:   trace,
+	}
+}
+
+// Seek 拖动到相对于开始时间 sec 秒的位置
+func (p *Playback) Seek(ctx context.Context, trace string, sec int64) error {
+	if sec < 0 || float64(sec) > p.duration() {
+		return ErrSeek
+	}
+	if p.s.IsClosed() {
+		return ErrSessionClosed
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err := info.SendInfoRange(ctx, p.info(trace), sec); err != nil {
+		return err
+	}
+	p.pos = float64(sec)
+	p.posTime = time.Now()
+	return nil
+}
+
+// SetSpeed 设置倍速，必须是 Speeds 中的值
+func (p *Playback) SetSpeed(ctx context.Context, trace, scale string) error {
+	if !isSpeed(scale) {
+		return ErrSpeed
+	}
+	num, _ := strconv.ParseFloat(scale, 64)
+	if p.s.IsClosed() {
+		return ErrSessionClosed
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err := info.SendInfoScale(ctx, p.info(trace), scale); err != nil {
+		return err
+	}
+	p.pos = p.position()
+	p.posTime = time.Now()
+	p.scale = scale
+	p.scaleNum = num
+	return nil
+}
+
+// Pause 暂停
+func (p *Playback) Pause(ctx context.Context, trace string) error {
+	if p.s.IsClosed() {
+		return ErrSessionClosed
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paused {
+		return nil
+	}
+	if err := info.SendInfoPause(ctx, p.info(trace)); err != nil {
+		return err
+	}
+	p.pos = p.position()
+	p.posTime = time.Now()
+	p.paused = true
+	return nil
+}
+
+// Resume 恢复播放
+func (p *Playback) Resume(ctx context.Context, trace string) error {
+	if p.s.IsClosed() {
+		return ErrSessionClosed
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.paused {
+		return nil
+	}
+	if err := info.SendInfoPlay(ctx, p.info(trace)); err != nil {
+		return err
+	}
+	p.posTime = time.Now()
+	p.paused = false
+	return nil
+}
+
+// isSpeed 返回 scale 是否标准允许的倍速
+func isSpeed(scale string) bool {
+	for _, s := range Speeds {
+		if s == scale {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package media
+
+import (
+	"goutil/gb28181/request"
+	"goutil/gb28181/request/invite"
+	"sync"
+	"sync/atomic"
+)
+
+// Session 表示一个媒体会话，
+// 包括 zlm 的收流端口，设备的 INVITE 对话和 ssrc
+type Session struct {
+	// 类型，invite.InvitePlay/InvitePlayback/InviteDownload
+	Kind string
+	// 设备
+	Device request.Request
+	// 通道
+	ChannelID string
+	// 回放和下载的时间范围，时间戳
+	StartTime int64
+	EndTime   int64
+	// 下载速度
+	Speed string
+	// 媒体服务
+	Server Server
+	// zlm 的流标识，也是会话的标识
+	Stream string
+	// ssrc
+	SSRC string
+	// 平台的传输模式
+	StreamMode invite.StreamMode
+	// 收流端口
+	LocalPort string
+	// 设备的发流地址，主动模式使用
+	RemoteIP   string
+	RemotePort string
+	// 对话
+	CallID  string
+	FromTag string
+	ToTag   string
+	// 收流端口是否打开
+	rtpOpened bool
+	// 流注册的信号
+	streamC    chan struct{}
+	streamOnce sync.Once
+	// 结束信号
+	done chan struct{}
+	err  error
+	// 关闭标记
+	closed int32
+	// 设备发送完毕的标记
+	ended int32
+}
+
+// newSession 返回新的 Session
+func newSession(kind string, dev request.Request, channelID string) *Session {
+	s := new(Session)
+	s.Kind = kind
+	s.Device = dev
+	s.ChannelID = channelID
+	s.streamC = make(chan struct{})
+	s.done = make(chan struct{})
+	return s
+}
+
+// GetSSRC 实现 invite.InviteData
+func (s *Session) GetSSRC() string {
+	return s.SSRC
+}
+
+// GetStreamMode 实现 invite.InviteData
+func (s *Session) GetStreamMode() invite.StreamMode {
+	return s.StreamMode
+}
+
+// GetLocalIP 实现 invite.InviteData
+func (s *Session) GetLocalIP() string {
+	return s.Server.GetIP()
+}
+
+// GetLocalPort 实现 invite.InviteData
+func (s *Session) GetLocalPort() string {
+	return s.LocalPort
+}
+
+// GetFromTag 实现 request.Invite
+func (s *Session) GetFromTag() string {
+	return s.FromTag
+}
+
+// GetToTag 实现 request.Invite
+func (s *Session) GetToTag() string {
+	return s.ToTag
+}
+
+// GetCallID 实现 request.Invite
+func (s *Session) GetCallID() string {
+	return s.CallID
+}
+
+// IsClosed 返回会话是否已经结束
+func (s *Session) IsClosed() bool {
+	return atomic.LoadInt32(&s.closed) == 1
+}
+
+// IsEnded 返回设备是否已经通知媒体文件发送结束
+func (s *Session) IsEnded() bool {
+	return atomic.LoadInt32(&s.ended) == 1
+}
+
+// finish 通知等待的调用者
+func (s *Session) finish(err error) {
+	s.err = err
+	close(s.done)
+}
+
+// streamDone 通知等待推流
+func (s *Session) streamDone() {
+	s.streamOnce.Do(func() {
+		close(s.streamC)
+	})
+}
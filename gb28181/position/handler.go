@@ -0,0 +1,80 @@
+package position
+
+import (
+	"bytes"
+	"context"
+	"goutil/gb28181/xml"
+	"goutil/log"
+	"goutil/sip"
+	"time"
+)
+
+// HandlerOption 是 NewHandler 的参数
+type HandlerOption struct {
+	// 返回是否处理设备的通知，nil 表示全部处理
+	IsDevice func(ctx *sip.Request, id string) bool
+	// 保存位置，nil 表示不保存
+	Store Store
+	// 位置的回调，在协程中执行
+	OnPosition func(*Position)
+}
+
+// Handler 处理移动设备的位置通知，
+// 自动响应 200 ，保存后通过回调交给应用
+type Handler struct {
+	opt HandlerOption
+}
+
+// NewHandler 返回新的 Handler
+func NewHandler(opt *HandlerOption) *Handler {
+	h := new(Handler)
+	h.opt = *opt
+	return h
+}
+
+// HandleMessage 处理 MESSAGE 的 Notify-MobilePosition ，用于 sip.Server.RequestFunc
+func (h *Handler) HandleMessage(ctx *sip.Request) {
+	h.handle(ctx)
+}
+
+// HandleNotify 处理订阅后的 NOTIFY 位置，用于 sip.Server.RequestFunc
+func (h *Handler) HandleNotify(ctx *sip.Request) {
+	h.handle(ctx)
+}
+
+// handle 处理位置，不是位置通知直接返回
+func (h *Handler) handle(ctx *sip.Request) {
+	fromID := ctx.Header.From.URI.Name
+	if h.opt.IsDevice != nil && !h.opt.IsDevice(ctx, fromID) {
+		return
+	}
+	// 解析，不能影响后面的函数读取
+	var msg xml.Message
+	if err := xml.Decode(bytes.NewReader(ctx.Body.Bytes()), &msg); err != nil {
+		return
+	}
+	if msg.XMLName.Local != xml.TypeNotify || msg.CmdType != xml.CmdMobilePosition {
+		return
+	}
+	// 响应
+	ctx.ResponseStatus(sip.StatusOK)
+	// 位置
+	p := new(Position)
+	p.Init(fromID, &msg, time.Now().Unix())
+	go h.routine(ctx.Trace(), p)
+}
+
+// routine 在协程中保存和通知
+func (h *Handler) routine(trace string, p *Position) {
+	defer func() {
+		log.Recover(recover())
+	}()
+	if h.opt.Store != nil {
+		if err := h.opt.Store.Add(context.Background(), p); err != nil {
+			log.Errorf(-1, trace, 0, "position %s save %v", p.DeviceID, err)
+		}
+	}
+	if h.opt.OnPosition != nil {
+		h.opt.OnPosition(p)
+	}
+}
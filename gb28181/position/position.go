@@ -0,0 +1,52 @@
+package position
+
+import (
+	"goutil/gb28181"
+	"goutil/gb28181/xml"
+	"strconv"
+)
+
+// Position 表示一个移动设备的位置
+type Position struct {
+	ID int64 `json:"-" gorm:"primaryKey"`
+	// 发送通知的设备编号
+	FromID string `json:"fromID" gorm:"type:varchar(20)"`
+	// 设备/通道编号
+	DeviceID string `json:"deviceID" gorm:"type:varchar(20);index:idx_device_time,priority:1"`
+	// 通知时间，国标格式
+	Time string `json:"time" gorm:"type:varchar(20)"`
+	// 通知时间戳，解析失败使用收到的时间
+	Timestamp int64 `json:"timestamp" gorm:"index:idx_device_time,priority:2"`
+	// 经度
+	Longitude float64 `json:"longitude"`
+	// 纬度
+	Latitude float64 `json:"latitude"`
+	// 速度，单位 km/h
+	Speed float64 `json:"speed"`
+	// 方向，和正北方的顺时针夹角，0~360
+	Direction float64 `json:"direction"`
+	// 海拔，单位 m
+	Altitude float64 `json:"altitude"`
+}
+
+// Init 使用 msg 初始化，now 是收到的时间戳
+func (p *Position) Init(fromID string, msg *xml.Message, now int64) {
+	p.FromID = fromID
+	p.DeviceID = msg.DeviceID
+	p.Time = msg.Time
+	p.Timestamp = gb28181.Timestamp(msg.Time)
+	if p.Timestamp == 0 {
+		p.Timestamp = now
+	}
+	p.Longitude = parseFloat(msg.Longitude)
+	p.Latitude = parseFloat(msg.Latitude)
+	p.Speed = parseFloat(msg.Speed)
+	p.Direction = parseFloat(msg.Direction)
+	p.Altitude = parseFloat(msg.Altitude)
+}
+
+// parseFloat 解析失败返回 0
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -0,0 +1,126 @@
+package position
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Store 是位置的存储
+type Store interface {
+	// Add 添加位置
+	Add(ctx context.Context, p *Position) error
+	// Query 返回设备在 [start, end] 时间戳范围内的位置，按时间升序
+	Query(ctx context.Context, deviceID string, start, end int64) ([]*Position, error)
+}
+
+// MemoryStore 在内存中保存每个设备最近的位置
+type MemoryStore struct {
+	// 每个设备保存的个数
+	size int
+	lock sync.RWMutex
+	// key 是设备编号
+	rings map[string]*ring
+}
+
+// ring 是一个环形缓冲
+type ring struct {
+	data []*Position
+	// 下一个写入的位置
+	next int
+	// 是否已经写满
+	full bool
+}
+
+// NewMemoryStore 返回新的 MemoryStore ，size 是每个设备保存的个数
+func NewMemoryStore(size int) *MemoryStore {
+	if size < 1 {
+		size = 1
+	}
+	s := new(MemoryStore)
+	s.size = size
+	s.rings = make(map[string]*ring)
+	return s
+}
+
+// Add 实现 Store ，满了覆盖最旧的
+func (s *MemoryStore) Add(ctx context.Context, p *Position) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r := s.rings[p.DeviceID]
+	if r == nil {
+		r = &ring{data: make([]*Position, s.size)}
+		s.rings[p.DeviceID] = r
+	}
+	r.data[r.next] = p
+	r.next++
+	if r.next >= len(r.data) {
+		r.next = 0
+		r.full = true
+	}
+	return nil
+}
+
+// Query 实现 Store
+func (s *MemoryStore) Query(ctx context.Context, deviceID string, start, end int64) ([]*Position, error) {
+	s.lock.RLock()
+	r := s.rings[deviceID]
+	if r == nil {
+		s.lock.RUnlock()
+		return nil, nil
+	}
+	var ps []*Position
+	for _, p := range r.data {
+		if p != nil && p.Timestamp >= start && p.Timestamp <= end {
+			ps = append(ps, p)
+		}
+	}
+	s.lock.RUnlock()
+	// 设备上报的时间可能乱序
+	sort.SliceStable(ps, func(i, j int) bool {
+		return ps[i].Timestamp < ps[j].Timestamp
+	})
+	return ps, nil
+}
+
+// Remove 删除设备的所有位置
+func (s *MemoryStore) Remove(deviceID string) {
+	s.lock.Lock()
+	delete(s.rings, deviceID)
+	s.lock.Unlock()
+}
+
+// GormStore 使用数据库保存位置
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 返回新的 GormStore ，会自动迁移 Position 表
+func NewGormStore(db *gorm.DB) (*GormStore, error) {
+	if err := db.AutoMigrate(new(Position)); err != nil {
+		return nil, err
+	}
+	return &GormStore{db: db}, nil
+}
+
+// Add 实现 Store
+func (s *GormStore) Add(ctx context.Context, p *Position) error {
+	return s.db.WithContext(ctx).Create(p).Error
+}
+
+// Query 实现 Store
+func (s *GormStore) Query(ctx context.Context, deviceID string, start, end int64) ([]*Position, error) {
+	var ps []*Position
+	err := s.db.WithContext(ctx).
+		Where("`DeviceID` = ? AND `Timestamp` >= ? AND `Timestamp` <= ?", deviceID, start, end).
+		Order("`Timestamp`").Find(&ps).Error
+	return ps, err
+}
+
+// Delete 删除 before 时间戳之前的位置，用于定时清理
+func (s *GormStore) Delete(ctx context.Context, before int64) (int64, error) {
+	db := s.db.WithContext(ctx).Where("`Timestamp` < ?", before).Delete(new(Position))
+	return db.RowsAffected, db.Error
+}
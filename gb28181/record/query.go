@@ -0,0 +1,25 @@
+package record
+
+import (
+	"context"
+	"goutil/gb28181"
+	"goutil/gb28181/request/message/query"
+)
+
+// Query 查询录像，等待所有的分包之后返回时间轴，
+// m.StartTime 和 m.EndTime 可以是国标格式或者用空格分隔日期和时间，发送前会改为国标格式，
+// 时间格式错误或者范围无效返回 ErrTimeRange ，
+// 超时返回已经收到的部分和错误，
+// 需要使用 query.HandleResponse 处理设备的应答
+func Query(ctx context.Context, m *query.RecordInfo, opt *Option) (*Timeline, error) {
+	start, end, err := parseRange(m.StartTime, m.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	// 统一为国标格式
+	m.StartTime = gb28181.TimeFromTimestamp(start)
+	m.EndTime = gb28181.TimeFromTimestamp(end)
+	items, err := query.SendRecordInfo(ctx, m)
+	t, _ := NewTimeline(items, start, end, opt)
+	return t, err
+}
@@ -0,0 +1,232 @@
+package record
+
+import (
+	"errors"
+	"goutil/gb28181"
+	"goutil/gb28181/xml"
+	"sort"
+	"time"
+)
+
+// 录像产生类型
+const (
+	TypeTime   = "time"
+	TypeAlarm  = "alarm"
+	TypeManual = "manual"
+	TypeAll    = "all"
+)
+
+// DefaultTolerance 是默认的合并间隔，单位秒，
+// 设备切分文件时前后两个录像之间通常有 1 秒左右的空隙
+const DefaultTolerance = 2
+
+// timeFormatSpace 是用空格分隔日期和时间的格式，有些设备使用
+const timeFormatSpace = "2006-01-02 15:04:05"
+
+var (
+	// ErrTimeRange 表示时间格式错误或者结束时间不大于开始时间
+	ErrTimeRange = errors.New("invalid time range")
+)
+
+// Option 是 NewTimeline 的参数
+type Option struct {
+	// 过滤的录像类型，空表示全部
+	Types []string
+	// 间隔小于等于这个值的录像合并为一段，单位秒，默认 DefaultTolerance ，
+	// 小于 0 表示只合并重叠的
+	Tolerance int64
+}
+
+// Segment 表示时间轴上连续的一段录像
+type Segment struct {
+	// 开始时间戳
+	Start int64 `json:"start"`
+	// 结束时间戳
+	End int64 `json:"end"`
+	// 包含的录像类型
+	Types []string `json:"types"`
+	// 包含的录像
+	Records []*xml.Record `json:"-"`
+}
+
+// Gap 表示时间轴上没有录像的一段
+type Gap struct {
+	// 开始时间戳
+	Start int64 `json:"start"`
+	// 结束时间戳
+	End int64 `json:"end"`
+}
+
+// Timeline 表示一个时间范围内的录像时间轴，用于回放的进度条
+type Timeline struct {
+	// 查询的开始时间戳
+	Start int64 `json:"start"`
+	// 查询的结束时间戳
+	End int64 `json:"end"`
+	// 有录像的时长，单位秒
+	Duration int64 `json:"duration"`
+	// 按时间升序的录像段
+	Segments []*Segment `json:"segments"`
+	// 按时间升序的空隙
+	Gaps []*Gap `json:"gaps"`
+}
+
+// NewTimeline 把 records 合并为 [start, end] 范围内的时间轴，
+// 重叠的录像（比如中心和设备都有存储）会合并，opt 可以为 nil ，
+// end 不大于 start 返回 ErrTimeRange
+func NewTimeline(records []*xml.Record, start, end int64, opt *Option) (*Timeline, error) {
+	if end <= start {
+		return nil, ErrTimeRange
+	}
+	var types []string
+	tolerance := int64(DefaultTolerance)
+	if opt != nil {
+		types = opt.Types
+		if opt.Tolerance != 0 {
+			tolerance = opt.Tolerance
+		}
+	}
+	if tolerance < 0 {
+		tolerance = 0
+	}
+	// 解析和过滤
+	segs := make([]*Segment, 0, len(records))
+	for _, r := range records {
+		if !matchType(types, r.Type) {
+			continue
+		}
+		s, e, err := parseRange(r.StartTime, r.EndTime)
+		if err != nil {
+			continue
+		}
+		// 裁剪
+		if s < start {
+			s = start
+		}
+		if e > end {
+			e = end
+		}
+		if e <= s {
+			continue
+		}
+		segs = append(segs, &Segment{Start: s, End: e, Records: []*xml.Record{r}})
+	}
+	sort.SliceStable(segs, func(i, j int) bool {
+		return segs[i].Start < segs[j].Start
+	})
+	// 合并
+	t := new(Timeline)
+	t.Start = start
+	t.End = end
+	for _, s := range segs {
+		n := len(t.Segments)
+		if n > 0 {
+			last := t.Segments[n-1]
+			if s.Start <= last.End+tolerance {
+				if s.End > last.End {
+					last.End = s.End
+				}
+				last.Records = append(last.Records, s.Records...)
+				continue
+			}
+		}
+		t.Segments = append(t.Segments, s)
+	}
+	// 类型，时长和空隙
+	pos := start
+	for _, s := range t.Segments {
+		s.Types = recordTypes(s.Records)
+		t.Duration += s.End - s.Start
+		if s.Start > pos {
+			t.Gaps = append(t.Gaps, &Gap{Start: pos, End: s.Start})
+		}
+		pos = s.End
+	}
+	if pos < end {
+		t.Gaps = append(t.Gaps, &Gap{Start: pos, End: end})
+	}
+	return t, nil
+}
+
+// parseTime 解析国标格式或者用空格分隔的时间，返回时间戳
+func parseTime(s string) (int64, error) {
+	for _, layout := range []string{gb28181.TimeForamt, timeFormatSpace} {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, ErrTimeRange
+}
+
+// parseRange 解析开始和结束时间，结束时间不大于开始时间返回 ErrTimeRange
+func parseRange(start, end string) (int64, int64, error) {
+	s, err := parseTime(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	e, err := parseTime(end)
+	if err != nil {
+		return 0, 0, err
+	}
+	if e <= s {
+		return 0, 0, ErrTimeRange
+	}
+	return s, e, nil
+}
+
+// Find 返回包含时间戳 ts 的录像段，没有返回 nil
+func (t *Timeline) Find(ts int64) *Segment {
+	i := sort.Search(len(t.Segments), func(i int) bool {
+		return t.Segments[i].End > ts
+	})
+	if i < len(t.Segments) && t.Segments[i].Start <= ts {
+		return t.Segments[i]
+	}
+	return nil
+}
+
+// Next 返回时间戳 ts 之后（包含）的第一个录像段，用于跳过空隙，没有返回 nil
+func (t *Timeline) Next(ts int64) *Segment {
+	i := sort.Search(len(t.Segments), func(i int) bool {
+		return t.Segments[i].End > ts
+	})
+	if i < len(t.Segments) {
+		return t.Segments[i]
+	}
+	return nil
+}
+
+// matchType 返回 typ 是否在 types 中，types 为空或者包含 TypeAll 表示全部
+func matchType(types []string, typ string) bool {
+	if len(types) < 1 {
+		return true
+	}
+	for _, t := range types {
+		if t == TypeAll || t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// recordTypes 返回录像的类型，去重
+func recordTypes(records []*xml.Record) []string {
+	var types []string
+	for _, r := range records {
+		if r.Type == "" {
+			continue
+		}
+		has := false
+		for _, t := range types {
+			if t == r.Type {
+				has = true
+				break
+			}
+		}
+		if !has {
+			types = append(types, r.Type)
+		}
+	}
+	return types
+}
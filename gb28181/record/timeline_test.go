@@ -0,0 +1,198 @@
+package record
+
+import (
+	"goutil/gb28181/xml"
+	"reflect"
+	"testing"
+)
+
+// rec 返回测试的录像
+func rec(start, end, typ string) *xml.Record {
+	return &xml.Record{StartTime: start, EndTime: end, Type: typ}
+}
+
+// ts 返回测试的时间戳
+func ts(t *testing.T, s string) int64 {
+	n, err := parseTime(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func Test_NewTimeline(t *testing.T) {
+	type seg struct {
+		start, end string
+		types      []string
+	}
+	type gap struct {
+		start, end string
+	}
+	tests := []struct {
+		name     string
+		records  []*xml.Record
+		opt      *Option
+		segments []seg
+		gaps     []gap
+	}{
+		{
+			name: "merge overlap",
+			records: []*xml.Record{
+				rec("2023-01-01T10:10:00", "2023-01-01T10:30:00", TypeTime),
+				rec("2023-01-01T10:00:00", "2023-01-01T10:20:00", TypeAlarm),
+			},
+			segments: []seg{{"2023-01-01T10:00:00", "2023-01-01T10:30:00", []string{TypeAlarm, TypeTime}}},
+			gaps:     []gap{{"2023-01-01T10:30:00", "2023-01-01T11:00:00"}},
+		},
+		{
+			name: "merge within tolerance",
+			records: []*xml.Record{
+				rec("2023-01-01T10:00:00", "2023-01-01T10:10:00", TypeTime),
+				rec("2023-01-01T10:10:01", "2023-01-01T11:00:00", TypeTime),
+			},
+			segments: []seg{{"2023-01-01T10:00:00", "2023-01-01T11:00:00", []string{TypeTime}}},
+		},
+		{
+			name: "gap",
+			records: []*xml.Record{
+				rec("2023-01-01T10:05:00", "2023-01-01T10:10:00", TypeTime),
+				rec("2023-01-01T10:20:00", "2023-01-01T10:30:00", TypeTime),
+			},
+			segments: []seg{
+				{"2023-01-01T10:05:00", "2023-01-01T10:10:00", []string{TypeTime}},
+				{"2023-01-01T10:20:00", "2023-01-01T10:30:00", []string{TypeTime}},
+			},
+			gaps: []gap{
+				{"2023-01-01T10:00:00", "2023-01-01T10:05:00"},
+				{"2023-01-01T10:10:00", "2023-01-01T10:20:00"},
+				{"2023-01-01T10:30:00", "2023-01-01T11:00:00"},
+			},
+		},
+		{
+			name: "no tolerance",
+			records: []*xml.Record{
+				rec("2023-01-01T10:00:00", "2023-01-01T10:10:00", TypeTime),
+				rec("2023-01-01T10:10:01", "2023-01-01T11:00:00", TypeTime),
+			},
+			opt: &Option{Tolerance: -1},
+			segments: []seg{
+				{"2023-01-01T10:00:00", "2023-01-01T10:10:00", []string{TypeTime}},
+				{"2023-01-01T10:10:01", "2023-01-01T11:00:00", []string{TypeTime}},
+			},
+			gaps: []gap{{"2023-01-01T10:10:00", "2023-01-01T10:10:01"}},
+		},
+		{
+			name: "type filter",
+			records: []*xml.Record{
+				rec("2023-01-01T10:00:00", "2023-01-01T10:20:00", TypeTime),
+				rec("2023-01-01T10:30:00", "2023-01-01T10:40:00", TypeAlarm),
+				rec("2023-01-01T10:40:00", "2023-01-01T10:50:00", TypeManual),
+			},
+			opt:      &Option{Types: []string{TypeAlarm}},
+			segments: []seg{{"2023-01-01T10:30:00", "2023-01-01T10:40:00", []string{TypeAlarm}}},
+			gaps: []gap{
+				{"2023-01-01T10:00:00", "2023-01-01T10:30:00"},
+				{"2023-01-01T10:40:00", "2023-01-01T11:00:00"},
+			},
+		},
+		{
+			name: "type all",
+			records: []*xml.Record{
+				rec("2023-01-01T10:00:00", "2023-01-01T10:30:00", TypeTime),
+				rec("2023-01-01T10:30:00", "2023-01-01T11:00:00", TypeManual),
+			},
+			opt:      &Option{Types: []string{TypeAll}},
+			segments: []seg{{"2023-01-01T10:00:00", "2023-01-01T11:00:00", []string{TypeTime, TypeManual}}},
+		},
+		{
+			name: "clip and space separated",
+			records: []*xml.Record{
+				rec("2023-01-01 09:00:00", "2023-01-01 10:15:00", TypeTime),
+				rec("2023-01-01T10:45:00", "2023-01-01T12:00:00", TypeTime),
+			},
+			segments: []seg{
+				{"2023-01-01T10:00:00", "2023-01-01T10:15:00", []string{TypeTime}},
+				{"2023-01-01T10:45:00", "2023-01-01T11:00:00", []string{TypeTime}},
+			},
+			gaps: []gap{{"2023-01-01T10:15:00", "2023-01-01T10:45:00"}},
+		},
+		{
+			name: "skip invalid",
+			records: []*xml.Record{
+				rec("bad", "2023-01-01T10:15:00", TypeTime),
+				rec("2023-01-01T10:20:00", "2023-01-01T10:10:00", TypeTime),
+				rec("2023-01-01T08:00:00", "2023-01-01T09:00:00", TypeTime),
+			},
+			gaps: []gap{{"2023-01-01T10:00:00", "2023-01-01T11:00:00"}},
+		},
+	}
+	start := ts(t, "2023-01-01T10:00:00")
+	end := ts(t, "2023-01-01T11:00:00")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl, err := NewTimeline(tt.records, start, end, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tl.Segments) != len(tt.segments) {
+				t.Fatalf("segments %d, want %d", len(tl.Segments), len(tt.segments))
+			}
+			var duration int64
+			for i, s := range tt.segments {
+				got := tl.Segments[i]
+				if got.Start != ts(t, s.start) || got.End != ts(t, s.end) {
+					t.Errorf("segment %d [%d, %d], want [%s, %s]", i, got.Start, got.End, s.start, s.end)
+				}
+				if !reflect.DeepEqual(got.Types, s.types) {
+					t.Errorf("segment %d types %v, want %v", i, got.Types, s.types)
+				}
+				duration += got.End - got.Start
+			}
+			if tl.Duration != duration {
+				t.Errorf("duration %d, want %d", tl.Duration, duration)
+			}
+			if len(tl.Gaps) != len(tt.gaps) {
+				t.Fatalf("gaps %d, want %d", len(tl.Gaps), len(tt.gaps))
+			}
+			for i, g := range tt.gaps {
+				got := tl.Gaps[i]
+				if got.Start != ts(t, g.start) || got.End != ts(t, g.end) {
+					t.Errorf("gap %d [%d, %d], want [%s, %s]", i, got.Start, got.End, g.start, g.end)
+				}
+			}
+		})
+	}
+}
+
+func Test_NewTimeline_InvalidRange(t *testing.T) {
+	start := ts(t, "2023-01-01T10:00:00")
+	for _, end := range []int64{start, start - 1} {
+		if _, err := NewTimeline(nil, start, end, nil); err != ErrTimeRange {
+			t.Errorf("end %d err %v, want %v", end, err, ErrTimeRange)
+		}
+	}
+}
+
+func Test_parseRange(t *testing.T) {
+	tests := []struct {
+		start, end string
+		ok         bool
+	}{
+		{"2023-01-01T10:00:00", "2023-01-01T11:00:00", true},
+		{"2023-01-01 10:00:00", "2023-01-01 11:00:00", true},
+		{"2023-01-01 10:00:00", "2023-01-01T11:00:00", true},
+		{"2023-01-01T11:00:00", "2023-01-01T10:00:00", false},
+		{"2023-01-01T10:00:00", "2023-01-01T10:00:00", false},
+		{"", "2023-01-01T11:00:00", false},
+		{"2023-01-01T10:00:00", "2023/01/01 11:00:00", false},
+	}
+	for _, tt := range tests {
+		_, _, err := parseRange(tt.start, tt.end)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseRange(%q, %q) err %v", tt.start, tt.end, err)
+		}
+		if err != nil && err != ErrTimeRange {
+			t.Errorf("parseRange(%q, %q) err %v, want %v", tt.start, tt.end, err, ErrTimeRange)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	"strconv"
+	"sync"
+)
+
+// Dialog 表示 SUBSCRIBE 建立的对话，
+// 第一次请求之后保存 Call-ID 、From-tag 和 To-tag ，
+// 之后的刷新和取消都在同一个对话中发送，CSeq 每次加 1
+type Dialog struct {
+	lock    sync.Mutex
+	callID  string
+	fromTag string
+	toTag   string
+	cseq    int64
+	// 响应的 xml.Result
+	result string
+}
+
+// GetCallID 实现 Invite 接口
+func (d *Dialog) GetCallID() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.callID
+}
+
+// GetFromTag 实现 Invite 接口
+func (d *Dialog) GetFromTag() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.fromTag
+}
+
+// GetToTag 实现 Invite 接口
+func (d *Dialog) GetToTag() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.toTag
+}
+
+// IsEstablished 返回对方是否已经成功响应过
+func (d *Dialog) IsEstablished() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.toTag != ""
+}
+
+// Result 返回最后一次响应的 xml.Result
+func (d *Dialog) Result() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.result
+}
+
+// Reset 重置，下一次请求建立新的对话
+func (d *Dialog) Reset() {
+	d.lock.Lock()
+	d.callID = ""
+	d.fromTag = ""
+	d.toTag = ""
+	d.cseq = 0
+	d.result = ""
+	d.lock.Unlock()
+}
+
+// apply 第一次保存 msg 的对话，之后使用保存的对话并且 CSeq 加 1
+func (d *Dialog) apply(msg *sip.Message) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.result = ""
+	if d.callID == "" {
+		d.callID = msg.Header.CallID
+		d.fromTag = msg.Header.From.Tag
+		d.cseq, _ = strconv.ParseInt(msg.Header.CSeq.SN, 10, 64)
+		return
+	}
+	d.cseq++
+	msg.Header.CallID = d.callID
+	msg.Header.From.Tag = d.fromTag
+	msg.Header.To.Tag = d.toTag
+	msg.Header.CSeq.SN = strconv.FormatInt(d.cseq, 10)
+}
+
+// HandleDialogResponse 处理对话中请求的响应，用于 sip.Server.ResponseFunc ，
+// 成功保存 To-tag 和 xml.Result ，失败结束请求并返回 *sip.ResponseError ，
+// 不是对话的请求直接返回
+func HandleDialogResponse(ctx *sip.Response) {
+	d, ok := ctx.ReqData.(*Dialog)
+	if !ok {
+		return
+	}
+	status := ctx.Status()
+	if status == "" || status[0] != '2' {
+		ctx.Finish(&sip.ResponseError{Status: status, Phrase: ctx.Phrase()})
+		return
+	}
+	var res xml.Message
+	if ctx.Body.Len() > 0 {
+		xml.Decode(bytes.NewReader(ctx.Body.Bytes()), &res)
+	}
+	d.lock.Lock()
+	if d.toTag == "" {
+		d.toTag = ctx.Header.To.Tag
+	}
+	d.result = res.Result
+	d.lock.Unlock()
+	ctx.Finish(nil)
+}
+
+// SendSubscribeDialog 和 SendSubscribe 一样，但是在 dlg 的对话中发送，
+// 需要使用 HandleDialogResponse 处理响应
+func SendSubscribeDialog(ctx context.Context, trace string, ser *sip.Server, req Request, body *xml.Subscribe, expire int64, dlg *Dialog) error {
+	// 地址
+	addr, err := req.GetNetAddr()
+	if err != nil {
+		return err
+	}
+	// 消息
+	msg := NewSubscribe(req, addr.Network(), body)
+	msg.Header.Expires = strconv.FormatInt(expire, 10)
+	msg.Header.Set("Event", "presence")
+	dlg.apply(msg)
+	//
+	return ser.RequestWithContext(ctx, trace, msg, addr, dlg)
+}
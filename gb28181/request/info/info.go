@@ -21,12 +21,31 @@ type Info struct {
 	Device    request.Request
 	ChannelID string
 	Invite    request.Invite
+	// MANSRTSP 的 CSeq ，0 使用全局递增的序号
+	CSeq int64
 	// 追踪标识
 	TraceID string
 }
 
 func (m *Info) encStartLline(w *bytes.Buffer, cmd string) {
-	fmt.Fprintf(w, "%s RTSP/1.0\r\nCSeq: %d\r\n", cmd, sip.GetSN())
+	cseq := m.CSeq
+	if cseq < 1 {
+		cseq = sip.GetSN()
+	}
+	fmt.Fprintf(w, "%s RTSP/1.0\r\nCSeq: %d\r\n", cmd, cseq)
+}
+
+// HandleResponse 处理 INFO 的响应，用于 sip.Server.ResponseFunc ，
+// 不是 SendInfoXX 的请求直接返回
+func HandleResponse(ctx *sip.Response) {
+	if _, ok := ctx.ReqData.(*Info); !ok {
+		return
+	}
+	if ctx.Status() != sip.StatusOK {
+		ctx.Finish(&sip.ResponseError{Status: ctx.Status(), Phrase: ctx.Phrase()})
+		return
+	}
+	ctx.Finish(nil)
 }
 
 // SendInfoRaw 用于级联转发，因为 body 的数据不变
@@ -0,0 +1,82 @@
+package deviceconfig
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+)
+
+// DeviceConfig 是 SendXXX 的参数
+type DeviceConfig struct {
+	Ser       *sip.Server
+	Device    request.Request
+	ChannelID string
+	// 追踪标识
+	TraceID string
+}
+
+// SendBasicParam 配置基本参数
+func SendBasicParam(ctx context.Context, m *DeviceConfig, data *xml.MessageBasicParam) (string, error) {
+	return send(ctx, m, &xml.Message{BasicParam: data})
+}
+
+// SendSVACEncodeConfig 配置 SVAC 编码
+func SendSVACEncodeConfig(ctx context.Context, m *DeviceConfig, data *xml.MessageSVACEncodeConfig) (string, error) {
+	return send(ctx, m, &xml.Message{SVACEncodeConfig: data})
+}
+
+// SendSVACDecodeConfig 配置 SVAC 解码
+func SendSVACDecodeConfig(ctx context.Context, m *DeviceConfig, data *xml.MessageSVACDecodeConfig) (string, error) {
+	return send(ctx, m, &xml.Message{SVACDecodeConfig: data})
+}
+
+// SendVideoParamAttribute 配置视频参数属性，2022
+func SendVideoParamAttribute(ctx context.Context, m *DeviceConfig, data *xml.MessageVideoParamAttribute) (string, error) {
+	data.Num = int64(len(data.Item))
+	return send(ctx, m, &xml.Message{VideoParamAttribute: data})
+}
+
+// SendOSDConfig 配置 OSD ，2022
+func SendOSDConfig(ctx context.Context, m *DeviceConfig, data *xml.MessageOSDConfig) (string, error) {
+	data.SumNum = int64(len(data.Item))
+	return send(ctx, m, &xml.Message{OSDConfig: data})
+}
+
+// SendPictureMask 配置画面遮挡，2022
+func SendPictureMask(ctx context.Context, m *DeviceConfig, data *xml.MessagePictureMask) (string, error) {
+	data.SumNum = 0
+	if data.RegionList != nil {
+		data.SumNum = int64(len(data.RegionList.Item))
+	}
+	return send(ctx, m, &xml.Message{PictureMask: data})
+}
+
+// SendFrameMirror 配置画面翻转，2022
+func SendFrameMirror(ctx context.Context, m *DeviceConfig, mirror string) (string, error) {
+	return send(ctx, m, &xml.Message{FrameMirror: mirror})
+}
+
+// SendAlarmReport 配置报警上报开关，2022
+func SendAlarmReport(ctx context.Context, m *DeviceConfig, data *xml.MessageAlarmReport) (string, error) {
+	return send(ctx, m, &xml.Message{AlarmReport: data})
+}
+
+// SendSnapShotConfig 配置图像抓拍，设备收到后开始抓拍并上传，2022
+func SendSnapShotConfig(ctx context.Context, m *DeviceConfig, data *xml.MessageSnapShotConfig) (string, error) {
+	return send(ctx, m, &xml.Message{SnapShotConfig: data})
+}
+
+// send 封装代码，返回 Response-DeviceConfig 的 Result
+func send(ctx context.Context, m *DeviceConfig, body *xml.Message) (string, error) {
+	// 消息
+	body.XMLName.Local = xml.TypeControl
+	body.CmdType = xml.CmdDeviceConfig
+	// 通道编号
+	body.DeviceID = m.ChannelID
+	body.SN = sip.GetSNString()
+	// 请求
+	var res request.XMLResult
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, body, &res)
+	return res.Result, err
+}
@@ -0,0 +1,64 @@
+package devicecontrol
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+)
+
+// Position3D 是 SendPosition3D 的参数，
+// 坐标是播放窗口中的像素值，从起点拖动到终点
+type Position3D struct {
+	Ser       *sip.Server
+	Device    request.Request
+	ChannelID string
+	// 播放窗口长度和宽度像素值
+	Length int64
+	Width  int64
+	// 拖动的起点
+	StartX int64
+	StartY int64
+	// 拖动的终点
+	EndX int64
+	EndY int64
+	// 追踪标识
+	TraceID string
+}
+
+// DragZoom 返回拖动对应的拉框数据，以及是否放大，
+// 从左往右拖动是放大，从右往左是缩小
+func (m *Position3D) DragZoom() (*xml.MessageDragZoom, bool) {
+	d := new(xml.MessageDragZoom)
+	d.Length = m.Length
+	d.Width = m.Width
+	d.MidPointX = (m.StartX + m.EndX) / 2
+	d.MidPointY = (m.StartY + m.EndY) / 2
+	d.LengthX = abs(m.EndX - m.StartX)
+	d.LengthY = abs(m.EndY - m.StartY)
+	return d, m.EndX >= m.StartX
+}
+
+// SendPosition3D 3D 拖动定位，转换为拉框放大或者拉框缩小，
+// 拖动的距离为 0 时是点击定位，把点击的位置转到画面中心
+func SendPosition3D(ctx context.Context, m *Position3D) error {
+	d, in := m.DragZoom()
+	dz := &DragZoom{
+		Ser:       m.Ser,
+		Device:    m.Device,
+		ChannelID: m.ChannelID,
+		Data:      d,
+		TraceID:   m.TraceID,
+	}
+	if in {
+		return SendDragZoomIn(ctx, dz)
+	}
+	return SendDragZoomOut(ctx, dz)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
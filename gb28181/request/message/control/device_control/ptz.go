@@ -109,6 +109,8 @@ func PTZScanSpeedValue(v6, v7 byte) int16 {
 type PTZ struct {
 	// 命令
 	Command string
+	// ParsePTZCmd 解析出的和方向组合的变倍命令
+	Zoom string
 	// 优先级
 	ControlPriority string
 	// 原始指令
@@ -0,0 +1,145 @@
+package devicecontrol
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrPTZCmdFormat 表示 PTZCmd 的格式错误
+	ErrPTZCmdFormat = errors.New("error ptz cmd format")
+	// ErrPTZCmdChecksum 表示 PTZCmd 的校验码错误
+	ErrPTZCmdChecksum = errors.New("error ptz cmd checksum")
+)
+
+// ParsePTZCmd 解析 PTZCmd 的十六进制字符串，返回的 PTZ 的 RawCmd 是 cmd ，
+// 可以使用 SendPTZRaw 转发，
+// 方向和变倍组合的指令 Command 是方向的，Zoom 是变倍的
+func ParsePTZCmd(cmd string) (*PTZ, error) {
+	b, err := hex.DecodeString(cmd)
+	if err != nil || len(b) != 8 || b[0] != 0xA5 {
+		return nil, ErrPTZCmdFormat
+	}
+	// 校验码
+	sum := 0
+	for _, n := range b[:7] {
+		sum += int(n)
+	}
+	if byte(sum%256) != b[7] {
+		return nil, ErrPTZCmdChecksum
+	}
+	//
+	m := new(PTZ)
+	m.RawCmd = strings.ToUpper(cmd)
+	m.V1 = b[4]
+	m.V2 = b[5]
+	// 标准是字节 7 的高 4 位，低 4 位是地址的高位，
+	// 高 4 位是 0 的时候兼容 PTZ.Cmd 编码在低 4 位的
+	m.V3 = b[6] >> 4
+	if m.V3 == 0 {
+		m.V3 = b[6] & 0x0F
+	}
+	code := b[3]
+	switch {
+	case code == 0x00:
+		m.Command = CmdPTZStop
+	case code < 0x40:
+		m.Command = ptzDirection(code & 0x0F)
+		switch code & 0x30 {
+		case 0x10:
+			m.Zoom = CmdPTZZoomIn
+		case 0x20:
+			m.Zoom = CmdPTZZoomOut
+		}
+		if m.Command == "" {
+			m.Command = m.Zoom
+			m.Zoom = ""
+		}
+	case code == 0x40:
+		m.Command = CmdFIStop
+	case code < 0x50:
+		m.Command = fiCommand(code)
+	case code == 0x81:
+		m.Command = CmdPresetSet
+	case code == 0x82:
+		m.Command = CmdPresetCall
+	case code == 0x83:
+		m.Command = CmdPresetDelete
+	case code == 0x84:
+		m.Command = CmdCruiseAdd
+	case code == 0x85:
+		m.Command = CmdCruiseDelete
+	case code == 0x86:
+		m.Command = CmdCruiseSpeed
+	case code == 0x87:
+		m.Command = CmdCruiseStay
+	case code == 0x88:
+		m.Command = CmdCruiseStart
+	case code == 0x89:
+		switch m.V2 {
+		case 0x00:
+			m.Command = CmdScanStart
+		case 0x01:
+			m.Command = CmdScanLeft
+		case 0x02:
+			m.Command = CmdScanRight
+		}
+	case code == 0x8A:
+		m.Command = CmdScanSpeed
+	case code == 0x8C:
+		m.Command = CmdAssistStart
+	case code == 0x8D:
+		m.Command = CmdAssistStop
+	}
+	if m.Command == "" {
+		return nil, ErrPTZCmdFormat
+	}
+	return m, nil
+}
+
+// ptzDirection 返回方向的命令
+func ptzDirection(code byte) string {
+	switch code {
+	case 0x08:
+		return CmdPTZUp
+	case 0x04:
+		return CmdPTZDownn
+	case 0x02:
+		return CmdPTZLeft
+	case 0x01:
+		return CmdPTZRight
+	case 0x0a:
+		return CmdPTZLeftUp
+	case 0x06:
+		return CmdPTZLeftDown
+	case 0x09:
+		return CmdPTZRightUp
+	case 0x05:
+		return CmdPTZRightDown
+	}
+	return ""
+}
+
+// fiCommand 返回聚焦和光圈的命令
+func fiCommand(code byte) string {
+	switch code {
+	case 0x48:
+		return CmdFIIrisOut
+	case 0x44:
+		return CmdFIIrisIn
+	case 0x42:
+		return CmdFIFocusNear
+	case 0x41:
+		return CmdFIFocusFar
+	case 0x49:
+		return CmdFIIrisOutFocusFar
+	case 0x4a:
+		return CmdFIIrisOutFocusNear
+	case 0x45:
+		return CmdFIIrisInFocusFar
+	case 0x46:
+		return CmdFIIrisInFocusNear
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package devicecontrol
+
+import (
+	"fmt"
+	"testing"
+)
+
+// stdCmd 返回标准编码的指令，b7 是字节 7 ，高 4 位是数据，低 4 位是地址高位
+func stdCmd(code, v1, v2, b7 byte) string {
+	b := []byte{0xA5, 0x0F, 0x01, code, v1, v2, b7, 0}
+	sum := 0
+	for _, n := range b[:7] {
+		sum += int(n)
+	}
+	b[7] = byte(sum % 256)
+	return fmt.Sprintf("%X", b)
+}
+
+func Test_ParsePTZCmd_Standard(t *testing.T) {
+	tests := []struct {
+		name          string
+		cmd           string
+		command, zoom string
+		v1, v2, v3    byte
+	}{
+		{"zoom in", stdCmd(0x10, 0, 0, 0x50), CmdPTZZoomIn, "", 0, 0, 0x05},
+		{"zoom out with address", stdCmd(0x20, 0, 0, 0xF2), CmdPTZZoomOut, "", 0, 0, 0x0F},
+		{"right and zoom in", stdCmd(0x11, 0x20, 0, 0x81), CmdPTZRight, CmdPTZZoomIn, 0x20, 0, 0x08},
+		{"left up and zoom out", stdCmd(0x2A, 0x10, 0x30, 0x30), CmdPTZLeftUp, CmdPTZZoomOut, 0x10, 0x30, 0x03},
+		{"scan speed", stdCmd(0x8A, 0x01, 0x23, 0x10), CmdScanSpeed, "", 0x01, 0x23, 0x01},
+		{"preset call", stdCmd(0x82, 0, 0x05, 0x00), CmdPresetCall, "", 0, 0x05, 0},
+		// 不标准的，编码在低 4 位
+		{"zoom in low nibble", stdCmd(0x10, 0, 0, 0x05), CmdPTZZoomIn, "", 0, 0, 0x05},
+	}
+	for _, tt := range tests {
+		m, err := ParsePTZCmd(tt.cmd)
+		if err != nil {
+			t.Fatalf("%s parse %s %v", tt.name, tt.cmd, err)
+		}
+		if m.Command != tt.command || m.Zoom != tt.zoom {
+			t.Errorf("%s command %s zoom %s, want %s %s", tt.name, m.Command, m.Zoom, tt.command, tt.zoom)
+		}
+		if m.V1 != tt.v1 || m.V2 != tt.v2 || m.V3 != tt.v3 {
+			t.Errorf("%s values %x %x %x, want %x %x %x", tt.name, m.V1, m.V2, m.V3, tt.v1, tt.v2, tt.v3)
+		}
+	}
+}
+
+func Test_ParsePTZCmd_Cmd(t *testing.T) {
+	// PTZ.Cmd 编码的都能解析，没有单独编码的命令解析为 CmdPTZStop
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{CmdPTZZoomOut, CmdPTZZoomOut},
+		{CmdPTZZoomIn, CmdPTZZoomIn},
+		{CmdPTZUp, CmdPTZUp},
+		{CmdPTZDownn, CmdPTZDownn},
+		{CmdPTZLeft, CmdPTZLeft},
+		{CmdPTZLeftUp, CmdPTZLeftUp},
+		{CmdPTZLeftDown, CmdPTZLeftDown},
+		{CmdPTZRight, CmdPTZRight},
+		{CmdPTZRightUp, CmdPTZRightUp},
+		{CmdPTZRightDown, CmdPTZRightDown},
+		{CmdPTZStop, CmdPTZStop},
+		{CmdFIFocusNear, CmdFIFocusNear},
+		{CmdFIFocusFar, CmdFIFocusFar},
+		{CmdFIIrisOut, CmdFIIrisOut},
+		{CmdFIIrisOutFocusFar, CmdFIIrisOutFocusFar},
+		{CmdFIIrisOutFocusNear, CmdFIIrisOutFocusNear},
+		{CmdFIIrisIn, CmdFIIrisIn},
+		{CmdFIIrisInFocusFar, CmdFIIrisInFocusFar},
+		{CmdFIIrisInFocusNear, CmdFIIrisInFocusNear},
+		{CmdFIStop, CmdFIStop},
+		{CmdPresetSet, CmdPresetSet},
+		{CmdPresetCall, CmdPresetCall},
+		{CmdPresetDelete, CmdPresetDelete},
+		{CmdCruiseAdd, CmdCruiseAdd},
+		{CmdCruiseDelete, CmdCruiseDelete},
+		{CmdCruiseSpeed, CmdCruiseSpeed},
+		{CmdCruiseStay, CmdCruiseStay},
+		{CmdCruiseStart, CmdCruiseStart},
+		{CmdCruiseStop, CmdPTZStop},
+		{CmdScanStart, CmdScanStart},
+		{CmdScanLeft, CmdScanLeft},
+		{CmdScanRight, CmdScanRight},
+		{CmdScanSpeed, CmdScanSpeed},
+		{CmdScanStop, CmdPTZStop},
+		{CmdAssistStart, CmdAssistStart},
+		{CmdAssistStop, CmdAssistStop},
+	}
+	for _, tt := range tests {
+		for _, v3 := range []byte{0x00, 0x01, 0x0F} {
+			src := &PTZ{Command: tt.cmd, V1: 0x12, V2: 0x34, V3: v3}
+			cmd, err := src.Cmd()
+			if err != nil {
+				t.Fatalf("%s cmd %v", tt.cmd, err)
+			}
+			m, err := ParsePTZCmd(cmd)
+			if err != nil {
+				t.Fatalf("%s parse %s %v", tt.cmd, cmd, err)
+			}
+			if m.Command != tt.want {
+				t.Errorf("%s parse %s command %s, want %s", tt.cmd, cmd, m.Command, tt.want)
+			}
+			if m.RawCmd != cmd {
+				t.Errorf("%s raw cmd %s, want %s", tt.cmd, m.RawCmd, cmd)
+			}
+		}
+	}
+	// 变倍速度在低 4 位也能解析
+	for v3 := byte(0); v3 < 0x10; v3++ {
+		cmd, _ := (&PTZ{Command: CmdPTZZoomIn, V3: v3}).Cmd()
+		m, err := ParsePTZCmd(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.V3 != v3 {
+			t.Errorf("%s v3 %d, want %d", cmd, m.V3, v3)
+		}
+	}
+}
+
+func Test_ParsePTZCmd_Error(t *testing.T) {
+	tests := []struct {
+		cmd string
+		err error
+	}{
+		{"", ErrPTZCmdFormat},
+		{"zz", ErrPTZCmdFormat},
+		{"A50F0108003000", ErrPTZCmdFormat},
+		{"B50F0108003000E5", ErrPTZCmdFormat},
+		{"A50F0108003000E6", ErrPTZCmdChecksum},
+		{"A50F01FF000000B4", ErrPTZCmdFormat},
+	}
+	for _, tt := range tests {
+		if _, err := ParsePTZCmd(tt.cmd); err != tt.err {
+			t.Errorf("%q err %v, want %v", tt.cmd, err, tt.err)
+		}
+	}
+}
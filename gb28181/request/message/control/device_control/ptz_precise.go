@@ -0,0 +1,34 @@
+package devicecontrol
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+)
+
+// PTZPrecise 是 SendPTZPrecise 的参数
+type PTZPrecise struct {
+	Ser       *sip.Server
+	Device    request.Request
+	ChannelID string
+	//
+	Data *xml.MessagePTZPreciseCtrl
+	// 追踪标识
+	TraceID string
+}
+
+// SendPTZPrecise PTZ 精准控制，转到指定的绝对位置，2022
+func SendPTZPrecise(ctx context.Context, m *PTZPrecise) (string, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeControl
+	body.CmdType = xml.CmdDeviceControl
+	body.DeviceID = m.ChannelID
+	body.SN = sip.GetSNString()
+	body.PTZPreciseCtrl = m.Data
+	// 请求
+	var res request.XMLResult
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, &res)
+	return res.Result, err
+}
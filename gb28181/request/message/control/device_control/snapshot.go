@@ -0,0 +1,35 @@
+package devicecontrol
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+)
+
+// SnapShot 是 SendSnapShot 的参数
+type SnapShot struct {
+	Ser       *sip.Server
+	Device    request.Request
+	ChannelID string
+	// 抓拍配置
+	Data *xml.MessageSnapShotConfig
+	// 追踪标识
+	TraceID string
+}
+
+// SendSnapShot 图像抓拍，设备抓拍后上传到 Data.UploadURL ，
+// 完成后发送 Notify-UploadSnapShotFinished ，2022
+func SendSnapShot(ctx context.Context, m *SnapShot) (string, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeControl
+	body.CmdType = xml.CmdDeviceControl
+	body.DeviceID = m.ChannelID
+	body.SN = sip.GetSNString()
+	body.SnapShotConfig = m.Data
+	// 请求
+	var res request.XMLResult
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, &res)
+	return res.Result, err
+}
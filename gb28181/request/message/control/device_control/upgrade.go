@@ -0,0 +1,34 @@
+package devicecontrol
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+)
+
+// Upgrade 是 SendUpgrade 的参数
+type Upgrade struct {
+	Ser    *sip.Server
+	Device request.Request
+	// 升级参数
+	Data *xml.MessageDeviceUpgrade
+	// 追踪标识
+	TraceID string
+}
+
+// SendUpgrade 设备软件升级，设备下载 Data.FileURL 的固件升级，
+// 完成后发送 Notify-DeviceUpgradeResult ，2022
+func SendUpgrade(ctx context.Context, m *Upgrade) (string, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeControl
+	body.CmdType = xml.CmdDeviceControl
+	body.DeviceID = m.Device.GetToID()
+	body.SN = sip.GetSNString()
+	body.DeviceUpgrade = m.Data
+	// 请求
+	var res request.XMLResult
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, &res)
+	return res.Result, err
+}
@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+)
+
+// Broadcast 是 SendBroadcast 的参数
+type Broadcast struct {
+	Ser    *sip.Server
+	Device request.Request
+	// 语音输入设备的设备编码，一般是平台的编号
+	SourceID string
+	// 语音输出设备的设备编码，也就是设备的音频输出通道
+	TargetID string
+	// 追踪标识
+	TraceID string
+}
+
+// SendBroadcast 语音广播通知，等待设备的 Response-Broadcast 并返回 Result ，
+// 设备的应答使用 TargetID 和 SN 匹配
+func SendBroadcast(ctx context.Context, m *Broadcast) (string, error) {
+	// 地址
+	addr, err := m.Device.GetNetAddr()
+	if err != nil {
+		return "", err
+	}
+	// 消息体
+	var body xml.Broadcast
+	body.XMLName.Local = xml.TypeNotify
+	body.CmdType = xml.CmdBroadcast
+	body.SN = sip.GetSNString()
+	body.SourceID = m.SourceID
+	body.TargetID = m.TargetID
+	// 消息
+	msg := request.New(m.Device, addr.Network(), "", sip.MethodMessage, request.ContentTypeXML)
+	xml.Encode(&msg.Body, m.Device.GetXMLEncoding(), &body)
+	// 应答
+	var result request.XMLResult
+	rep := request.AddReply(m.TargetID, body.SN, &result, m.Ser.MsgTimeout())
+	// 请求
+	if err := m.Ser.RequestWithContext(ctx, m.TraceID, msg, addr, rep); err != nil {
+		rep.Finish(nil, err)
+		return "", err
+	}
+	// 等待
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-rep.Done():
+		return result.Result, rep.Err()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"goutil/gb28181/request"
 	"goutil/gb28181/xml"
 	"goutil/sip"
+	"sync"
 )
 
 // Catalog 是 SendCatalog 的参数
@@ -17,6 +18,34 @@ type Catalog struct {
 	Item []*xml.Device
 	// 追踪标识
 	TraceID string
+	// 分包并发添加 Item
+	lock sync.Mutex
+}
+
+// AddItem 添加接收到的目录项，返回 true 表示已经收齐 sumNum 个
+func (m *Catalog) AddItem(sumNum int64, items ...*xml.Device) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	m.Item = append(m.Item, items...)
+	return int64(len(m.Item)) >= sumNum
+}
+
+// Items 返回已接收的目录项
+func (m *Catalog) Items() []*xml.Device {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	return m.Item
+}
+
+// handleResponse 处理 Response-Catalog ，返回是否已经收齐
+func (m *Catalog) handleResponse(msg *xml.Message) bool {
+	var items []*xml.Device
+	if msg.DeviceList != nil {
+		items = msg.DeviceList.Item
+	}
+	return m.AddItem(msg.SumNum, items...)
 }
 
 // SendCatalog 目录查询
@@ -30,5 +59,6 @@ func SendCatalog(ctx context.Context, m *Catalog) ([]*xml.Device, error) {
 	body.StartTime = m.StartTime
 	body.EndTime = m.EndTime
 	// 请求
-	return m.Item, request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	return m.Items(), err
 }
@@ -0,0 +1,160 @@
+package query
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	"strings"
+	"sync"
+)
+
+// ConfigDownload 是 SendConfigDownload 的参数
+type ConfigDownload struct {
+	Ser       *sip.Server
+	Device    request.Request
+	ChannelID string
+	// 查询的配置类型，xml.ConfigXXX
+	ConfigType []string
+	// 结果
+	lock   sync.Mutex
+	result *xml.Message
+	// 已经收到的配置类型
+	got map[string]bool
+	// 追踪标识
+	TraceID string
+}
+
+// AddResult 合并一个应答，一个应答可能有多个配置类型，
+// 重复的应答不影响，返回查询的配置类型是否已经收齐，
+// 没有任何配置的失败应答表示设备拒绝了，也返回 true
+func (m *ConfigDownload) AddResult(msg *xml.Message) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.result == nil {
+		m.result = msg
+	} else {
+		mergeConfig(m.result, msg)
+	}
+	// 记录
+	types := configTypes(msg)
+	if len(types) < 1 && msg.Result != "" && msg.Result != "OK" {
+		return true
+	}
+	if m.got == nil {
+		m.got = make(map[string]bool)
+	}
+	for _, t := range types {
+		m.got[t] = true
+	}
+	// 检查
+	for _, t := range m.ConfigType {
+		if !m.got[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// Result 返回合并后的应答
+func (m *ConfigDownload) Result() *xml.Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.result
+}
+
+// handleResponse 处理 Response-ConfigDownload ，返回是否已经收齐
+func (m *ConfigDownload) handleResponse(msg *xml.Message) bool {
+	return m.AddResult(msg)
+}
+
+// configTypes 返回 msg 中有的配置类型
+func configTypes(msg *xml.Message) []string {
+	var types []string
+	if msg.BasicParam != nil {
+		types = append(types, xml.ConfigBasicParam)
+	}
+	if msg.VideoParamOpt != nil {
+		types = append(types, xml.ConfigVideoParamOpt)
+	}
+	if msg.SVACEncodeConfig != nil {
+		types = append(types, xml.ConfigSVACEncodeConfig)
+	}
+	if msg.SVACDecodeConfig != nil {
+		types = append(types, xml.ConfigSVACDecodeConfig)
+	}
+	if msg.VideoParamAttribute != nil {
+		types = append(types, xml.ConfigVideoParamAttribute)
+	}
+	if msg.OSDConfig != nil {
+		types = append(types, xml.ConfigOSDConfig)
+	}
+	if msg.PictureMask != nil {
+		types = append(types, xml.ConfigPictureMask)
+	}
+	if msg.FrameMirror != "" {
+		types = append(types, xml.ConfigFrameMirror)
+	}
+	if msg.AlarmReport != nil {
+		types = append(types, xml.ConfigAlarmReport)
+	}
+	if msg.SnapShotConfig != nil {
+		types = append(types, xml.ConfigSnapShotConfig)
+	}
+	return types
+}
+
+// mergeConfig 把 src 的配置合并到 dst
+func mergeConfig(dst, src *xml.Message) {
+	if src.Result != "" && dst.Result != src.Result {
+		// 有一个失败就是失败
+		if dst.Result == "" || src.Result != "OK" {
+			dst.Result = src.Result
+		}
+	}
+	if src.BasicParam != nil {
+		dst.BasicParam = src.BasicParam
+	}
+	if src.VideoParamOpt != nil {
+		dst.VideoParamOpt = src.VideoParamOpt
+	}
+	if src.SVACEncodeConfig != nil {
+		dst.SVACEncodeConfig = src.SVACEncodeConfig
+	}
+	if src.SVACDecodeConfig != nil {
+		dst.SVACDecodeConfig = src.SVACDecodeConfig
+	}
+	if src.VideoParamAttribute != nil {
+		dst.VideoParamAttribute = src.VideoParamAttribute
+	}
+	if src.OSDConfig != nil {
+		dst.OSDConfig = src.OSDConfig
+	}
+	if src.PictureMask != nil {
+		dst.PictureMask = src.PictureMask
+	}
+	if src.FrameMirror != "" {
+		dst.FrameMirror = src.FrameMirror
+	}
+	if src.AlarmReport != nil {
+		dst.AlarmReport = src.AlarmReport
+	}
+	if src.SnapShotConfig != nil {
+		dst.SnapShotConfig = src.SnapShotConfig
+	}
+}
+
+// SendConfigDownload 查询设备配置，多个配置类型的应答会合并，
+// 超时返回已经收到的和错误
+func SendConfigDownload(ctx context.Context, m *ConfigDownload) (*xml.Message, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeQuery
+	body.CmdType = xml.CmdConfigDownload
+	body.DeviceID = m.ChannelID
+	body.SN = sip.GetSNString()
+	body.ConfigType = strings.Join(m.ConfigType, "/")
+	// 请求
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	return m.Result(), err
+}
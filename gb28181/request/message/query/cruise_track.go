@@ -0,0 +1,114 @@
+package query
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	"sync"
+)
+
+// CruiseTrackList 是 SendCruiseTrackList 的参数
+type CruiseTrackList struct {
+	Ser       *sip.Server
+	Device    request.Request
+	ChannelID string
+	// 结果
+	result *xml.Message
+	// 追踪标识
+	TraceID string
+	// 应答和查询在不同的协程
+	lock sync.Mutex
+}
+
+// SetResult 设置应答的消息
+func (m *CruiseTrackList) SetResult(msg *xml.Message) {
+	m.lock.Lock()
+	m.result = msg
+	m.lock.Unlock()
+}
+
+// Result 返回应答的消息
+func (m *CruiseTrackList) Result() *xml.Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	return m.result
+}
+
+// handleResponse 处理 Response-CruiseTrackListQuery ，只有一个包
+func (m *CruiseTrackList) handleResponse(msg *xml.Message) bool {
+	m.SetResult(msg)
+	return true
+}
+
+// SendCruiseTrackList 查询巡航轨迹列表，2022
+func SendCruiseTrackList(ctx context.Context, m *CruiseTrackList) ([]*xml.MessageCruiseTrackListItem, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeQuery
+	body.CmdType = xml.CmdCruiseTrackListQuery
+	body.DeviceID = m.ChannelID
+	body.SN = sip.GetSNString()
+	// 请求
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	res := m.Result()
+	if res == nil || res.CruiseTrackList == nil {
+		return nil, err
+	}
+	return res.CruiseTrackList.Item, err
+}
+
+// CruiseTrack 是 SendCruiseTrack 的参数
+type CruiseTrack struct {
+	Ser       *sip.Server
+	Device    request.Request
+	ChannelID string
+	// 巡航轨迹编号
+	Number string
+	// 结果
+	result *xml.Message
+	// 追踪标识
+	TraceID string
+	// 应答和查询在不同的协程
+	lock sync.Mutex
+}
+
+// SetResult 设置应答的消息
+func (m *CruiseTrack) SetResult(msg *xml.Message) {
+	m.lock.Lock()
+	m.result = msg
+	m.lock.Unlock()
+}
+
+// Result 返回应答的消息
+func (m *CruiseTrack) Result() *xml.Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	return m.result
+}
+
+// handleResponse 处理 Response-CruiseTrackQuery ，只有一个包
+func (m *CruiseTrack) handleResponse(msg *xml.Message) bool {
+	m.SetResult(msg)
+	return true
+}
+
+// SendCruiseTrack 查询巡航轨迹，2022
+func SendCruiseTrack(ctx context.Context, m *CruiseTrack) (*xml.MessageCruiseTrack, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeQuery
+	body.CmdType = xml.CmdCruiseTrackQuery
+	body.DeviceID = m.ChannelID
+	body.SN = sip.GetSNString()
+	body.Number = m.Number
+	// 请求
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	res := m.Result()
+	if res == nil {
+		return nil, err
+	}
+	return res.CruiseTrack, err
+}
@@ -0,0 +1,55 @@
+package query
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	"sync"
+)
+
+// DeviceInfo 是 SendDeviceInfo 的参数
+type DeviceInfo struct {
+	Ser    *sip.Server
+	Device request.Request
+	// 结果
+	result *xml.Message
+	// 追踪标识
+	TraceID string
+	// 应答和查询在不同的协程
+	lock sync.Mutex
+}
+
+// SetResult 设置应答的消息
+func (m *DeviceInfo) SetResult(msg *xml.Message) {
+	m.lock.Lock()
+	m.result = msg
+	m.lock.Unlock()
+}
+
+// Result 返回应答的消息
+func (m *DeviceInfo) Result() *xml.Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	return m.result
+}
+
+// handleResponse 处理 Response-DeviceInfo ，只有一个包
+func (m *DeviceInfo) handleResponse(msg *xml.Message) bool {
+	m.SetResult(msg)
+	return true
+}
+
+// SendDeviceInfo 查询设备信息
+func SendDeviceInfo(ctx context.Context, m *DeviceInfo) (*xml.Message, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeQuery
+	body.CmdType = xml.CmdDeviceInfo
+	body.DeviceID = m.Device.GetToID()
+	body.SN = sip.GetSNString()
+	// 请求
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	return m.Result(), err
+}
@@ -0,0 +1,55 @@
+package query
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	"sync"
+)
+
+// DeviceStatus 是 SendDeviceStatus 的参数
+type DeviceStatus struct {
+	Ser    *sip.Server
+	Device request.Request
+	// 结果
+	result *xml.Message
+	// 追踪标识
+	TraceID string
+	// 应答和查询在不同的协程
+	lock sync.Mutex
+}
+
+// SetResult 设置应答的消息
+func (m *DeviceStatus) SetResult(msg *xml.Message) {
+	m.lock.Lock()
+	m.result = msg
+	m.lock.Unlock()
+}
+
+// Result 返回应答的消息
+func (m *DeviceStatus) Result() *xml.Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	return m.result
+}
+
+// handleResponse 处理 Response-DeviceStatus ，只有一个包
+func (m *DeviceStatus) handleResponse(msg *xml.Message) bool {
+	m.SetResult(msg)
+	return true
+}
+
+// SendDeviceStatus 查询设备状态
+func SendDeviceStatus(ctx context.Context, m *DeviceStatus) (*xml.Message, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeQuery
+	body.CmdType = xml.CmdDeviceStatus
+	body.DeviceID = m.Device.GetToID()
+	body.SN = sip.GetSNString()
+	// 请求
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	return m.Result(), err
+}
@@ -5,6 +5,7 @@ import (
 	"goutil/gb28181/request"
 	"goutil/gb28181/xml"
 	"goutil/sip"
+	"sync"
 )
 
 // PresetQuery 是 SendPresetQuery 的参数
@@ -18,6 +19,40 @@ type PresetQuery struct {
 	Total int64
 	// 追踪标识
 	TraceID string
+	// 分包并发添加 Item
+	lock sync.Mutex
+}
+
+// AddItem 添加接收到的预置位，返回 true 表示已经收齐 total 个
+func (m *PresetQuery) AddItem(total int64, items ...*xml.MessagePresetListItem) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	m.Total = total
+	m.Item = append(m.Item, items...)
+	return int64(len(m.Item)) >= total
+}
+
+// Items 返回已接收的预置位
+func (m *PresetQuery) Items() []*xml.MessagePresetListItem {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	return m.Item
+}
+
+// handleResponse 处理 Response-PresetQuery ，返回是否已经收齐
+func (m *PresetQuery) handleResponse(msg *xml.Message) bool {
+	var items []*xml.MessagePresetListItem
+	total := msg.SumNum
+	if msg.PresetList != nil {
+		items = msg.PresetList.Item
+		// 没有 SumNum 就是只有一个包
+		if total < 1 {
+			total = msg.PresetList.Num
+		}
+	}
+	return m.AddItem(total, items...)
 }
 
 // SendPresetQuery 查询预置位
@@ -29,5 +64,6 @@ func SendPresetQuery(ctx context.Context, m *PresetQuery) ([]*xml.MessagePresetL
 	body.DeviceID = m.ChannelID
 	body.SN = sip.GetSNString()
 	// 请求
-	return m.Item, request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	return m.Items(), err
 }
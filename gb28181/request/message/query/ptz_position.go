@@ -0,0 +1,56 @@
+package query
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	"sync"
+)
+
+// PTZPosition 是 SendPTZPosition 的参数
+type PTZPosition struct {
+	Ser       *sip.Server
+	Device    request.Request
+	ChannelID string
+	// 结果
+	result *xml.Message
+	// 追踪标识
+	TraceID string
+	// 应答和查询在不同的协程
+	lock sync.Mutex
+}
+
+// SetResult 设置应答的消息
+func (m *PTZPosition) SetResult(msg *xml.Message) {
+	m.lock.Lock()
+	m.result = msg
+	m.lock.Unlock()
+}
+
+// Result 返回应答的消息
+func (m *PTZPosition) Result() *xml.Message {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	return m.result
+}
+
+// handleResponse 处理 Response-PTZPosition ，只有一个包
+func (m *PTZPosition) handleResponse(msg *xml.Message) bool {
+	m.SetResult(msg)
+	return true
+}
+
+// SendPTZPosition 查询云台的位置，2022
+func SendPTZPosition(ctx context.Context, m *PTZPosition) (*xml.Message, error) {
+	// 消息
+	var body xml.Message
+	body.XMLName.Local = xml.TypeQuery
+	body.CmdType = xml.CmdPTZPosition
+	body.DeviceID = m.ChannelID
+	body.SN = sip.GetSNString()
+	// 请求
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	return m.Result(), err
+}
@@ -0,0 +1,133 @@
+package query
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+)
+
+// responder 是可以处理应答的查询，
+// 每个查询在自己的文件中实现怎么合并应答
+type responder interface {
+	// 返回 true 表示已经收齐
+	handleResponse(msg *xml.Message) bool
+}
+
+// HandleResponse 处理设备的查询应答，根据 DeviceID 和 SN 找到对应的查询，
+// 分包的应答收齐 SumNum 个之后结束查询，每收到一个分包都会重置超时时间，
+// 返回 false 表示没有对应的查询，用于 MESSAGE 的回调
+func HandleResponse(msg *xml.Message) bool {
+	rep := request.GetReply(msg.DeviceID, msg.SN)
+	if rep == nil {
+		return false
+	}
+	var done bool
+	switch m := rep.Value(nil).(type) {
+	case responder:
+		done = m.handleResponse(msg)
+	case *request.XMLResult:
+		m.Result = msg.Result
+		done = true
+	default:
+		return false
+	}
+	if done {
+		rep.Finish(nil, nil)
+	} else {
+		rep.ResetDeadline()
+	}
+	return true
+}
+
+// QueryCatalog 查询目录，返回所有分包的目录项，超时返回已经收到的和错误
+func QueryCatalog(ctx context.Context, trace string, ser *sip.Server, dev request.Request) ([]*xml.Device, error) {
+	return SendCatalog(ctx, &Catalog{
+		Ser:     ser,
+		Device:  dev,
+		TraceID: trace,
+	})
+}
+
+// QueryRecordInfo 查询录像，返回所有分包的录像，超时返回已经收到的和错误
+func QueryRecordInfo(ctx context.Context, trace string, ser *sip.Server, dev request.Request, channelID, startTime, endTime string) ([]*xml.Record, error) {
+	return SendRecordInfo(ctx, &RecordInfo{
+		Ser:       ser,
+		Device:    dev,
+		ChannelID: channelID,
+		StartTime: startTime,
+		EndTime:   endTime,
+		TraceID:   trace,
+	})
+}
+
+// QueryPreset 查询预置位，超时返回已经收到的和错误
+func QueryPreset(ctx context.Context, trace string, ser *sip.Server, dev request.Request, channelID string) ([]*xml.MessagePresetListItem, error) {
+	return SendPresetQuery(ctx, &PresetQuery{
+		Ser:       ser,
+		Device:    dev,
+		ChannelID: channelID,
+		TraceID:   trace,
+	})
+}
+
+// QueryDeviceInfo 查询设备信息
+func QueryDeviceInfo(ctx context.Context, trace string, ser *sip.Server, dev request.Request) (*xml.Message, error) {
+	return SendDeviceInfo(ctx, &DeviceInfo{
+		Ser:     ser,
+		Device:  dev,
+		TraceID: trace,
+	})
+}
+
+// QueryDeviceStatus 查询设备状态
+func QueryDeviceStatus(ctx context.Context, trace string, ser *sip.Server, dev request.Request) (*xml.Message, error) {
+	return SendDeviceStatus(ctx, &DeviceStatus{
+		Ser:     ser,
+		Device:  dev,
+		TraceID: trace,
+	})
+}
+
+// QueryConfigDownload 查询设备配置，configTypes 是 xml.ConfigXXX ，
+// 多个配置类型的应答会合并到一个消息
+func QueryConfigDownload(ctx context.Context, trace string, ser *sip.Server, dev request.Request, channelID string, configTypes ...string) (*xml.Message, error) {
+	return SendConfigDownload(ctx, &ConfigDownload{
+		Ser:        ser,
+		Device:     dev,
+		ChannelID:  channelID,
+		ConfigType: configTypes,
+		TraceID:    trace,
+	})
+}
+
+// QueryPTZPosition 查询云台的位置，2022
+func QueryPTZPosition(ctx context.Context, trace string, ser *sip.Server, dev request.Request, channelID string) (*xml.Message, error) {
+	return SendPTZPosition(ctx, &PTZPosition{
+		Ser:       ser,
+		Device:    dev,
+		ChannelID: channelID,
+		TraceID:   trace,
+	})
+}
+
+// QueryCruiseTrackList 查询巡航轨迹列表，2022
+func QueryCruiseTrackList(ctx context.Context, trace string, ser *sip.Server, dev request.Request, channelID string) ([]*xml.MessageCruiseTrackListItem, error) {
+	return SendCruiseTrackList(ctx, &CruiseTrackList{
+		Ser:       ser,
+		Device:    dev,
+		ChannelID: channelID,
+		TraceID:   trace,
+	})
+}
+
+// QueryCruiseTrack 查询巡航轨迹，2022
+func QueryCruiseTrack(ctx context.Context, trace string, ser *sip.Server, dev request.Request, channelID, number string) (*xml.MessageCruiseTrack, error) {
+	return SendCruiseTrack(ctx, &CruiseTrack{
+		Ser:       ser,
+		Device:    dev,
+		ChannelID: channelID,
+		Number:    number,
+		TraceID:   trace,
+	})
+}
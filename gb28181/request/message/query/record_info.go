@@ -5,6 +5,7 @@ import (
 	"goutil/gb28181/request"
 	"goutil/gb28181/xml"
 	"goutil/sip"
+	"sync"
 )
 
 // RecordInfo 是 SendRecord 的参数
@@ -27,6 +28,35 @@ type RecordInfo struct {
 	Data any
 	// 追踪标识
 	TraceID string
+	// 分包并发添加 Item
+	lock sync.Mutex
+}
+
+// AddItem 添加接收到的录像，返回 true 表示已经收齐 sumNum 个
+func (m *RecordInfo) AddItem(sumNum int64, items ...*xml.Record) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	m.Total = sumNum
+	m.Item = append(m.Item, items...)
+	return int64(len(m.Item)) >= sumNum
+}
+
+// Items 返回已接收的录像
+func (m *RecordInfo) Items() []*xml.Record {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	//
+	return m.Item
+}
+
+// handleResponse 处理 Response-RecordInfo ，返回是否已经收齐
+func (m *RecordInfo) handleResponse(msg *xml.Message) bool {
+	var items []*xml.Record
+	if msg.RecordList != nil {
+		items = msg.RecordList.Item
+	}
+	return m.AddItem(msg.SumNum, items...)
 }
 
 // SendRecordInfo 查询录像文件
@@ -44,5 +74,6 @@ func SendRecordInfo(ctx context.Context, m *RecordInfo) ([]*xml.Record, error) {
 		body.Type = "all"
 	}
 	// 请求
-	return m.Item, request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	err := request.SendReplyMessage(ctx, m.TraceID, m.Ser, m.Device, &body, m)
+	return m.Items(), err
 }
@@ -0,0 +1,66 @@
+package register
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+)
+
+// 非对称认证 A 字段的算法名称
+const (
+	AlgorithmRSA = "RSA"
+	AlgorithmSM2 = "SM2"
+)
+
+// PrivateKey 表示非对称认证使用的私钥
+// 标准库没有 sm2 ，使用者可以自己实现这个接口
+type PrivateKey interface {
+	// Sign 签名哈希后的数据
+	Sign(hash crypto.Hash, digest []byte) ([]byte, error)
+	// Decrypt 解密
+	Decrypt(data []byte) ([]byte, error)
+}
+
+// PublicKey 表示非对称认证使用的公钥
+type PublicKey interface {
+	// Verify 验证签名
+	Verify(hash crypto.Hash, digest, sign []byte) error
+	// Encrypt 加密
+	Encrypt(data []byte) ([]byte, error)
+}
+
+// NewRSAPrivateKey 返回 rsa 实现的 PrivateKey
+func NewRSAPrivateKey(key *rsa.PrivateKey) PrivateKey {
+	return &rsaPrivateKey{key: key}
+}
+
+// rsaPrivateKey 实现 PrivateKey
+type rsaPrivateKey struct {
+	key *rsa.PrivateKey
+}
+
+func (k *rsaPrivateKey) Sign(hash crypto.Hash, digest []byte) ([]byte, error) {
+	return rsa.SignPKCS1v15(rand.Reader, k.key, hash, digest)
+}
+
+func (k *rsaPrivateKey) Decrypt(data []byte) ([]byte, error) {
+	return rsa.DecryptPKCS1v15(rand.Reader, k.key, data)
+}
+
+// NewRSAPublicKey 返回 rsa 实现的 PublicKey
+func NewRSAPublicKey(key *rsa.PublicKey) PublicKey {
+	return &rsaPublicKey{key: key}
+}
+
+// rsaPublicKey 实现 PublicKey
+type rsaPublicKey struct {
+	key *rsa.PublicKey
+}
+
+func (k *rsaPublicKey) Verify(hash crypto.Hash, digest, sign []byte) error {
+	return rsa.VerifyPKCS1v15(k.key, hash, digest, sign)
+}
+
+func (k *rsaPublicKey) Encrypt(data []byte) ([]byte, error) {
+	return rsa.EncryptPKCS1v15(rand.Reader, k.key, data)
+}
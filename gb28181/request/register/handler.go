@@ -0,0 +1,268 @@
+package register
+
+import (
+	"goutil/sip"
+	gsync "goutil/sync"
+	"goutil/uid"
+	"strconv"
+	"time"
+)
+
+const (
+	// DateFormat 是 200 响应 Date 字段的时间格式，设备用来校时
+	DateFormat = "2006-01-02T15:04:05.000"
+	// DefaultNonceTimeout 是 nonce 默认的有效时长
+	DefaultNonceTimeout = time.Minute
+	// defaultQOP 是 Digest 认证的 qop
+	defaultQOP = "auth"
+	// defaultHash 是非对称认证默认的哈希算法
+	defaultHash = "SHA256"
+	// defaultSymmetric 是非对称认证默认的对称算法
+	defaultSymmetric = "SM4"
+)
+
+// Device 是 Handler 需要的设备信息
+type Device interface {
+	// GetPassword 返回 Digest 认证的密码，空字符串表示不需要认证
+	GetPassword() string
+	// GetPublicKey 返回设备的公钥，nil 表示不支持非对称认证
+	GetPublicKey() PublicKey
+}
+
+// HandlerOption 是 NewHandler 的参数
+type HandlerOption struct {
+	// Digest 认证的域，一般是服务编号的前 10 位
+	Realm string
+	// 服务端私钥，key 是算法名称，AlgorithmRSA/AlgorithmSM2
+	PrivateKey map[string]PrivateKey
+	// nonce 的有效时长，默认是 DefaultNonceTimeout
+	NonceTimeout time.Duration
+	// 返回设备，nil 表示设备不存在，返回 *sip.ResponseError 可以指定响应的状态
+	GetDevice func(ctx *sip.Request, id string) (Device, error)
+	// 认证成功的回调，返回错误则响应失败
+	OnRegister func(ctx *sip.Request, dev Device, expires int64) error
+	// 注销的回调，也就是 Expires 为 0 ，返回错误则响应失败
+	OnUnregister func(ctx *sip.Request, dev Device) error
+}
+
+// Handler 用于处理设备发送的 REGISTER 请求，
+// 支持 Digest 和 Capability/Asymmetric 两种认证方式
+type Handler struct {
+	opt HandlerOption
+	// 已经发出的 nonce
+	nonce *gsync.TimeoutContextPool
+	// 已经发出的非对称认证，key 是设备编号
+	asymmetric gsync.Map[string, *asymmetricChallenge]
+}
+
+// asymmetricChallenge 是发出的非对称认证
+type asymmetricChallenge struct {
+	// 算法名称
+	a string
+	// 服务端的随机数
+	c []byte
+	// 过期时间
+	deadline time.Time
+}
+
+// NewHandler 返回新的 Handler
+func NewHandler(opt *HandlerOption) *Handler {
+	h := new(Handler)
+	h.opt = *opt
+	if h.opt.NonceTimeout <= 0 {
+		h.opt.NonceTimeout = DefaultNonceTimeout
+	}
+	h.nonce = gsync.NewTimeoutContextPool()
+	h.asymmetric.Init()
+	return h
+}
+
+// Handle 处理 REGISTER 请求，用于 sip.Server.RequestFunc
+func (h *Handler) Handle(ctx *sip.Request) {
+	// 过期时间
+	expires, err := strconv.ParseInt(ctx.Header.Expires, 10, 64)
+	if err != nil || expires < 0 {
+		ctx.ResponseStatus(sip.StatusBadRequest)
+		return
+	}
+	// 设备
+	id := ctx.Header.From.URI.Name
+	dev, err := h.opt.GetDevice(ctx, id)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if dev == nil {
+		ctx.ResponseStatus(sip.StatusNotFound)
+		return
+	}
+	// 解析
+	var header RegisterHeader
+	if !header.Parse(ctx.Message) {
+		ctx.ResponseStatus(sip.StatusBadRequest)
+		return
+	}
+	auth := header.Authorization
+	switch {
+	case auth == nil:
+		// 不需要认证
+		if dev.GetPassword() == "" {
+			h.ok(ctx, dev, expires)
+			return
+		}
+		h.challengeDigest(ctx, id)
+	case auth.Capability != nil:
+		h.challengeAsymmetric(ctx, id, dev, auth.Capability)
+	case auth.Digest != nil:
+		h.verifyDigest(ctx, id, dev, expires, auth.Digest)
+	case auth.Asymmetric != nil:
+		h.verifyAsymmetric(ctx, id, dev, expires, auth.Asymmetric)
+	default:
+		ctx.ResponseStatus(sip.StatusBadRequest)
+	}
+}
+
+// challengeDigest 发送 Digest 的 401
+func (h *Handler) challengeDigest(ctx *sip.Request, id string) {
+	var www RegisterHeaderWWWAuthenticateDigest
+	www.Realm = h.opt.Realm
+	www.QOP = defaultQOP
+	www.Nonce = uid.UUID1NoHyphen()
+	// 保存
+	h.nonce.New(id+www.Nonce, StrDigest, h.opt.NonceTimeout)
+	// 响应
+	msg := h.newResponse(ctx, sip.StatusUnauthorized)
+	msg.Header.Set(StrWWWAuthenticate, www.String())
+	ctx.Response(msg)
+}
+
+// challengeAsymmetric 发送 Asymmetric 的 401
+func (h *Handler) challengeAsymmetric(ctx *sip.Request, id string, dev Device, capability *RegisterHeaderAuthorizationCapability) {
+	var www RegisterHeaderWWWAuthenticateAsymmetric
+	www.A = capability.A
+	if www.A == "" {
+		www.A = AlgorithmSM2
+	}
+	www.H = capability.H
+	if www.H == "" {
+		www.H = defaultHash
+	}
+	www.S = capability.S
+	if www.S == "" {
+		www.S = defaultSymmetric
+	}
+	// 密钥
+	pri := h.opt.PrivateKey[www.A]
+	pub := dev.GetPublicKey()
+	if pri == nil || pub == nil {
+		// 不支持，能用 Digest 就降级
+		if dev.GetPassword() != "" {
+			h.challengeDigest(ctx, id)
+			return
+		}
+		ctx.ResponseStatus(sip.StatusForbidden)
+		return
+	}
+	// 生成
+	if err := www.GenNonceWith(pri, pub); err != nil {
+		ctx.ResponseStatus(sip.StatusServerInternalError)
+		return
+	}
+	// 保存，设备下一个请求的 nonce 是自己生成的，所以用设备编号来标记，
+	// response 必须用这次的随机数计算，新的认证替换旧的
+	h.asymmetric.Set(id, &asymmetricChallenge{
+		a:        www.A,
+		c:        www.C,
+		deadline: time.Now().Add(h.opt.NonceTimeout),
+	})
+	// 响应
+	msg := h.newResponse(ctx, sip.StatusUnauthorized)
+	msg.Header.Set(StrWWWAuthenticate, www.String())
+	ctx.Response(msg)
+}
+
+// verifyDigest 验证 Digest
+func (h *Handler) verifyDigest(ctx *sip.Request, id string, dev Device, expires int64, auth *RegisterHeaderAuthorizationDigest) {
+	// nonce 无效或者过期，重新认证
+	if auth.Realm != h.opt.Realm || !h.takeNonce(id+auth.Nonce) {
+		h.challengeDigest(ctx, id)
+		return
+	}
+	// 验证
+	if !auth.VerifyResponse(id, dev.GetPassword()) {
+		ctx.ResponseStatus(sip.StatusForbidden)
+		return
+	}
+	h.ok(ctx, dev, expires)
+}
+
+// verifyAsymmetric 验证 Asymmetric
+func (h *Handler) verifyAsymmetric(ctx *sip.Request, id string, dev Device, expires int64, auth *RegisterHeaderAuthorizationAsymmetric) {
+	// 没有发出过 nonce 或者过期，使用掉
+	ch := h.asymmetric.Take(id)
+	if ch == nil || time.Now().After(ch.deadline) {
+		ctx.ResponseStatus(sip.StatusForbidden)
+		return
+	}
+	pri := h.opt.PrivateKey[ch.a]
+	pub := dev.GetPublicKey()
+	if pri == nil || pub == nil {
+		ctx.ResponseStatus(sip.StatusForbidden)
+		return
+	}
+	// 验证，response 要用发出的随机数
+	auth.C = ch.c
+	ok, err := auth.VerifyResponseWith(pri, pub)
+	if err != nil || !ok {
+		ctx.ResponseStatus(sip.StatusForbidden)
+		return
+	}
+	h.ok(ctx, dev, expires)
+}
+
+// takeNonce 返回 key 对应的 nonce 是否有效，有效则使用掉
+func (h *Handler) takeNonce(key string) bool {
+	tc := h.nonce.Get(key)
+	if tc == nil {
+		return false
+	}
+	select {
+	case <-tc.Done():
+		// 已经使用或者过期
+		return false
+	default:
+		tc.Finish(nil, nil)
+		return true
+	}
+}
+
+// ok 认证通过，回调并响应 200
+func (h *Handler) ok(ctx *sip.Request, dev Device, expires int64) {
+	// 回调
+	var err error
+	if expires == 0 {
+		if h.opt.OnUnregister != nil {
+			err = h.opt.OnUnregister(ctx, dev)
+		}
+	} else {
+		if h.opt.OnRegister != nil {
+			err = h.opt.OnRegister(ctx, dev, expires)
+		}
+	}
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	// 响应
+	msg := h.newResponse(ctx, sip.StatusOK)
+	msg.Header.Set(StrDate, time.Now().Format(DateFormat))
+	ctx.Response(msg)
+}
+
+// newResponse 返回响应消息，去掉请求带过来的其他字段，
+// 保留 Contact 表示注册的地址
+func (h *Handler) newResponse(ctx *sip.Request, status string) *sip.Message {
+	msg := ctx.NewResponse(status, sip.StatusPhrase(status))
+	msg.Header.ResetOther()
+	return msg
+}
@@ -18,6 +18,7 @@ import (
 const (
 	StrAuthorization   = "Authorization"
 	StrWWWAuthenticate = "WWW-Authenticate"
+	StrDate            = "Date"
 )
 
 // WWW-Authenticate 算法的名称
@@ -51,13 +52,13 @@ func kvQuotationMark(line string) (string, string) {
 	return k, v
 }
 
-// rsaGenNonce 返回 pri.sign(hash(rand)), pub.enc(pub.enc(rand)), rand
-func rsaGenNonce(pri *rsa.PrivateKey, pub *rsa.PublicKey, hash crypto.Hash) ([]byte, []byte, []byte, error) {
+// genNonce 返回 pri.sign(hash(rand)), pub.enc(rand), rand
+func genNonce(pri PrivateKey, pub PublicKey, hash crypto.Hash) ([]byte, []byte, []byte, error) {
 	// 随机 c
 	c := make([]byte, 32)
 	rand.Reader.Read(c)
 	// 公钥加密 c ，得到 b
-	b, err := rsa.EncryptPKCS1v15(rand.Reader, pub, c)
+	b, err := pub.Encrypt(c)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -67,7 +68,7 @@ func rsaGenNonce(pri *rsa.PrivateKey, pub *rsa.PublicKey, hash crypto.Hash) ([]b
 	h.Write(c)
 	d := h.Sum(nil)
 	// 使用 ser 私钥签名 d ，得到 a
-	a, err := rsa.SignPKCS1v15(rand.Reader, pri, hash, d)
+	a, err := pri.Sign(hash, d)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -75,9 +76,9 @@ func rsaGenNonce(pri *rsa.PrivateKey, pub *rsa.PublicKey, hash crypto.Hash) ([]b
 	return a, b, c, nil
 }
 
-// rsaVerifyNonce 验证 nonce ，返回随机数 c
+// verifyNonce 验证 nonce ，返回随机数 c
 // 算法 pub.verify(hash(pri.dec(base64.dec(nonce2))), base64.dec(nonce1))
-func rsaVerifyNonce(pri *rsa.PrivateKey, pub *rsa.PublicKey, hash crypto.Hash, nonce string) ([]byte, error) {
+func verifyNonce(pri PrivateKey, pub PublicKey, hash crypto.Hash, nonce string) ([]byte, error) {
 	// 得到 a 和 b
 	p1, p2 := gstrings.Split(nonce, gstrings.CharAmpersand)
 	a, err := base64.StdEncoding.DecodeString(p1)
@@ -89,7 +90,7 @@ func rsaVerifyNonce(pri *rsa.PrivateKey, pub *rsa.PublicKey, hash crypto.Hash, n
 		return nil, err
 	}
 	// 私钥解密 b ，得到 c
-	c, err := rsa.DecryptPKCS1v15(rand.Reader, pri, b)
+	c, err := pri.Decrypt(b)
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +100,7 @@ func rsaVerifyNonce(pri *rsa.PrivateKey, pub *rsa.PublicKey, hash crypto.Hash, n
 	h.Write(c)
 	d := h.Sum(nil)
 	// 公钥验证 d 和 a
-	return c, rsa.VerifyPKCS1v15(pub, hash, d, a)
+	return c, pub.Verify(hash, d, a)
 }
 
 // RegisterHeader 表示 REGISTER 消息的独有字段
@@ -157,21 +158,16 @@ func (m *RegisterHeaderWWWAuthenticate) Parse(line string) bool {
 type RegisterHeaderWWWAuthenticateDigest struct {
 	Realm string
 	Nonce string
-	QOP   string
+	// 可能有多个，逗号分隔
+	QOP string
+	// 为空表示 MD5
+	Algorithm string
 }
 
-// Parse 解析 realm="x",nonce="x",qop="x"
+// Parse 解析 realm="x",nonce="x",qop="x",algorithm=x
 func (m *RegisterHeaderWWWAuthenticateDigest) Parse(line string) bool {
-	prefix, suffix := "", line
-	for {
-		// 去空白
-		suffix = gstrings.TrimByte(suffix, gstrings.CharSpace, gstrings.CharSpace)
-		if suffix == "" {
-			break
-		}
-		// kv,kv,
-		prefix, suffix = gstrings.Split(suffix, gstrings.CharComma)
-		//
+	// qop 可能是 "auth,auth-int" ，不能直接按逗号分割
+	for _, prefix := range splitParams(line) {
 		k, v := kvQuotationMark(prefix)
 		switch k {
 		case StrRealm:
@@ -180,6 +176,8 @@ func (m *RegisterHeaderWWWAuthenticateDigest) Parse(line string) bool {
 			m.Nonce = v
 		case StrQOP:
 			m.QOP = v
+		case StrAlgorithm:
+			m.Algorithm = v
 		}
 	}
 	return true
@@ -187,7 +185,65 @@ func (m *RegisterHeaderWWWAuthenticateDigest) Parse(line string) bool {
 
 // String 返回 Digest realm="x",nonce="x",qop="x"
 func (m *RegisterHeaderWWWAuthenticateDigest) String() string {
-	return fmt.Sprintf(`%s %s="%s",%s="%s",%s="%s"`, StrDigest, StrRealm, m.Realm, StrQOP, m.QOP, StrNonce, m.Nonce)
+	str := fmt.Sprintf(`%s %s="%s",%s="%s",%s="%s"`, StrDigest, StrRealm, m.Realm, StrQOP, m.QOP, StrNonce, m.Nonce)
+	if m.Algorithm != "" {
+		str += fmt.Sprintf(`,%s=%s`, StrAlgorithm, m.Algorithm)
+	}
+	return str
+}
+
+// ChooseQOP 从服务端的 qop 列表中选择一个，只支持 auth ，
+// 服务端没有 qop 返回空，没有支持的返回 false
+func (m *RegisterHeaderWWWAuthenticateDigest) ChooseQOP() (string, bool) {
+	if m.QOP == "" {
+		return "", true
+	}
+	for _, q := range strings.Split(m.QOP, ",") {
+		if strings.TrimSpace(q) == defaultQOP {
+			return defaultQOP, true
+		}
+	}
+	return "", false
+}
+
+// DigestHashName 返回 Digest 的 algorithm 对应的 hash ，
+// 支持 MD5/SHA-256 这样的写法，为空是 MD5 ，不支持返回 false
+func DigestHashName(algorithm string) (gb28181.HashName, bool) {
+	switch strings.ToUpper(strings.ReplaceAll(algorithm, "-", "")) {
+	case "", string(gb28181.HashMD5):
+		return gb28181.HashMD5, true
+	case string(gb28181.HashSHA1):
+		return gb28181.HashSHA1, true
+	case string(gb28181.HashSHA256):
+		return gb28181.HashSHA256, true
+	case string(gb28181.HashSHA384):
+		return gb28181.HashSHA384, true
+	case string(gb28181.HashSHA512):
+		return gb28181.HashSHA512, true
+	}
+	return "", false
+}
+
+// splitParams 按逗号分割 k="v",k="v" ，忽略引号中的逗号
+func splitParams(line string) []string {
+	var ps []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case gstrings.CharQuotationMark:
+			quoted = !quoted
+		case gstrings.CharComma:
+			if !quoted {
+				ps = append(ps, strings.TrimSpace(line[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if p := strings.TrimSpace(line[start:]); p != "" {
+		ps = append(ps, p)
+	}
+	return ps
 }
 
 // RegisterHeaderAuthorizationDigest 是客户端发送的消息，表示
@@ -243,7 +299,8 @@ func (m *RegisterHeaderAuthorizationDigest) Parse(line string) bool {
 // sign 返回签名
 // 算法 hex(hash(hash(username:realm:password):nonce:hash(method:uri)))
 func (m *RegisterHeaderAuthorizationDigest) sign(password string) string {
-	h := gb28181.NewHash(gb28181.HashName(m.Algorithm))
+	name, _ := DigestHashName(m.Algorithm)
+	h := gb28181.NewHash(name)
 	w := bufio.NewWriter(h)
 	// hash(username:realm:password)
 	fmt.Fprintf(w, "%s:%s:%s", m.Username, m.Realm, password)
@@ -319,6 +376,8 @@ func (m *RegisterHeaderAuthorizationDigest) String() string {
 type RegisterHeaderWWWAuthenticateAsymmetric struct {
 	Nonce   string
 	A, H, S string
+	// 服务端的随机数，GenNonce 生成，服务端保存用于验证 response
+	C []byte
 }
 
 // Parse 解析 nonce="x&x" algorithm="A:x;H:x;S:x;"
@@ -391,11 +450,17 @@ func (m *RegisterHeaderWWWAuthenticateAsymmetric) String() string {
 // GenNonce 返回签名，服务端调用
 // 算法 base64(ser.sign(hash(rand)))&base64(cli.enc(rand))
 func (m *RegisterHeaderWWWAuthenticateAsymmetric) GenNonce(ser *rsa.PrivateKey, cli *rsa.PublicKey) error {
-	a, b, _, err := rsaGenNonce(ser, cli, gb28181.CryptoHash(gb28181.HashName(m.H)))
+	return m.GenNonceWith(NewRSAPrivateKey(ser), NewRSAPublicKey(cli))
+}
+
+// GenNonceWith 和 GenNonce 一样，但是可以使用 sm2 等其他算法
+func (m *RegisterHeaderWWWAuthenticateAsymmetric) GenNonceWith(ser PrivateKey, cli PublicKey) error {
+	a, b, c, err := genNonce(ser, cli, gb28181.CryptoHash(gb28181.HashName(m.H)))
 	if err != nil {
 		return err
 	}
 	//
+	m.C = c
 	m.Nonce = base64.StdEncoding.EncodeToString(a) + "&" + base64.StdEncoding.EncodeToString(b)
 	//
 	return nil
@@ -404,7 +469,12 @@ func (m *RegisterHeaderWWWAuthenticateAsymmetric) GenNonce(ser *rsa.PrivateKey,
 // Verify 验证并返回随机数，客户端使用
 // 算法 ser.verify(hash(cli.dec(base64.dec(nonce2))), base64.dec(nonce1))
 func (m *RegisterHeaderWWWAuthenticateAsymmetric) VerifyNonce(ser *rsa.PublicKey, cli *rsa.PrivateKey) ([]byte, error) {
-	return rsaVerifyNonce(cli, ser, gb28181.CryptoHash(gb28181.HashName(m.H)), m.Nonce)
+	return m.VerifyNonceWith(NewRSAPublicKey(ser), NewRSAPrivateKey(cli))
+}
+
+// VerifyNonceWith 和 VerifyNonce 一样，但是可以使用 sm2 等其他算法
+func (m *RegisterHeaderWWWAuthenticateAsymmetric) VerifyNonceWith(ser PublicKey, cli PrivateKey) ([]byte, error) {
+	return verifyNonce(cli, ser, gb28181.CryptoHash(gb28181.HashName(m.H)), m.Nonce)
 }
 
 // RegisterHeaderAuthorization 表示
@@ -551,12 +621,21 @@ func (m *RegisterHeaderAuthorizationAsymmetric) GenResponse() (string, error) {
 // Verify 验证 response ，服务端调用
 // 算法 cli.verify(hash(ser.dec(base64.dec(nonce2))), base64.dec(nonce1))
 func (m *RegisterHeaderAuthorizationAsymmetric) VerifyResponse(ser *rsa.PrivateKey, cli *rsa.PublicKey) (bool, error) {
+	return m.VerifyResponseWith(NewRSAPrivateKey(ser), NewRSAPublicKey(cli))
+}
+
+// VerifyResponseWith 和 VerifyResponse 一样，但是可以使用 sm2 等其他算法，
+// m.C 不为空时使用 m.C 计算 response ，也就是服务端发出的随机数，和 GenResponse 一致
+func (m *RegisterHeaderAuthorizationAsymmetric) VerifyResponseWith(ser PrivateKey, cli PublicKey) (bool, error) {
 	hash := gb28181.CryptoHash(gb28181.HashName(m.Algorithm))
 	// 验证 nonce
-	c, err := rsaVerifyNonce(ser, cli, hash, m.Nonce)
+	c, err := verifyNonce(ser, cli, hash, m.Nonce)
 	if err != nil {
 		return false, err
 	}
+	if len(m.C) > 0 {
+		c = m.C
+	}
 	// 验证 hash(c+nonce)==response
 	h := hash.New()
 	h.Reset()
@@ -2,8 +2,19 @@ package register
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"goutil/gb28181"
 	"goutil/gb28181/request"
 	"goutil/sip"
+	"goutil/uid"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnsupportedAuthenticate 表示不支持的认证方式
+	ErrUnsupportedAuthenticate = errors.New("unsupported www-authenticate")
 )
 
 // Register 是 SendRegister 的参数
@@ -16,6 +27,18 @@ type Register struct {
 	Authorization string
 	// 追踪标识
 	TraceID string
+	// 同一个注册的 Call-ID 和 From-tag ，为空则生成新的，
+	// SendRegister 之后保存这一次的值，之后的请求复用并且 CSeq 加 1
+	CallID  string
+	FromTag string
+	CSeq    int64
+	// 以下是响应的结果，由 HandleResponse 设置
+	// 状态码
+	Status string
+	// Header.WWW-Authenticate
+	WWWAuthenticate string
+	// Header.Date
+	Date string
 }
 
 // SendRegister 注册
@@ -27,9 +50,81 @@ func SendRegister(ctx context.Context, m *Register) error {
 	}
 	// 消息
 	msg := request.NewRegister(m.Cascade, addr.Network(), m.Expires)
+	if m.CallID != "" {
+		msg.Header.CallID = m.CallID
+		msg.Header.From.Tag = m.FromTag
+		msg.Header.CSeq.SN = strconv.FormatInt(m.CSeq+1, 10)
+	}
+	m.CallID = msg.Header.CallID
+	m.FromTag = msg.Header.From.Tag
+	m.CSeq, _ = strconv.ParseInt(msg.Header.CSeq.SN, 10, 64)
 	if m.Authorization != "" {
 		msg.Header.Set(StrAuthorization, m.Authorization)
 	}
 	//
 	return m.Ser.RequestWithContext(ctx, m.TraceID, msg, addr, m)
 }
+
+// HandleResponse 用于 sip.Server.ResponseFunc ，
+// 保存 SendRegister 的响应结果，Login 需要
+func HandleResponse(ctx *sip.Response) {
+	m, ok := ctx.ReqData.(*Register)
+	if !ok {
+		return
+	}
+	m.Status = ctx.Status()
+	m.WWWAuthenticate = ctx.Header.Get(strings.ToUpper(StrWWWAuthenticate))
+	m.Date = ctx.Header.Get(strings.ToUpper(StrDate))
+}
+
+// Login 注册，如果上级返回 401 则使用 Digest 认证再注册一次，
+// 认证的请求和第一次使用相同的 Call-ID 和 From-tag ，CSeq 加 1 ，
+// 需要使用 HandleResponse 处理响应
+func Login(ctx context.Context, m *Register, password string) error {
+	// 第一次
+	m.Authorization = ""
+	if err := SendRegister(ctx, m); err != nil {
+		return err
+	}
+	// 认证
+	if m.Status == sip.StatusUnauthorized {
+		var www RegisterHeaderWWWAuthenticate
+		if !www.Parse(m.WWWAuthenticate) || www.Digest == nil {
+			return ErrUnsupportedAuthenticate
+		}
+		// 使用服务端提供的算法和 qop
+		if _, ok := DigestHashName(www.Digest.Algorithm); !ok {
+			return ErrUnsupportedAuthenticate
+		}
+		qop, ok := www.Digest.ChooseQOP()
+		if !ok {
+			return ErrUnsupportedAuthenticate
+		}
+		var auth RegisterHeaderAuthorizationDigest
+		auth.Username = m.Cascade.GetFromID()
+		auth.Realm = www.Digest.Realm
+		auth.Nonce = www.Digest.Nonce
+		auth.URI = fmt.Sprintf("sip:%s@%s", m.Cascade.GetToID(), m.Cascade.GetToDomain())
+		auth.Algorithm = www.Digest.Algorithm
+		if auth.Algorithm == "" {
+			auth.Algorithm = string(gb28181.HashMD5)
+		}
+		auth.QOP = qop
+		if auth.QOP != "" {
+			auth.CNonce = uid.UUID1NoHyphen()
+			auth.NC = "00000001"
+		}
+		auth.GenResponse(password)
+		m.Authorization = auth.String()
+		// 第二次
+		m.Status = ""
+		if err := SendRegister(ctx, m); err != nil {
+			return err
+		}
+	}
+	// 结果
+	if m.Status != sip.StatusOK {
+		return &sip.ResponseError{Status: m.Status, Phrase: sip.StatusPhrase(m.Status)}
+	}
+	return nil
+}
@@ -41,6 +41,8 @@ type Alarm struct {
 	result string
 	// 追踪标识
 	TraceID string
+	// 不为空则在对话中发送，用于刷新和取消订阅
+	Dialog *request.Dialog
 }
 
 func (m *Alarm) SetResult(s string) {
@@ -58,7 +60,13 @@ func SendAlarm(ctx context.Context, m *Alarm) (string, error) {
 	body.StartAlarmPriority = m.StartAlarmPriority
 	body.EndAlarmPriority = m.EndAlarmPriority
 	body.AlarmMethod = m.AlarmMethod
+	// 对话
+	if m.Dialog != nil {
+		err := request.SendSubscribeDialog(ctx, m.TraceID, m.Ser, m.Device, &body, m.Expire, m.Dialog)
+		return m.Dialog.Result(), err
+	}
 	//
 	var result request.XMLResult
-	return result.Result, request.SendSubscribe(ctx, m.TraceID, m.Ser, m.Device, &body, m.Expire, &result)
+	err := request.SendSubscribe(ctx, m.TraceID, m.Ser, m.Device, &body, m.Expire, &result)
+	return result.Result, err
 }
@@ -19,6 +19,8 @@ type MobilePosition struct {
 	result string
 	// 追踪标识
 	TraceID string
+	// 不为空则在对话中发送，用于刷新和取消订阅
+	Dialog *request.Dialog
 }
 
 func (m *MobilePosition) SetResult(s string) {
@@ -34,7 +36,13 @@ func SendMobilePosition(ctx context.Context, m *MobilePosition) (string, error)
 	body.DeviceID = m.Device.GetToID()
 	body.SN = sip.GetSNString()
 	body.Interval = m.Interval
+	// 对话
+	if m.Dialog != nil {
+		err := request.SendSubscribeDialog(ctx, m.TraceID, m.Ser, m.Device, &body, m.Expire, m.Dialog)
+		return m.Dialog.Result(), err
+	}
 	//
 	var result request.XMLResult
-	return result.Result, request.SendSubscribe(ctx, m.TraceID, m.Ser, m.Device, &body, m.Expire, &result)
+	err := request.SendSubscribe(ctx, m.TraceID, m.Ser, m.Device, &body, m.Expire, &result)
+	return result.Result, err
 }
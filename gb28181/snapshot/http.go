@@ -0,0 +1,163 @@
+package snapshot
+
+import (
+	"errors"
+	"goutil/log"
+	"goutil/uid"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 文件标识的查询参数
+	queryFileID = "fileID"
+	// 文件标识的头
+	headerFileID = "X-File-ID"
+	// 表单中除了文件之外的数据的最大字节数，包括边界和头
+	maxFormOverhead = 64 << 10
+	// 表单中不是文件的部分的最大个数
+	maxFormFields = 16
+)
+
+var (
+	// errFileTooLarge 表示图像超过了 MaxFileSize
+	errFileTooLarge = errors.New("snapshot file too large")
+	// errTooManyFiles 表示上传的文件超过了抓拍的张数
+	errTooManyFiles = errors.New("snapshot too many files")
+)
+
+// ServeHTTP 实现 http.Handler ，接收设备上传的图像，
+// 路径的最后一段是 SessionID ，支持 multipart/form-data 和直接上传，
+// 每个图像不能超过 MaxFileSize ，每个会话最多上传抓拍的张数
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	sessionID := path.Base(r.URL.Path)
+	t := m.tasks.Get(sessionID)
+	if t == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	remain := t.remain()
+	if remain < 1 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// 表单
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if strings.HasPrefix(ct, "multipart/") {
+		r.Body = http.MaxBytesReader(w, r.Body, m.opt.MaxFileSize*remain+maxFormOverhead)
+		mr, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fields := 0
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				w.WriteHeader(statusCode(err))
+				return
+			}
+			fileID := p.FileName()
+			if fileID == "" {
+				p.Close()
+				fields++
+				if fields > maxFormFields {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				continue
+			}
+			err = m.save(t, fileID, p)
+			p.Close()
+			if err != nil {
+				w.WriteHeader(statusCode(err))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	// 直接上传
+	r.Body = http.MaxBytesReader(w, r.Body, m.opt.MaxFileSize)
+	fileID := r.URL.Query().Get(queryFileID)
+	if fileID == "" {
+		fileID = r.Header.Get(headerFileID)
+	}
+	if fileID == "" {
+		fileID = uid.SnowflakeIDString() + ".jpg"
+	}
+	if err := m.save(t, fileID, r.Body); err != nil {
+		w.WriteHeader(statusCode(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// statusCode 返回错误对应的状态码
+func statusCode(err error) int {
+	var mbe *http.MaxBytesError
+	if errors.Is(err, errFileTooLarge) || errors.As(err, &mbe) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if errors.Is(err, errTooManyFiles) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// HandleGin 是 ServeHTTP 的 gin 适配
+func (m *Manager) HandleGin(ctx *gin.Context) {
+	m.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// save 保存图像，超过抓拍的张数或者 MaxFileSize 返回错误
+func (m *Manager) save(t *Task, fileID string, r io.Reader) error {
+	if !t.reserve() {
+		return errTooManyFiles
+	}
+	err := m.opt.Store.Save(t.SessionID, fileID, &limitReader{r: r, n: m.opt.MaxFileSize})
+	t.addFile(fileID, err == nil)
+	if err != nil {
+		log.Errorf(-1, t.SessionID, 0, "snapshot %s save %s %v", t.DeviceID, fileID, err)
+		return err
+	}
+	return nil
+}
+
+// limitReader 和 io.LimitReader 一样，但是超过之后返回 errFileTooLarge ，
+// 让 Store 知道图像不完整
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read 实现 io.Reader
+func (r *limitReader) Read(b []byte) (int, error) {
+	if r.n <= 0 {
+		// 刚好读完，看看还有没有
+		var one [1]byte
+		n, err := r.r.Read(one[:])
+		if n > 0 {
+			return 0, errFileTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(b)) > r.n {
+		b = b[:r.n]
+	}
+	n, err := r.r.Read(b)
+	r.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,214 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"goutil/gb28181/request"
+	devicecontrol "goutil/gb28181/request/message/control/device_control"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	gsync "goutil/sync"
+	"goutil/uid"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout 是等待上传完成的默认超时时间
+	DefaultTimeout = time.Minute
+	// DefaultMaxFileSize 是单个图像默认的最大字节数
+	DefaultMaxFileSize = 8 << 20
+	// DefaultDir 是默认保存图像的目录
+	DefaultDir = "snapshot"
+	// resultOK 是设备接受抓拍的结果
+	resultOK = "OK"
+)
+
+var (
+	// ErrRejected 表示设备拒绝了抓拍
+	ErrRejected = errors.New("snapshot rejected")
+	// ErrTimeout 表示等待上传完成超时
+	ErrTimeout = errors.New("wait snapshot timeout")
+)
+
+// Option 是 NewManager 的参数
+type Option struct {
+	Ser *sip.Server
+	// 设备上传图像的地址，会在后面加上 /SessionID ，
+	// 比如 http://192.168.1.10:8080/snapshot
+	UploadURL string
+	// 保存图像，默认是目录为 DefaultDir 的 DirStore
+	Store Store
+	// 等待上传完成的超时时间，默认 DefaultTimeout
+	Timeout time.Duration
+	// 单个图像的最大字节数，默认 DefaultMaxFileSize
+	MaxFileSize int64
+}
+
+// Task 表示一次抓拍
+type Task struct {
+	// 会话标识
+	SessionID string
+	// 设备
+	DeviceID  string
+	ChannelID string
+	// 抓拍的张数，也是可以上传的文件数
+	num int64
+	// 已经保存的图像的文件标识
+	lock  sync.Mutex
+	files []string
+	// 正在保存的个数
+	saving int64
+	// 设备通知的图像文件标识
+	finished []string
+	done     chan struct{}
+}
+
+// Files 返回已经保存的图像的文件标识
+func (t *Task) Files() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]string(nil), t.files...)
+}
+
+// Finished 返回设备在完成通知中的图像文件标识
+func (t *Task) Finished() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.finished
+}
+
+// remain 返回还可以上传的文件数
+func (t *Task) remain() int64 {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.num - int64(len(t.files)) - t.saving
+}
+
+// reserve 占用一个上传的名额，超过抓拍的张数返回 false
+func (t *Task) reserve() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if int64(len(t.files))+t.saving >= t.num {
+		return false
+	}
+	t.saving++
+	return true
+}
+
+// addFile 释放名额，保存成功则添加图像
+func (t *Task) addFile(fileID string, ok bool) {
+	t.lock.Lock()
+	t.saving--
+	if ok {
+		t.files = append(t.files, fileID)
+	}
+	t.lock.Unlock()
+}
+
+// finish 设备通知上传完成
+func (t *Task) finish(files []string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	select {
+	case <-t.done:
+	default:
+		t.finished = files
+		close(t.done)
+	}
+}
+
+// Manager 用于图像抓拍，发送抓拍命令，接收设备上传的图像，
+// 等待设备的上传完成通知
+type Manager struct {
+	opt Option
+	// key 是 SessionID
+	tasks gsync.Map[string, *Task]
+}
+
+// NewManager 返回新的 Manager
+func NewManager(opt *Option) *Manager {
+	m := new(Manager)
+	m.opt = *opt
+	if m.opt.Timeout <= 0 {
+		m.opt.Timeout = DefaultTimeout
+	}
+	if m.opt.MaxFileSize <= 0 {
+		m.opt.MaxFileSize = DefaultMaxFileSize
+	}
+	if m.opt.Store == nil {
+		m.opt.Store = &DirStore{Dir: DefaultDir}
+	}
+	m.tasks.Init()
+	return m
+}
+
+// SnapShot 抓拍 num 张图像，间隔 interval 秒，等待设备上传完成后返回，
+// 超时返回已经保存的和错误
+func (m *Manager) SnapShot(ctx context.Context, trace string, dev request.Request, channelID string, num, interval int64) (*Task, error) {
+	t := new(Task)
+	t.SessionID = uid.SnowflakeIDString()
+	t.DeviceID = dev.GetToID()
+	t.ChannelID = channelID
+	t.num = num
+	if t.num < 1 {
+		t.num = 1
+	}
+	t.done = make(chan struct{})
+	m.tasks.Set(t.SessionID, t)
+	defer m.tasks.Del(t.SessionID)
+	// 命令
+	result, err := devicecontrol.SendSnapShot(ctx, &devicecontrol.SnapShot{
+		Ser:       m.opt.Ser,
+		Device:    dev,
+		ChannelID: channelID,
+		Data: &xml.MessageSnapShotConfig{
+			SnapNum:   t.num,
+			Interval:  interval,
+			UploadURL: m.opt.UploadURL + "/" + t.SessionID,
+			SessionID: t.SessionID,
+		},
+		TraceID: trace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result != resultOK {
+		return nil, ErrRejected
+	}
+	// 等待
+	timer := time.NewTimer(m.opt.Timeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return t, ctx.Err()
+	case <-timer.C:
+		return t, ErrTimeout
+	case <-t.done:
+		return t, nil
+	}
+}
+
+// HandleMessage 处理设备的 Notify-UploadSnapShotFinished ，用于 sip.Server.RequestFunc ，
+// 不是抓拍的通知直接返回
+func (m *Manager) HandleMessage(ctx *sip.Request) {
+	// 解析，不能影响后面的函数读取
+	var msg xml.Message
+	if err := xml.Decode(bytes.NewReader(ctx.Body.Bytes()), &msg); err != nil {
+		return
+	}
+	if msg.XMLName.Local != xml.TypeNotify || msg.CmdType != xml.CmdUploadSnapShotFinished {
+		return
+	}
+	t := m.tasks.Get(msg.SessionID)
+	if t == nil {
+		return
+	}
+	ctx.ResponseStatus(sip.StatusOK)
+	var files []string
+	if msg.SnapShotList != nil {
+		files = msg.SnapShotList.SnapShotFileID
+	}
+	t.finish(files)
+}
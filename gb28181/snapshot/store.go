@@ -0,0 +1,43 @@
+package snapshot
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Store 用于保存设备上传的图像
+type Store interface {
+	// Save 保存图像，sessionID 是抓拍的会话标识，fileID 是图像的文件标识
+	Save(sessionID, fileID string, r io.Reader) error
+}
+
+// DirStore 把图像保存在目录中，路径是 Dir/sessionID/fileID
+type DirStore struct {
+	Dir string
+}
+
+// Save 实现 Store ，失败删除写了一部分的文件
+func (s *DirStore) Save(sessionID, fileID string, r io.Reader) error {
+	// 防止路径穿越
+	dir := filepath.Join(s.Dir, filepath.Base(sessionID))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, filepath.Base(fileID))
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	f.Close()
+	if err != nil {
+		os.Remove(name)
+	}
+	return err
+}
+
+// Path 返回图像的路径
+func (s *DirStore) Path(sessionID, fileID string) string {
+	return filepath.Join(s.Dir, filepath.Base(sessionID), filepath.Base(fileID))
+}
@@ -0,0 +1,150 @@
+package ssrc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore 是内存中的 Store ，用于单个实例
+type MemoryStore struct {
+	lock sync.Mutex
+	// key 是前缀
+	seqs map[string]*memorySeq
+	// key 是 Call-ID
+	binds map[string]*memoryBind
+}
+
+// memorySeq 是一个前缀的序号
+type memorySeq struct {
+	// 下一次开始查找的序号
+	next int
+	// 正在使用的序号
+	used map[int]*memoryLease
+}
+
+// memoryLease 是正在使用的序号
+type memoryLease struct {
+	owner  string
+	expire time.Time
+}
+
+// memoryBind 是 Call-ID 的绑定
+type memoryBind struct {
+	ssrc   string
+	owner  string
+	expire time.Time
+}
+
+// NewMemoryStore 返回新的 MemoryStore
+func NewMemoryStore() *MemoryStore {
+	s := new(MemoryStore)
+	s.seqs = make(map[string]*memorySeq)
+	s.binds = make(map[string]*memoryBind)
+	return s
+}
+
+// Alloc 实现 Store
+func (s *MemoryStore) Alloc(ctx context.Context, prefix, owner string, lease time.Duration) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	//
+	seq := s.seqs[prefix]
+	if seq == nil {
+		seq = &memorySeq{next: 1, used: make(map[int]*memoryLease)}
+		s.seqs[prefix] = seq
+	}
+	now := time.Now()
+	// 满了先清理过期的
+	if len(seq.used) >= MaxSeq {
+		for n, l := range seq.used {
+			if !now.Before(l.expire) {
+				delete(seq.used, n)
+			}
+		}
+		if len(seq.used) >= MaxSeq {
+			return "", ErrExhausted
+		}
+	}
+	// 从上一次的位置开始找，尽量不马上复用刚释放的
+	for {
+		n := seq.next
+		seq.next++
+		if seq.next > MaxSeq {
+			seq.next = 1
+		}
+		if l, ok := seq.used[n]; !ok || !now.Before(l.expire) {
+			seq.used[n] = &memoryLease{owner: owner, expire: now.Add(lease)}
+			return format(prefix, "", n), nil
+		}
+	}
+}
+
+// lookup 返回 owner 没有过期的 ssrc ，必须在锁中调用
+func (s *MemoryStore) lookup(ssrc, owner string) (*memorySeq, int, bool) {
+	prefix, n, ok := split(ssrc)
+	if !ok {
+		return nil, 0, false
+	}
+	seq := s.seqs[prefix]
+	if seq == nil {
+		return nil, 0, false
+	}
+	l := seq.used[n]
+	if l == nil || l.owner != owner || !time.Now().Before(l.expire) {
+		return nil, 0, false
+	}
+	return seq, n, true
+}
+
+// Renew 实现 Store
+func (s *MemoryStore) Renew(ctx context.Context, ssrc, owner string, lease time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	seq, n, ok := s.lookup(ssrc, owner)
+	if !ok {
+		return false, nil
+	}
+	seq.used[n].expire = time.Now().Add(lease)
+	return true, nil
+}
+
+// Free 实现 Store
+func (s *MemoryStore) Free(ctx context.Context, ssrc, owner string) error {
+	s.lock.Lock()
+	if seq, n, ok := s.lookup(ssrc, owner); ok {
+		delete(seq.used, n)
+	}
+	s.lock.Unlock()
+	return nil
+}
+
+// Bind 实现 Store
+func (s *MemoryStore) Bind(ctx context.Context, callID, ssrc, owner string, lease time.Duration) error {
+	now := time.Now()
+	s.lock.Lock()
+	// 顺便清理过期的
+	for k, b := range s.binds {
+		if !now.Before(b.expire) {
+			delete(s.binds, k)
+		}
+	}
+	s.binds[callID] = &memoryBind{ssrc: ssrc, owner: owner, expire: now.Add(lease)}
+	s.lock.Unlock()
+	return nil
+}
+
+// Unbind 实现 Store
+func (s *MemoryStore) Unbind(ctx context.Context, callID string) (string, string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	b := s.binds[callID]
+	if b == nil {
+		return "", "", nil
+	}
+	delete(s.binds, callID)
+	if !time.Now().Before(b.expire) {
+		return "", "", nil
+	}
+	return b.ssrc, b.owner, nil
+}
@@ -0,0 +1,152 @@
+package ssrc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// 分配的脚本，有序集合的分数是租期的过期时间，哈希表保存 owner ，
+	// 先删除过期的，序号循环递增，加入集合成功表示没有被使用
+	redisAlloc = redis.NewScript(`
+-- 参数
+local set, seq, owners = KEYS[1], KEYS[2], KEYS[3]
+local prefix, max, now, expire, owner = ARGV[1], tonumber(ARGV[2]), ARGV[3], ARGV[4], ARGV[5]
+-- 过期
+local expired = redis.call("ZRANGEBYSCORE", set, "-inf", now)
+if #expired > 0 then
+	redis.call("ZREMRANGEBYSCORE", set, "-inf", now)
+	for i = 1, #expired, 1000 do
+		redis.call("HDEL", owners, unpack(expired, i, math.min(i + 999, #expired)))
+	end
+end
+-- 满了
+if redis.call("ZCARD", set) >= max then
+	return ""
+end
+-- 查找
+for i = 1, max do
+	local n = redis.call("INCR", seq)
+	if n > max then
+		n = 1
+		redis.call("SET", seq, n)
+	end
+	local ssrc = prefix .. string.format("%04d", n)
+	if redis.call("ZADD", set, "NX", expire, ssrc) == 1 then
+		redis.call("HSET", owners, ssrc, owner)
+		return ssrc
+	end
+end
+return ""
+`)
+	// 续租的脚本，已经释放、过期或者不属于 owner 返回 0
+	redisRenew = redis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if not score or tonumber(score) <= tonumber(ARGV[3]) or redis.call("HGET", KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call("ZADD", KEYS[1], "XX", ARGV[4], ARGV[1])
+return 1
+`)
+	// 释放的脚本，不属于 owner 返回 0
+	redisFree = redis.NewScript(`
+if redis.call("HGET", KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call("ZREM", KEYS[1], ARGV[1])
+redis.call("HDEL", KEYS[2], ARGV[1])
+return 1
+`)
+)
+
+// RedisStore 是 redis 的 Store ，用于多个实例共享 ssrc
+type RedisStore struct {
+	db  redis.UniversalClient
+	key string
+}
+
+// NewRedisStore 返回新的 RedisStore ，key 是 redis 键的前缀，
+// 集群模式下键使用 {key} 保证在同一个 slot
+func NewRedisStore(db redis.UniversalClient, key string) *RedisStore {
+	return &RedisStore{db: db, key: key}
+}
+
+// setKey 返回保存正在使用的 ssrc 的有序集合的键
+func (s *RedisStore) setKey(prefix string) string {
+	return "{" + s.key + ":" + prefix + "}:used"
+}
+
+// ownerKey 返回保存 ssrc 的 owner 的哈希表的键
+func (s *RedisStore) ownerKey(prefix string) string {
+	return "{" + s.key + ":" + prefix + "}:owner"
+}
+
+// seqKey 返回序号的键
+func (s *RedisStore) seqKey(prefix string) string {
+	return "{" + s.key + ":" + prefix + "}:seq"
+}
+
+// bindKey 返回 Call-ID 绑定的键
+func (s *RedisStore) bindKey(callID string) string {
+	return s.key + ":call:" + callID
+}
+
+// Alloc 实现 Store
+func (s *RedisStore) Alloc(ctx context.Context, prefix, owner string, lease time.Duration) (string, error) {
+	now := time.Now()
+	ssrc, err := redisAlloc.Run(ctx, s.db, []string{s.setKey(prefix), s.seqKey(prefix), s.ownerKey(prefix)},
+		prefix, MaxSeq, now.UnixMilli(), now.Add(lease).UnixMilli(), owner).Text()
+	if err != nil {
+		return "", err
+	}
+	if ssrc == "" {
+		return "", ErrExhausted
+	}
+	return ssrc, nil
+}
+
+// Renew 实现 Store
+func (s *RedisStore) Renew(ctx context.Context, ssrc, owner string, lease time.Duration) (bool, error) {
+	prefix, _, ok := split(ssrc)
+	if !ok {
+		return false, nil
+	}
+	now := time.Now()
+	n, err := redisRenew.Run(ctx, s.db, []string{s.setKey(prefix), s.ownerKey(prefix)},
+		ssrc, owner, now.UnixMilli(), now.Add(lease).UnixMilli()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Free 实现 Store
+func (s *RedisStore) Free(ctx context.Context, ssrc, owner string) error {
+	prefix, _, ok := split(ssrc)
+	if !ok {
+		return nil
+	}
+	return redisFree.Run(ctx, s.db, []string{s.setKey(prefix), s.ownerKey(prefix)}, ssrc, owner).Err()
+}
+
+// Bind 实现 Store ，值是 ssrc:owner
+func (s *RedisStore) Bind(ctx context.Context, callID, ssrc, owner string, lease time.Duration) error {
+	return s.db.Set(ctx, s.bindKey(callID), ssrc+":"+owner, lease).Err()
+}
+
+// Unbind 实现 Store
+func (s *RedisStore) Unbind(ctx context.Context, callID string) (string, string, error) {
+	val, err := s.db.GetDel(ctx, s.bindKey(callID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+	ssrc, owner, _ := strings.Cut(val, ":")
+	return ssrc, owner, nil
+}
@@ -0,0 +1,287 @@
+package ssrc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goutil/log"
+	"goutil/sip"
+	"goutil/uid"
+	"sync"
+	"time"
+)
+
+// 根据国标附录 F ，ssrc 是 10 位十进制数字，
+// 第 1 位 0 表示实时流，1 表示历史流，
+// 第 2 到 6 位是 20 位监控域编号的第 4 到 8 位，
+// 第 7 到 10 位是域内的序号
+const (
+	// Realtime 是实时流 ssrc 的前缀
+	Realtime = "0"
+	// History 是历史流 ssrc 的前缀
+	History = "1"
+	// MaxSeq 是最大的序号
+	MaxSeq = 9999
+	// DefaultLease 是 ssrc 默认的租期
+	DefaultLease = time.Minute
+)
+
+var (
+	// ErrExhausted 表示没有可以分配的 ssrc
+	ErrExhausted = errors.New("ssrc exhausted")
+	// ErrDomainID 表示监控域编号格式错误
+	ErrDomainID = errors.New("invalid domain id")
+	// ErrNotAllocated 表示 ssrc 不是 Allocator 分配的或者已经释放
+	ErrNotAllocated = errors.New("ssrc not allocated")
+)
+
+// DomainCode 返回监控域编号的第 4 到 8 位
+func DomainCode(domainID string) (string, error) {
+	if len(domainID) < 8 {
+		return "", ErrDomainID
+	}
+	return domainID[3:8], nil
+}
+
+// IsHistory 返回 ssrc 是否历史流
+func IsHistory(ssrc string) bool {
+	return len(ssrc) > 0 && ssrc[:1] == History
+}
+
+// format 返回 ssrc
+func format(prefix, code string, seq int) string {
+	return fmt.Sprintf("%s%s%04d", prefix, code, seq)
+}
+
+// split 返回 ssrc 的前缀和序号
+func split(ssrc string) (string, int, bool) {
+	n := len(ssrc) - 4
+	if n < 0 {
+		return "", 0, false
+	}
+	var i int
+	for _, c := range ssrc[n:] {
+		if c < '0' || c > '9' {
+			return "", 0, false
+		}
+		i = i*10 + int(c-'0')
+	}
+	return ssrc[:n], i, true
+}
+
+// Store 是 ssrc 的存储，保证同时使用的 ssrc 不重复，
+// 每一次分配和绑定都有租期，过期之后自动释放，
+// 避免实例异常退出之后 ssrc 一直被占用，
+// 每一次分配都有 owner 标识，只有 owner 可以续租和释放
+type Store interface {
+	// Alloc 分配一个以 prefix 开头的 ssrc ，租期是 lease ，
+	// prefix 是 Realtime/History 加上域编号
+	Alloc(ctx context.Context, prefix, owner string, lease time.Duration) (string, error)
+	// Renew 续租 ssrc ，已经释放、过期或者不属于 owner 返回 false
+	Renew(ctx context.Context, ssrc, owner string, lease time.Duration) (bool, error)
+	// Free 释放 ssrc ，不属于 owner 不释放
+	Free(ctx context.Context, ssrc, owner string) error
+	// Bind 保存 Call-ID 和 ssrc 以及 owner 的绑定，租期是 lease
+	Bind(ctx context.Context, callID, ssrc, owner string, lease time.Duration) error
+	// Unbind 删除 Call-ID 的绑定，返回绑定的 ssrc 和 owner ，没有绑定返回空
+	Unbind(ctx context.Context, callID string) (string, string, error)
+}
+
+// Option 是 NewAllocator 的参数
+type Option struct {
+	// 20 位的监控域编号
+	DomainID string
+	// 存储，默认 NewMemoryStore
+	Store Store
+	// 租期，每 1/3 续租一次，默认 DefaultLease
+	Lease time.Duration
+}
+
+// Allocator 用于分配 ssrc ，并在会话结束后释放，
+// 在协程中为分配出去的 ssrc 续租，直到释放或者 Close
+type Allocator struct {
+	store Store
+	code  string
+	lease time.Duration
+	// 分配出去的 ssrc ，key 是 ssrc
+	lock   sync.Mutex
+	leases map[string]*lease
+	// 用于退出
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// lease 是分配出去的 ssrc
+type lease struct {
+	// 分配的标识
+	owner string
+	// 绑定的 Call-ID
+	callID string
+}
+
+// NewAllocator 返回新的 Allocator ，启动续租的协程
+func NewAllocator(opt *Option) (*Allocator, error) {
+	code, err := DomainCode(opt.DomainID)
+	if err != nil {
+		return nil, err
+	}
+	a := new(Allocator)
+	a.store = opt.Store
+	if a.store == nil {
+		a.store = NewMemoryStore()
+	}
+	a.lease = opt.Lease
+	if a.lease <= 0 {
+		a.lease = DefaultLease
+	}
+	a.code = code
+	a.leases = make(map[string]*lease)
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	a.done = make(chan struct{})
+	go a.routine()
+	return a, nil
+}
+
+// Close 停止续租，没有释放的 ssrc 在租期之后自动释放
+func (a *Allocator) Close() {
+	a.cancel()
+	<-a.done
+}
+
+// Realtime 分配实时流的 ssrc
+func (a *Allocator) Realtime(ctx context.Context) (string, error) {
+	return a.alloc(ctx, Realtime+a.code)
+}
+
+// History 分配历史流的 ssrc
+func (a *Allocator) History(ctx context.Context) (string, error) {
+	return a.alloc(ctx, History+a.code)
+}
+
+// alloc 分配并记录，用于续租
+func (a *Allocator) alloc(ctx context.Context, prefix string) (string, error) {
+	owner := uid.UUID1NoHyphen()
+	ssrc, err := a.store.Alloc(ctx, prefix, owner, a.lease)
+	if err != nil {
+		return "", err
+	}
+	a.lock.Lock()
+	a.leases[ssrc] = &lease{owner: owner}
+	a.lock.Unlock()
+	return ssrc, nil
+}
+
+// Free 释放 ssrc ，同时删除绑定，不是自己分配的不处理
+func (a *Allocator) Free(ctx context.Context, ssrc string) error {
+	a.lock.Lock()
+	l := a.leases[ssrc]
+	delete(a.leases, ssrc)
+	a.lock.Unlock()
+	if l == nil {
+		return nil
+	}
+	if l.callID != "" {
+		if _, _, err := a.store.Unbind(ctx, l.callID); err != nil {
+			return err
+		}
+	}
+	return a.store.Free(ctx, ssrc, l.owner)
+}
+
+// Bind 把 ssrc 和会话的 Call-ID 绑定，绑定保存在 Store 中，
+// 会话结束时任意实例都可以 Release 或者 HandleBye 释放，
+// ssrc 必须是自己分配的
+func (a *Allocator) Bind(ctx context.Context, callID, ssrc string) error {
+	a.lock.Lock()
+	l := a.leases[ssrc]
+	a.lock.Unlock()
+	if l == nil {
+		return ErrNotAllocated
+	}
+	if err := a.store.Bind(ctx, callID, ssrc, l.owner, a.lease); err != nil {
+		return err
+	}
+	a.lock.Lock()
+	if a.leases[ssrc] == l {
+		l.callID = callID
+	}
+	a.lock.Unlock()
+	return nil
+}
+
+// Release 释放 Call-ID 绑定的 ssrc ，没有绑定返回 nil
+func (a *Allocator) Release(ctx context.Context, callID string) error {
+	ssrc, owner, err := a.store.Unbind(ctx, callID)
+	if err != nil {
+		return err
+	}
+	if ssrc == "" {
+		return nil
+	}
+	a.lock.Lock()
+	if l := a.leases[ssrc]; l != nil && l.owner == owner {
+		delete(a.leases, ssrc)
+	}
+	a.lock.Unlock()
+	return a.store.Free(ctx, ssrc, owner)
+}
+
+// HandleBye 释放会话绑定的 ssrc ，用于 sip.Server.RequestFunc ，
+// 不会响应，交给调用链后面的函数处理
+func (a *Allocator) HandleBye(ctx *sip.Request) {
+	if err := a.Release(context.Background(), ctx.Header.CallID); err != nil {
+		log.Errorf(-1, "", 0, "ssrc release %s %v", ctx.Header.CallID, err)
+	}
+}
+
+// routine 在协程中续租
+func (a *Allocator) routine() {
+	defer func() {
+		close(a.done)
+		log.Recover(recover())
+	}()
+	ticker := time.NewTicker(a.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		a.renew()
+	}
+}
+
+// renew 续租一次，已经不属于自己的 ssrc 不再续租
+func (a *Allocator) renew() {
+	a.lock.Lock()
+	leases := make(map[string]lease, len(a.leases))
+	for ssrc, l := range a.leases {
+		leases[ssrc] = *l
+	}
+	a.lock.Unlock()
+	//
+	for ssrc, l := range leases {
+		ctx, cancel := context.WithTimeout(a.ctx, a.lease/3)
+		ok, err := a.store.Renew(ctx, ssrc, l.owner, a.lease)
+		if err == nil && ok && l.callID != "" {
+			err = a.store.Bind(ctx, l.callID, ssrc, l.owner, a.lease)
+		}
+		cancel()
+		if err != nil {
+			if a.ctx.Err() != nil {
+				return
+			}
+			log.Errorf(-1, "", 0, "ssrc renew %s %v", ssrc, err)
+			continue
+		}
+		if !ok {
+			a.lock.Lock()
+			if cur := a.leases[ssrc]; cur != nil && cur.owner == l.owner {
+				delete(a.leases, ssrc)
+			}
+			a.lock.Unlock()
+		}
+	}
+}
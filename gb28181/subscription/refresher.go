@@ -0,0 +1,135 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"goutil/gb28181/request"
+	"goutil/log"
+	gsync "goutil/sync"
+	"time"
+)
+
+const (
+	// DefaultRetryInterval 是订阅失败后重试的默认间隔
+	DefaultRetryInterval = 30 * time.Second
+	// resultOK 是订阅成功的 xml.Result
+	resultOK = "OK"
+)
+
+// Sender 在 dlg 的对话中发送一次订阅，expire 为 0 表示取消订阅
+type Sender func(ctx context.Context, dlg *request.Dialog, expire int64) error
+
+// Refresher 维护设备的订阅，在过期之前刷新，
+// 刷新和取消都在第一次订阅建立的对话中发送，
+// 需要使用 request.HandleDialogResponse 处理 SUBSCRIBE 的响应
+type Refresher struct {
+	// 失败重试的间隔
+	retry time.Duration
+	// key 是设备编号
+	subs gsync.Map[string, *entry]
+}
+
+// entry 表示一个订阅
+type entry struct {
+	id     string
+	expire int64
+	trace  string
+	send   Sender
+	dlg    request.Dialog
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRefresher 返回新的 Refresher ，retry 是订阅失败后重试的间隔，
+// 默认 DefaultRetryInterval
+func NewRefresher(retry time.Duration) *Refresher {
+	r := new(Refresher)
+	if retry <= 0 {
+		retry = DefaultRetryInterval
+	}
+	r.retry = retry
+	r.subs.Init()
+	return r
+}
+
+// Subscribe 订阅，启动协程在过期之前刷新，已经存在则替换，
+// 替换的时候先在旧的对话中取消订阅，expire 是有效期，单位秒
+func (r *Refresher) Subscribe(id string, expire int64, trace string, send Sender) {
+	old := r.stop(id)
+	//
+	e := &entry{
+		id:     id,
+		expire: expire,
+		trace:  trace,
+		send:   send,
+		done:   make(chan struct{}),
+	}
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	r.subs.Set(id, e)
+	go r.routine(e, old)
+}
+
+// Unsubscribe 停止刷新，在对话中发送有效期为 0 的订阅，会阻塞等待设备的响应
+func (r *Refresher) Unsubscribe(ctx context.Context, id string) error {
+	e := r.stop(id)
+	if e == nil || !e.dlg.IsEstablished() {
+		return nil
+	}
+	return e.send(ctx, &e.dlg, 0)
+}
+
+// Has 返回是否订阅了设备
+func (r *Refresher) Has(id string) bool {
+	return r.subs.Has(id)
+}
+
+// stop 停止刷新，等待协程退出
+func (r *Refresher) stop(id string) *entry {
+	e := r.subs.Take(id)
+	if e != nil {
+		e.cancel()
+		<-e.done
+	}
+	return e
+}
+
+// routine 在协程中订阅和刷新，old 不为空则先取消旧的订阅
+func (r *Refresher) routine(e *entry, old *entry) {
+	defer func() {
+		close(e.done)
+		log.Recover(recover())
+	}()
+	if old != nil && old.dlg.IsEstablished() {
+		if err := old.send(e.ctx, &old.dlg, 0); err != nil {
+			log.Errorf(-1, e.trace, 0, "unsubscribe %s %v", e.id, err)
+		}
+	}
+	// 有效期的 2/3 刷新一次
+	refresh := time.Duration(e.expire) * time.Second * 2 / 3
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		err := e.send(e.ctx, &e.dlg, e.expire)
+		// 响应 200 但是设备拒绝了
+		if res := e.dlg.Result(); err == nil && res != "" && res != resultOK {
+			err = fmt.Errorf("result %s", res)
+		}
+		if err != nil {
+			if e.ctx.Err() != nil {
+				return
+			}
+			log.Errorf(-1, e.trace, 0, "subscribe %s %v", e.id, err)
+			// 设备可能已经丢失了对话，重新订阅
+			e.dlg.Reset()
+			timer.Reset(r.retry)
+			continue
+		}
+		timer.Reset(refresh)
+	}
+}
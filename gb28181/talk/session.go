@@ -0,0 +1,219 @@
+package talk
+
+import (
+	"context"
+	"goutil/gb28181/request"
+	"goutil/gb28181/request/invite"
+	"goutil/sdp"
+	"goutil/zlm"
+	"strconv"
+	"strings"
+)
+
+// 音频编码
+const (
+	CodecPCMA = "PCMA"
+	CodecPCMU = "PCMU"
+)
+
+// 音频编码的负载类型
+var codecPayloadTypes = map[string]string{
+	CodecPCMA: "8",
+	CodecPCMU: "0",
+}
+
+// Session 表示一个语音广播/对讲的会话，
+// 对话是设备发起的 INVITE ，平台向设备发送音频
+type Session struct {
+	// 设备
+	Device request.Request
+	// 语音输入设备的编码
+	SourceID string
+	// 语音输出设备的编码
+	TargetID string
+	// 对话
+	CallID string
+	// 设备的 tag
+	RemoteTag string
+	// 平台的 tag
+	LocalTag string
+	// 设备接收音频的地址
+	RemoteIP   string
+	RemotePort string
+	// 平台发送音频的地址
+	LocalIP   string
+	LocalPort string
+	// 编码，CodecPCMA/CodecPCMU
+	Codec string
+	// 负载类型
+	PayloadType string
+	// 平台的传输模式
+	StreamMode invite.StreamMode
+	// ssrc
+	SSRC string
+	// 绑定的 zlm 发送
+	zlm    zlm.Server
+	app    string
+	stream string
+}
+
+// GetFromTag 实现 request.Invite ，平台发送 BYE 时使用
+func (s *Session) GetFromTag() string {
+	return s.LocalTag
+}
+
+// GetToTag 实现 request.Invite ，平台发送 BYE 时使用
+func (s *Session) GetToTag() string {
+	return s.RemoteTag
+}
+
+// GetCallID 实现 request.Invite
+func (s *Session) GetCallID() string {
+	return s.CallID
+}
+
+// parseOffer 解析设备的 sdp ，codecs 是编码的优先级，
+// 没有匹配的编码或者没有 ssrc 返回 false
+func (s *Session) parseOffer(offer *sdp.Session, codecs []string) bool {
+	// 音频
+	var m *sdp.Media
+	for _, mm := range offer.M {
+		if mm.Type == invite.InviteAudio {
+			m = mm
+			break
+		}
+	}
+	if m == nil {
+		return false
+	}
+	// 编码
+	fmts := strings.Fields(m.FMT)
+	for _, codec := range codecs {
+		pt := codecPayloadTypes[codec]
+		for _, f := range fmts {
+			if f == pt {
+				s.Codec = codec
+				s.PayloadType = pt
+				break
+			}
+		}
+		if s.Codec != "" {
+			break
+		}
+	}
+	if s.Codec == "" {
+		return false
+	}
+	// 地址
+	s.RemotePort = m.Port
+	if m.C != nil {
+		s.RemoteIP = m.C.Address
+	} else if offer.C != nil {
+		s.RemoteIP = offer.C.Address
+	}
+	// 传输模式，和设备的相反
+	s.StreamMode = invite.StreamModeUDP
+	if m.Proto == sdp.ProtoTCP {
+		s.StreamMode = invite.StreamModeActive
+		if m.SearchA("setup:") == "active" {
+			s.StreamMode = invite.StreamModePassive
+		}
+	}
+	// ssrc ，可能在媒体里也可能在会话里，u= 是通道编号不是 ssrc
+	s.SSRC = m.SearchOther("y")
+	if s.SSRC == "" {
+		s.SSRC = offer.Y
+	}
+	return s.SSRC != ""
+}
+
+// answer 返回平台应答的 sdp
+func (s *Session) answer() *sdp.Session {
+	var a sdp.Session
+	a.Init()
+	a.S = invite.InvitePlay
+	a.O.Username = s.SourceID
+	a.O.Address = s.LocalIP
+	a.C.Address = s.LocalIP
+	//
+	m := new(sdp.Media)
+	m.Type = invite.InviteAudio
+	m.Port = s.LocalPort
+	m.FMT = s.PayloadType
+	m.A = append(m.A, sdp.SendOnly)
+	m.A = append(m.A, "rtpmap:"+s.PayloadType+" "+s.Codec+"/8000")
+	switch s.StreamMode {
+	case invite.StreamModePassive:
+		m.Proto = sdp.ProtoTCP
+		m.A = append(m.A, invite.SDPMediaSetupPassive)
+		m.A = append(m.A, invite.SDPMediaConnectionNew)
+	case invite.StreamModeActive:
+		m.Proto = sdp.ProtoTCP
+		m.A = append(m.A, invite.SDPMediaSetupActive)
+		m.A = append(m.A, invite.SDPMediaConnectionNew)
+	default:
+		m.Proto = sdp.ProtoUDP
+	}
+	m.AddOther("y", s.SSRC)
+	a.M = append(a.M, m)
+	return &a
+}
+
+// SendRTP 调用 zlm 向设备发送 app/stream 的音频，
+// 需要在 Option.Bind 中调用，因为 LocalPort 会用于应答
+func (s *Session) SendRTP(ctx context.Context, ser zlm.Server, app, stream string) error {
+	if s.StreamMode == invite.StreamModePassive {
+		// 等待设备连接
+		req := zlm.StartSendRTPPassiveReq{
+			App:       app,
+			Stream:    stream,
+			SSRC:      s.SSRC,
+			PT:        s.PayloadType,
+			UsePS:     zlm.RTPPayloadTypeES,
+			OnlyAudio: zlm.True,
+		}
+		var res zlm.StartSendRTPPassiveRes
+		if err := zlm.StartSendRTPPassive(ctx, ser, &req, &res); err != nil {
+			return err
+		}
+		s.LocalPort = strconv.Itoa(res.LocalPort)
+	} else {
+		// 主动发送
+		req := zlm.StartSendRTPReq{
+			App:       app,
+			Stream:    stream,
+			SSRC:      s.SSRC,
+			DstIP:     s.RemoteIP,
+			DstPort:   s.RemotePort,
+			IsUDP:     zlm.Zero,
+			PT:        s.PayloadType,
+			UsePS:     zlm.RTPPayloadTypeES,
+			OnlyAudio: zlm.True,
+		}
+		if s.StreamMode == invite.StreamModeUDP {
+			req.IsUDP = zlm.One
+		}
+		var res zlm.StartSendRTPRes
+		if err := zlm.StartSendRTP(ctx, ser, &req, &res); err != nil {
+			return err
+		}
+		s.LocalPort = strconv.Itoa(res.LocalPort)
+	}
+	s.zlm = ser
+	s.app = app
+	s.stream = stream
+	return nil
+}
+
+// StopRTP 调用 zlm 停止发送，没有绑定直接返回
+func (s *Session) StopRTP(ctx context.Context) error {
+	if s.zlm == nil {
+		return nil
+	}
+	var res zlm.StopSendRTPRes
+	return zlm.StopSendRTP(ctx, s.zlm, &zlm.StopSendRTPReq{
+		App:    s.app,
+		Stream: s.stream,
+		SSRC:   s.SSRC,
+	}, &res)
+}
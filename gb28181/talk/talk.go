@@ -0,0 +1,205 @@
+package talk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"goutil/gb28181/request"
+	"goutil/gb28181/request/message/notify"
+	"goutil/log"
+	"goutil/sdp"
+	"goutil/sip"
+	gsync "goutil/sync"
+	"time"
+)
+
+const (
+	// DefaultInviteTimeout 是等待设备 INVITE 的默认超时时间
+	DefaultInviteTimeout = 10 * time.Second
+	// resultOK 是 Response-Broadcast 成功的结果
+	resultOK = "OK"
+)
+
+var (
+	// ErrBusy 表示设备的音频输出通道正在广播
+	ErrBusy = errors.New("target is busy")
+	// ErrRejected 表示设备拒绝了广播通知
+	ErrRejected = errors.New("broadcast rejected")
+	// ErrInviteTimeout 表示等待设备的 INVITE 超时
+	ErrInviteTimeout = errors.New("wait invite timeout")
+)
+
+// Option 是 NewManager 的参数
+type Option struct {
+	Ser *sip.Server
+	// 平台的媒体地址，用于应答的 sdp
+	LocalIP string
+	// 等待设备 INVITE 的超时时间，默认 DefaultInviteTimeout
+	InviteTimeout time.Duration
+	// 编码的优先级，默认 CodecPCMA/CodecPCMU
+	Codecs []string
+	// 在应答设备之前调用，一般是调用 Session.SendRTP 绑定 zlm 的发送
+	Bind func(s *Session) error
+	// 设备发送 BYE 结束会话的回调
+	OnClose func(s *Session)
+}
+
+// Manager 用于语音广播，发送广播通知后等待设备的 INVITE ，
+// 然后应答并绑定音频的发送
+type Manager struct {
+	opt Option
+	// 等待 INVITE 的广播，key 是 TargetID
+	pending gsync.Map[string, *pending]
+	// 会话，key 是 Call-ID
+	sessions gsync.Map[string, *Session]
+}
+
+// pending 表示等待设备 INVITE 的广播
+type pending struct {
+	device   request.Request
+	sourceID string
+	c        chan *Session
+}
+
+// NewManager 返回新的 Manager
+func NewManager(opt *Option) *Manager {
+	m := new(Manager)
+	m.opt = *opt
+	if m.opt.InviteTimeout <= 0 {
+		m.opt.InviteTimeout = DefaultInviteTimeout
+	}
+	if len(m.opt.Codecs) < 1 {
+		m.opt.Codecs = []string{CodecPCMA, CodecPCMU}
+	}
+	m.pending.Init()
+	m.sessions.Init()
+	return m
+}
+
+// Broadcast 向设备发送广播通知，等待设备的 INVITE 建立会话后返回
+func (m *Manager) Broadcast(ctx context.Context, trace string, dev request.Request, sourceID, targetID string) (*Session, error) {
+	p := &pending{device: dev, sourceID: sourceID, c: make(chan *Session, 1)}
+	if !m.pending.TrySet(targetID, p) {
+		return nil, ErrBusy
+	}
+	defer m.pending.Del(targetID)
+	// 通知
+	result, err := notify.SendBroadcast(ctx, &notify.Broadcast{
+		Ser:      m.opt.Ser,
+		Device:   dev,
+		SourceID: sourceID,
+		TargetID: targetID,
+		TraceID:  trace,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result != resultOK {
+		return nil, ErrRejected
+	}
+	// 等待
+	timer := time.NewTimer(m.opt.InviteTimeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, ErrInviteTimeout
+	case s := <-p.c:
+		return s, nil
+	}
+}
+
+// Stop 平台结束会话，停止发送并向设备发送 BYE
+func (m *Manager) Stop(ctx context.Context, trace string, s *Session) error {
+	if m.sessions.Take(s.CallID) == nil {
+		return nil
+	}
+	if err := s.StopRTP(ctx); err != nil {
+		log.Errorf(-1, trace, 0, "talk %s stop rtp %v", s.TargetID, err)
+	}
+	return request.SendBye(ctx, trace, m.opt.Ser, s.Device, s.TargetID, s, nil)
+}
+
+// Sessions 返回所有的会话
+func (m *Manager) Sessions() []*Session {
+	return m.sessions.Values()
+}
+
+// HandleInvite 处理设备广播的 INVITE ，用于 sip.Server.RequestFunc ，
+// 不是等待中的广播直接返回
+func (m *Manager) HandleInvite(ctx *sip.Request) {
+	targetID := ctx.Header.From.URI.Name
+	p := m.pending.Get(targetID)
+	if p == nil {
+		return
+	}
+	// sdp
+	var offer sdp.Session
+	if err := offer.ParseFrom(bytes.NewReader(ctx.Body.Bytes())); err != nil {
+		ctx.ResponseStatus(sip.StatusBadRequest)
+		return
+	}
+	s := new(Session)
+	s.Device = p.device
+	s.SourceID = p.sourceID
+	s.TargetID = targetID
+	s.CallID = ctx.Header.CallID
+	s.RemoteTag = ctx.Header.From.Tag
+	s.LocalIP = m.opt.LocalIP
+	if !s.parseOffer(&offer, m.opt.Codecs) {
+		ctx.ResponseStatus(sip.StatusNotAcceptableHere)
+		return
+	}
+	// 绑定
+	if m.opt.Bind != nil {
+		if err := m.opt.Bind(s); err != nil {
+			log.Errorf(-1, ctx.Trace(), 0, "talk %s bind %v", targetID, err)
+			ctx.ResponseStatus(sip.StatusServerInternalError)
+			return
+		}
+	}
+	// 应答
+	msg := ctx.NewResponse(sip.StatusOK, sip.StatusPhrase(sip.StatusOK))
+	msg.Header.ResetOther()
+	msg.Header.ContentType = request.ContentTypeSDP
+	msg.Header.Contact.Scheme = sip.SIP
+	msg.Header.Contact.Name = s.SourceID
+	msg.Header.Contact.Domain = p.device.GetContactAddress()
+	s.answer().FormatTo(&msg.Body)
+	s.LocalTag = msg.Header.To.Tag
+	m.sessions.Set(s.CallID, s)
+	if err := ctx.Response(msg); err != nil {
+		m.sessions.Del(s.CallID)
+		s.StopRTP(context.Background())
+		return
+	}
+	// 通知
+	select {
+	case p.c <- s:
+	default:
+	}
+}
+
+// HandleAck 处理设备的 ACK ，用于 sip.Server.RequestFunc
+func (m *Manager) HandleAck(ctx *sip.Request) {
+	if m.sessions.Has(ctx.Header.CallID) {
+		ctx.Response(nil)
+	}
+}
+
+// HandleBye 处理设备的 BYE ，用于 sip.Server.RequestFunc ，
+// 不是广播的会话直接返回
+func (m *Manager) HandleBye(ctx *sip.Request) {
+	s := m.sessions.Take(ctx.Header.CallID)
+	if s == nil {
+		return
+	}
+	if err := s.StopRTP(context.Background()); err != nil {
+		log.Errorf(-1, ctx.Trace(), 0, "talk %s stop rtp %v", s.TargetID, err)
+	}
+	ctx.ResponseStatus(sip.StatusOK)
+	if m.opt.OnClose != nil {
+		m.opt.OnClose(s)
+	}
+}
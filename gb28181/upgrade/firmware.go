@@ -0,0 +1,151 @@
+package upgrade
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Firmware 表示一个固件文件
+type Firmware struct {
+	// 标识，也是下载路径的最后一段
+	ID string `json:"id"`
+	// 版本
+	Version string `json:"version"`
+	// 设备生产商
+	Manufacturer string `json:"manufacturer"`
+	// 文件路径
+	Path string `json:"-"`
+	// 文件大小
+	Size int64 `json:"size"`
+	// 校验，十六进制
+	MD5    string `json:"md5"`
+	SHA256 string `json:"sha256"`
+}
+
+// FileServer 用于设备下载固件，支持 Range 请求，
+// 响应头 Content-MD5 和 Digest 带有文件的校验
+type FileServer struct {
+	lock sync.RWMutex
+	// key 是 Firmware.ID
+	files map[string]*Firmware
+}
+
+// NewFileServer 返回新的 FileServer
+func NewFileServer() *FileServer {
+	s := new(FileServer)
+	s.files = make(map[string]*Firmware)
+	return s
+}
+
+// Add 添加固件，id 为空使用文件名，会计算文件的校验
+func (s *FileServer) Add(id, file, version, manufacturer string) (*Firmware, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// 校验
+	h1 := md5.New()
+	h2 := sha256.New()
+	n, err := io.Copy(io.MultiWriter(h1, h2), f)
+	if err != nil {
+		return nil, err
+	}
+	//
+	fw := new(Firmware)
+	fw.ID = id
+	if fw.ID == "" {
+		fw.ID = filepath.Base(file)
+	}
+	fw.Version = version
+	fw.Manufacturer = manufacturer
+	fw.Path = file
+	fw.Size = n
+	fw.MD5 = hex.EncodeToString(h1.Sum(nil))
+	fw.SHA256 = hex.EncodeToString(h2.Sum(nil))
+	//
+	s.lock.Lock()
+	s.files[fw.ID] = fw
+	s.lock.Unlock()
+	return fw, nil
+}
+
+// Del 移除固件，不会删除文件
+func (s *FileServer) Del(id string) {
+	s.lock.Lock()
+	delete(s.files, id)
+	s.lock.Unlock()
+}
+
+// Get 返回固件
+func (s *FileServer) Get(id string) *Firmware {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.files[id]
+}
+
+// ServeHTTP 实现 http.Handler ，路径的最后一段是 Firmware.ID
+func (s *FileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	fw := s.Get(path.Base(r.URL.Path))
+	if fw == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(fw.Path)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// 校验
+	h := w.Header()
+	h.Set("ETag", `"`+fw.SHA256+`"`)
+	h.Set("Content-Type", "application/octet-stream")
+	// 支持 Range ，整个文件的校验和只在 200 的时候设置
+	http.ServeContent(&digestWriter{ResponseWriter: w, fw: fw}, r, filepath.Base(fw.Path), fi.ModTime(), f)
+}
+
+// digestWriter 在响应 200 的时候设置整个文件的 Content-MD5 和 Digest ，
+// 206 只有部分内容，不能设置
+type digestWriter struct {
+	http.ResponseWriter
+	fw *Firmware
+}
+
+// WriteHeader 实现 http.ResponseWriter
+func (w *digestWriter) WriteHeader(code int) {
+	if code == http.StatusOK {
+		h := w.Header()
+		if b, err := hex.DecodeString(w.fw.MD5); err == nil {
+			h.Set("Content-MD5", base64.StdEncoding.EncodeToString(b))
+		}
+		if b, err := hex.DecodeString(w.fw.SHA256); err == nil {
+			h.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(b))
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// HandleGin 是 ServeHTTP 的 gin 适配
+func (s *FileServer) HandleGin(ctx *gin.Context) {
+	s.ServeHTTP(ctx.Writer, ctx.Request)
+}
@@ -0,0 +1,201 @@
+package upgrade
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"goutil/gb28181/request"
+	devicecontrol "goutil/gb28181/request/message/control/device_control"
+	"goutil/gb28181/xml"
+	"goutil/sip"
+	gsync "goutil/sync"
+	"goutil/uid"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout 是等待升级结果的默认超时时间
+	DefaultTimeout = 30 * time.Minute
+	// resultOK 是成功的结果
+	resultOK = "OK"
+)
+
+var (
+	// ErrRejected 表示设备拒绝了升级
+	ErrRejected = errors.New("upgrade rejected")
+	// ErrTimeout 表示等待升级结果超时
+	ErrTimeout = errors.New("wait upgrade result timeout")
+	// ErrFailed 表示设备升级失败，具体原因看 Job.Reason
+	ErrFailed = errors.New("upgrade failed")
+)
+
+// Option 是 NewManager 的参数
+type Option struct {
+	Ser *sip.Server
+	// 固件的下载地址，会在后面加上 /Firmware.ID ，
+	// 比如 http://192.168.1.10:8080/firmware
+	BaseURL string
+	// 等待升级结果的超时时间，默认 DefaultTimeout
+	Timeout time.Duration
+	// 升级结束的回调，成功，失败，超时和设备拒绝都会调用
+	OnResult func(*Job)
+}
+
+// Job 表示一个升级任务
+type Job struct {
+	// 会话标识
+	SessionID string `json:"sessionID"`
+	// 设备编号
+	DeviceID string `json:"deviceID"`
+	// 固件
+	Firmware *Firmware `json:"firmware"`
+	// 开始时间戳
+	StartTime int64 `json:"startTime"`
+	// 结束时间戳
+	EndTime int64 `json:"endTime"`
+	// 设备上报的固件版本，结束之后才有
+	Version string `json:"version"`
+	// 设备上报的失败原因，结束之后才有
+	Reason string `json:"reason"`
+	//
+	once  sync.Once
+	timer *time.Timer
+	done  chan struct{}
+	err   error
+}
+
+// Done 返回结束的信号
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Err 返回结果，nil 表示成功
+func (j *Job) Err() error {
+	return j.err
+}
+
+// Wait 等待升级结束
+func (j *Job) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-j.done:
+		return j.err
+	}
+}
+
+// finish 结束，设置结果和设备上报的数据，返回是否第一次
+func (j *Job) finish(err error, version, reason string) bool {
+	ok := false
+	j.once.Do(func() {
+		ok = true
+		j.timer.Stop()
+		j.EndTime = time.Now().Unix()
+		j.Version = version
+		j.Reason = reason
+		j.err = err
+		close(j.done)
+	})
+	return ok
+}
+
+// wait 在协程中等待超时
+func (j *Job) wait(m *Manager) {
+	select {
+	case <-j.timer.C:
+		m.finish(j, ErrTimeout, "", "")
+	case <-j.done:
+	}
+}
+
+// Manager 用于设备升级，发送升级命令后等待设备的升级结果通知
+type Manager struct {
+	opt Option
+	// key 是 SessionID
+	jobs gsync.Map[string, *Job]
+}
+
+// NewManager 返回新的 Manager
+func NewManager(opt *Option) *Manager {
+	m := new(Manager)
+	m.opt = *opt
+	if m.opt.Timeout <= 0 {
+		m.opt.Timeout = DefaultTimeout
+	}
+	m.jobs.Init()
+	return m
+}
+
+// Upgrade 向设备发送升级命令，设备接受后返回任务，使用 Job.Wait 等待结果
+func (m *Manager) Upgrade(ctx context.Context, trace string, dev request.Request, fw *Firmware) (*Job, error) {
+	j := new(Job)
+	j.SessionID = uid.SnowflakeIDString()
+	j.DeviceID = dev.GetToID()
+	j.Firmware = fw
+	j.StartTime = time.Now().Unix()
+	j.done = make(chan struct{})
+	// 超时，在添加和发送之前设置，设备可能很快就通知结果
+	j.timer = time.NewTimer(m.opt.Timeout)
+	m.jobs.Set(j.SessionID, j)
+	go j.wait(m)
+	// 命令
+	result, err := devicecontrol.SendUpgrade(ctx, &devicecontrol.Upgrade{
+		Ser:    m.opt.Ser,
+		Device: dev,
+		Data: &xml.MessageDeviceUpgrade{
+			Firmware:     fw.Version,
+			FileURL:      m.opt.BaseURL + "/" + fw.ID,
+			Manufacturer: fw.Manufacturer,
+			SessionID:    j.SessionID,
+		},
+		TraceID: trace,
+	})
+	if err == nil && result != resultOK {
+		err = ErrRejected
+	}
+	if err != nil {
+		m.finish(j, err, "", "")
+		return nil, err
+	}
+	return j, nil
+}
+
+// Jobs 返回正在进行的任务
+func (m *Manager) Jobs() []*Job {
+	return m.jobs.Values()
+}
+
+// finish 结束任务
+func (m *Manager) finish(j *Job, err error, version, reason string) {
+	m.jobs.Del(j.SessionID)
+	if !j.finish(err, version, reason) {
+		return
+	}
+	if m.opt.OnResult != nil {
+		m.opt.OnResult(j)
+	}
+}
+
+// HandleMessage 处理设备的 Notify-DeviceUpgradeResult ，用于 sip.Server.RequestFunc ，
+// 不是升级任务的通知直接返回
+func (m *Manager) HandleMessage(ctx *sip.Request) {
+	// 解析，不能影响后面的函数读取
+	var msg xml.Message
+	if err := xml.Decode(bytes.NewReader(ctx.Body.Bytes()), &msg); err != nil {
+		return
+	}
+	if msg.XMLName.Local != xml.TypeNotify || msg.CmdType != xml.CmdDeviceUpgradeResult {
+		return
+	}
+	j := m.jobs.Get(msg.SessionID)
+	if j == nil {
+		return
+	}
+	ctx.ResponseStatus(sip.StatusOK)
+	var err error
+	if msg.UpgradeResult != resultOK {
+		err = ErrFailed
+	}
+	go m.finish(j, err, msg.Firmware, msg.UpgradeFailedReason)
+}
@@ -0,0 +1,16 @@
+package xml
+
+import "encoding/xml"
+
+// Broadcast 用于语音广播通知 Notify-Broadcast ，
+// 设备的应答 Response-Broadcast 使用 Result 解析
+type Broadcast struct {
+	// 基本
+	XMLName xml.Name
+	CmdType string
+	SN      string
+	// 语音输入设备的设备编码
+	SourceID string
+	// 语音输出设备的设备编码
+	TargetID string
+}
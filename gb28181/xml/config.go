@@ -0,0 +1,143 @@
+package xml
+
+// 配置类型，用于 Query-ConfigDownload 的 ConfigType
+const (
+	ConfigBasicParam          = "BasicParam"
+	ConfigVideoParamOpt       = "VideoParamOpt"
+	ConfigSVACEncodeConfig    = "SVACEncodeConfig"
+	ConfigSVACDecodeConfig    = "SVACDecodeConfig"
+	ConfigVideoParamAttribute = "VideoParamAttribute"
+	ConfigOSDConfig           = "OSDConfig"
+	ConfigPictureMask         = "PictureMask"
+	ConfigFrameMirror         = "FrameMirror"
+	ConfigAlarmReport         = "AlarmReport"
+	ConfigSnapShotConfig      = "SnapShotConfig"
+)
+
+// MessageVideoParamAttribute 是 Message 的 VideoParamAttribute 字段
+type MessageVideoParamAttribute struct {
+	Num  int64                             `xml:"Num,attr"`
+	Item []*MessageVideoParamAttributeItem `xml:",omitempty"`
+}
+
+// MessageVideoParamAttributeItem 是 MessageVideoParamAttribute 的 Item 字段
+type MessageVideoParamAttributeItem struct {
+	// 码流编号
+	// 0: 主码流
+	// 1: 子码流 1
+	// 2: 子码流 2
+	StreamNumber string `xml:",omitempty" json:"streamNumber"`
+	// 视频编码格式，取值参见附录 F 中 SDP f 字段规定
+	VideoFormat string `xml:",omitempty" json:"videoFormat"`
+	// 分辨率，取值参见附录 F 中 SDP f 字段规定
+	Resolution string `xml:",omitempty" json:"resolution"`
+	// 帧率，取值 0~99
+	FrameRate string `xml:",omitempty" json:"frameRate"`
+	// 码率类型
+	// 1: 固定码率
+	// 2: 可变码率
+	BitRateType string `xml:",omitempty" json:"bitRateType"`
+	// 视频码率，单位 kbps
+	VideoBitRate string `xml:",omitempty" json:"videoBitRate"`
+}
+
+// MessageOSDConfig 是 Message 的 OSDConfig 字段
+type MessageOSDConfig struct {
+	// 配置窗口长度像素值
+	Length int64 `xml:",omitempty" json:"length"`
+	// 配置窗口宽度像素值
+	Width int64 `xml:",omitempty" json:"width"`
+	// 时间显示开关
+	// 0: 关闭
+	// 1: 打开
+	TimeEnable string `xml:",omitempty" json:"timeEnable"`
+	// 时间显示类型
+	// 0: YYYY-MM-DD HH:MM:SS
+	// 1: YYYY年MM月DD日 HH:MM:SS
+	TimeType string `xml:",omitempty" json:"timeType"`
+	// 时间显示 X 坐标
+	TimeX int64 `xml:",omitempty" json:"timeX"`
+	// 时间显示 Y 坐标
+	TimeY int64 `xml:",omitempty" json:"timeY"`
+	// 文字个数
+	SumNum int64 `xml:",omitempty" json:"sumNum"`
+	// 文字
+	Item []*MessageOSDConfigItem `xml:",omitempty" json:"item"`
+}
+
+// MessageOSDConfigItem 是 MessageOSDConfig 的 Item 字段
+type MessageOSDConfigItem struct {
+	// 文字内容
+	Text string `xml:",omitempty" json:"text"`
+	// 文字显示 X 坐标
+	X int64 `xml:",omitempty" json:"x"`
+	// 文字显示 Y 坐标
+	Y int64 `xml:",omitempty" json:"y"`
+}
+
+// MessagePictureMask 是 Message 的 PictureMask 字段
+type MessagePictureMask struct {
+	// 遮挡开关
+	// 0: 关闭
+	// 1: 打开
+	On string `xml:",omitempty" json:"on"`
+	// 区域个数
+	SumNum int64 `xml:",omitempty" json:"sumNum"`
+	// 区域
+	RegionList *MessagePictureMaskRegionList `xml:",omitempty" json:"regionList"`
+}
+
+// MessagePictureMaskRegionList 是 MessagePictureMask 的 RegionList 字段
+type MessagePictureMaskRegionList struct {
+	Item []*MessagePictureMaskRegion `xml:",omitempty" json:"item"`
+}
+
+// MessagePictureMaskRegion 是 MessagePictureMaskRegionList 的 Item 字段
+type MessagePictureMaskRegion struct {
+	// 区域编号，取值 1~4
+	Seq int64 `xml:",omitempty" json:"seq"`
+	// 区域左上角和右下角坐标，格式 x1,y1,x2,y2
+	Point string `xml:",omitempty" json:"point"`
+}
+
+// MessageAlarmReport 是 Message 的 AlarmReport 字段
+type MessageAlarmReport struct {
+	// 移动侦测报警上报开关
+	// 0: 关闭
+	// 1: 打开
+	MotionDetection string `xml:",omitempty" json:"motionDetection"`
+	// 区域入侵报警上报开关
+	// 0: 关闭
+	// 1: 打开
+	FieldDetection string `xml:",omitempty" json:"fieldDetection"`
+}
+
+// MessageSnapShotConfig 是 Message 的 SnapShotConfig 字段
+type MessageSnapShotConfig struct {
+	// 连拍张数，取值 1~10
+	SnapNum int64 `xml:",omitempty" json:"snapNum"`
+	// 单张抓拍间隔时间，单位秒，最短 1 秒
+	Interval int64 `xml:",omitempty" json:"interval"`
+	// 抓拍图像上传路径
+	UploadURL string `xml:",omitempty" json:"uploadURL"`
+	// 会话标识，设备上传图像和通知时携带
+	SessionID string `xml:",omitempty" json:"sessionID"`
+}
+
+// MessageSnapShotList 是 Message 的 SnapShotList 字段
+type MessageSnapShotList struct {
+	// 图像文件标识
+	SnapShotFileID []string `xml:",omitempty" json:"snapShotFileID"`
+}
+
+// MessageDeviceUpgrade 是 Message 的 DeviceUpgrade 字段
+type MessageDeviceUpgrade struct {
+	// 升级的固件版本
+	Firmware string `xml:",omitempty" json:"firmware"`
+	// 固件的下载地址
+	FileURL string `xml:",omitempty" json:"fileURL"`
+	// 设备生产商
+	Manufacturer string `xml:",omitempty" json:"manufacturer"`
+	// 会话标识，设备通知升级结果时携带
+	SessionID string `xml:",omitempty" json:"sessionID"`
+}
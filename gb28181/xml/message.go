@@ -43,6 +43,9 @@ type Message struct {
 	// Control-DeviceControl-HomePosition
 	// 看守位控制命令
 	HomePosition *MessageHomePosition `xml:",omitempty"`
+	// Control-DeviceControl-PTZPreciseCtrl
+	// PTZ 精准控制，2022
+	PTZPreciseCtrl *MessagePTZPreciseCtrl `xml:",omitempty"`
 	// Control-DeviceConfig
 	// 基本参数配置
 	BasicParam *MessageBasicParam `xml:",omitempty"`
@@ -57,6 +60,44 @@ type Message struct {
 	// Response-ConfigDownload
 	// 视频参数范围，各可选参数以 '/' 分隔
 	VideoParamOpt *MessageVideoParamOpt `xml:",omitempty"`
+	// Control-DeviceConfig
+	// Response-ConfigDownload
+	// 视频参数属性，2022
+	VideoParamAttribute *MessageVideoParamAttribute `xml:",omitempty"`
+	// Control-DeviceConfig
+	// Response-ConfigDownload
+	// 视频画面 OSD 配置，2022
+	OSDConfig *MessageOSDConfig `xml:",omitempty"`
+	// Control-DeviceConfig
+	// Response-ConfigDownload
+	// 画面遮挡配置，2022
+	PictureMask *MessagePictureMask `xml:",omitempty"`
+	// Control-DeviceConfig
+	// Response-ConfigDownload
+	// 画面翻转配置，2022
+	// 0: 不翻转
+	// 1: 水平翻转
+	// 2: 垂直翻转
+	// 3: 水平和垂直翻转
+	FrameMirror string `xml:",omitempty"`
+	// Control-DeviceConfig
+	// Response-ConfigDownload
+	// 报警上报开关，2022
+	AlarmReport *MessageAlarmReport `xml:",omitempty"`
+	// Control-DeviceConfig
+	// Control-DeviceControl
+	// Response-ConfigDownload
+	// 图像抓拍配置，2022
+	SnapShotConfig *MessageSnapShotConfig `xml:",omitempty"`
+	// Control-DeviceControl
+	// 设备软件升级，2022
+	DeviceUpgrade *MessageDeviceUpgrade `xml:",omitempty"`
+	// Notify-DeviceUpgradeResult
+	// 升级结果，OK/ERROR ，2022
+	UpgradeResult string `xml:",omitempty"`
+	// Notify-DeviceUpgradeResult
+	// 升级失败的原因，2022
+	UpgradeFailedReason string `xml:",omitempty"`
 	// Query-Catalog
 	// Query-RecordInfo
 	// 录像起始时间
@@ -142,6 +183,8 @@ type Message struct {
 	// 视频参数范围: VideoParamOpt
 	// SVAC 编码配置: SVACEncodeConfig
 	// SVAC 解码配置: SVACDecodeConfig
+	// 2022 增加了 VideoParamAttribute/OSDConfig/PictureMask/
+	// FrameMirror/AlarmReport/SnapShotConfig
 	// 可同时查询多个配置类型，各类型以 '/' 分隔，
 	// 可返回与查询 SN 值相同的多个响应，
 	// 每个响应对应一个配置类型。
@@ -217,6 +260,7 @@ type Message struct {
 	// 设备型号
 	Model string `xml:",omitempty"`
 	// Response-DeviceInfo
+	// Notify-DeviceUpgradeResult
 	// 设备固件版本
 	Firmware string `xml:",omitempty"`
 	// Response-DeviceInfo
@@ -251,6 +295,40 @@ type Message struct {
 	RecordList *MessageRecordList `xml:",omitempty"`
 	// Response-PresetQuery
 	PresetList *MessagePresetList `xml:",omitempty"`
+	// Response-PTZPosition
+	// 水平位置，2022
+	Pan string `xml:",omitempty"`
+	// Response-PTZPosition
+	// 垂直位置，2022
+	Tilt string `xml:",omitempty"`
+	// Response-PTZPosition
+	// 变倍，2022
+	Zoom string `xml:",omitempty"`
+	// Response-PTZPosition
+	// 水平视场角，2022
+	HorizontalFieldAngle string `xml:",omitempty"`
+	// Response-PTZPosition
+	// 垂直视场角，2022
+	VerticalFieldAngle string `xml:",omitempty"`
+	// Response-PTZPosition
+	// 可视距离，2022
+	MaxViewDistance string `xml:",omitempty"`
+	// Query-CruiseTrackQuery
+	// 巡航轨迹编号，2022
+	Number string `xml:",omitempty"`
+	// Response-CruiseTrackListQuery
+	// 巡航轨迹列表，2022
+	CruiseTrackList *MessageCruiseTrackList `xml:",omitempty"`
+	// Response-CruiseTrackQuery
+	// 巡航轨迹，2022
+	CruiseTrack *MessageCruiseTrack `xml:",omitempty"`
+	// Notify-UploadSnapShotFinished
+	// Notify-DeviceUpgradeResult
+	// 会话标识，和 SnapShotConfig/DeviceUpgrade 的一致
+	SessionID string `xml:",omitempty"`
+	// Notify-UploadSnapShotFinished
+	// 抓拍的图像文件标识列表
+	SnapShotList *MessageSnapShotList `xml:",omitempty"`
 	// Control-DeviceConfig
 	// Notify-Alarm
 	Info *MessageInfo `xml:",omitempty"`
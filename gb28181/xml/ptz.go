@@ -0,0 +1,51 @@
+package xml
+
+// MessagePTZPreciseCtrl 是 Message 的 PTZPreciseCtrl 字段
+type MessagePTZPreciseCtrl struct {
+	// 水平位置，取值 0~360 ，单位度
+	Pan string `xml:",omitempty" json:"pan"`
+	// 垂直位置，取值 -30~90 ，单位度
+	Tilt string `xml:",omitempty" json:"tilt"`
+	// 变倍，取值 1~最大倍数
+	Zoom string `xml:",omitempty" json:"zoom"`
+}
+
+// MessageCruiseTrackList 是 Message 的 CruiseTrackList 字段
+type MessageCruiseTrackList struct {
+	Num  int64                         `xml:"Num,attr"`
+	Item []*MessageCruiseTrackListItem `xml:"CruiseTrack,omitempty" json:"item"`
+}
+
+// MessageCruiseTrackListItem 是 MessageCruiseTrackList 的 CruiseTrack 字段
+type MessageCruiseTrackListItem struct {
+	// 巡航轨迹编号
+	Number string `xml:",omitempty" json:"number"`
+	// 巡航轨迹名称
+	Name string `xml:",omitempty" json:"name"`
+}
+
+// MessageCruiseTrack 是 Message 的 CruiseTrack 字段
+type MessageCruiseTrack struct {
+	// 巡航轨迹编号
+	Number string `xml:",omitempty" json:"number"`
+	// 巡航轨迹名称
+	Name string `xml:",omitempty" json:"name"`
+	// 巡航点
+	CruisePoint *MessageCruisePointList `xml:",omitempty" json:"cruisePoint"`
+}
+
+// MessageCruisePointList 是 MessageCruiseTrack 的 CruisePoint 字段
+type MessageCruisePointList struct {
+	Num  int64                 `xml:"Num,attr"`
+	Item []*MessageCruisePoint `xml:",omitempty" json:"item"`
+}
+
+// MessageCruisePoint 是 MessageCruisePointList 的 Item 字段
+type MessageCruisePoint struct {
+	// 预置位编号
+	PresetIndex string `xml:",omitempty" json:"presetIndex"`
+	// 停留时间，单位秒
+	StayTime string `xml:",omitempty" json:"stayTime"`
+	// 速度
+	Speed string `xml:",omitempty" json:"speed"`
+}
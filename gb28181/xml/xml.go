@@ -35,6 +35,12 @@ const (
 	CmdMediaStatus    = "MediaStatus"
 	CmdConfigDownload = "ConfigDownload"
 	CmdPresetQuery    = "PresetQuery"
+	// 2022
+	CmdUploadSnapShotFinished = "UploadSnapShotFinished"
+	CmdDeviceUpgradeResult    = "DeviceUpgradeResult"
+	CmdPTZPosition            = "PTZPosition"
+	CmdCruiseTrackListQuery   = "CruiseTrackListQuery"
+	CmdCruiseTrackQuery       = "CruiseTrackQuery"
 )
 
 // 编码
@@ -0,0 +1,374 @@
+package onvif
+
+import (
+	"context"
+	"errors"
+	"goutil/log"
+	owve "goutil/onvif/wsdl/ver10/events"
+	"goutil/soap"
+	gsync "goutil/sync"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 错误
+var (
+	ErrEventCapabilityUnsupported = errors.New("event capability unsupported")
+)
+
+// 事件类型
+const (
+	EventTypeMotion = "motion"
+	EventTypeTamper = "tamper"
+	EventTypeIO     = "io"
+	EventTypeOther  = "other"
+)
+
+// PullPoint 的默认参数
+const (
+	DefaultEventTerminationTime = time.Minute
+	DefaultEventPullTimeout     = 10 * time.Second
+	DefaultEventMessageLimit    = 100
+	DefaultEventRetryInterval   = 5 * time.Second
+	DefaultEventChanSize        = 100
+)
+
+var (
+	// 判断事件类型的主题关键字，小写
+	eventTypeTopics = []struct {
+		typ   string
+		words []string
+	}{
+		{EventTypeMotion, []string{"motion"}},
+		{EventTypeTamper, []string{"tamper", "globalscenechange"}},
+		{EventTypeIO, []string{"digitalinput", "relay", "/io/"}},
+	}
+	// 表示状态的数据名称，按顺序取第一个
+	eventStateNames = []string{"State", "IsMotion", "IsTamper", "LogicalState", "Value"}
+)
+
+// Event 表示设备的一个事件
+type Event struct {
+	// 设备地址
+	Host string `json:"host"`
+	// 类型，EventTypeXXX
+	Type string `json:"type"`
+	// 主题，去掉了命名空间的前缀，比如 RuleEngine/CellMotionDetector/Motion
+	Topic string `json:"topic"`
+	// Initialized/Changed/Deleted
+	Operation string `json:"operation"`
+	// 报警状态，true 表示开始，false 表示结束
+	State bool `json:"state"`
+	// 事件时间，设备格式
+	Time string `json:"time"`
+	// 事件时间戳，解析失败是 0
+	Timestamp int64 `json:"timestamp"`
+	// 来源，比如 VideoSourceConfigurationToken/InputToken
+	Source map[string]string `json:"source,omitempty"`
+	// 数据
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// Init 使用 msg 初始化
+func (e *Event) Init(host string, msg *owve.NotificationMessage) {
+	e.Host = host
+	e.Topic = trimTopicPrefix(msg.Topic.Value)
+	e.Type = EventTypeOther
+	topic := "/" + strings.ToLower(e.Topic) + "/"
+	for _, t := range eventTypeTopics {
+		for _, w := range t.words {
+			if strings.Contains(topic, w) {
+				e.Type = t.typ
+				break
+			}
+		}
+		if e.Type != EventTypeOther {
+			break
+		}
+	}
+	m := &msg.Message.Message
+	e.Operation = m.PropertyOperation
+	e.Time = m.UtcTime
+	if t := parseEventTime(m.UtcTime); !t.IsZero() {
+		e.Timestamp = t.Unix()
+	}
+	e.Source = itemMap(m.Source)
+	e.Data = itemMap(m.Data)
+	for _, name := range eventStateNames {
+		if v, ok := e.Data[name]; ok {
+			switch strings.ToLower(v) {
+			case "true", "1", "active", "on":
+				e.State = true
+			}
+			break
+		}
+	}
+}
+
+// trimTopicPrefix 去掉主题每一段的命名空间前缀
+func trimTopicPrefix(topic string) string {
+	parts := strings.Split(strings.TrimSpace(topic), "/")
+	for i, p := range parts {
+		if n := strings.IndexByte(p, ':'); n >= 0 {
+			parts[i] = p[n+1:]
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// itemMap 转换为 map
+func itemMap(l *owve.ItemList) map[string]string {
+	if l == nil || len(l.SimpleItem) < 1 {
+		return nil
+	}
+	m := make(map[string]string)
+	for _, item := range l.SimpleItem {
+		m[item.Name] = item.Value
+	}
+	return m
+}
+
+// parseEventTime 解析设备的时间，没有时区的当作 utc
+func parseEventTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// IsEventServiceOK 服务是否支持
+func (d *Device) IsEventServiceOK() bool {
+	return d.Capabilities != nil && d.Capabilities.Events != nil && d.Capabilities.Events.XAddr != ""
+}
+
+// CreatePullPointSubscription 创建 PullPoint 订阅，返回的订阅地址已经替换了 host
+func (d *Device) CreatePullPointSubscription(ctx context.Context, filter string, terminationTime time.Duration) (*owve.CreatePullPointSubscriptionResponse, error) {
+	if !d.IsEventServiceOK() {
+		return nil, ErrEventCapabilityUnsupported
+	}
+	res, err := owve.CreatePullPointSubscription(ctx, d.Capabilities.Events.XAddr, soap.NewSecurity(d.username, d.password), filter, terminationTime)
+	if err != nil {
+		return nil, err
+	}
+	if res.SubscriptionReference.Address != "" {
+		res.SubscriptionReference.Address = d.ReplaceXAddrHost(res.SubscriptionReference.Address)
+	}
+	return res, nil
+}
+
+// PullMessages 拉取订阅的通知
+func (d *Device) PullMessages(ctx context.Context, address string, timeout time.Duration, messageLimit int) (*owve.PullMessagesResponse, error) {
+	return owve.PullMessages(ctx, address, soap.NewSecurity(d.username, d.password), timeout, messageLimit)
+}
+
+// Renew 续订
+func (d *Device) Renew(ctx context.Context, address string, terminationTime time.Duration) (*owve.RenewResponse, error) {
+	return owve.Renew(ctx, address, soap.NewSecurity(d.username, d.password), terminationTime)
+}
+
+// Unsubscribe 取消订阅
+func (d *Device) Unsubscribe(ctx context.Context, address string) error {
+	return owve.Unsubscribe(ctx, address, soap.NewSecurity(d.username, d.password))
+}
+
+// PullPointOption 是 SubscribeEvents 的参数
+type PullPointOption struct {
+	// 主题表达式，比如 tns1:VideoSource//. ，空表示全部
+	Filter string
+	// 订阅的有效时间，过期之前自动续订，默认 DefaultEventTerminationTime
+	TerminationTime time.Duration
+	// 每次拉取设备最多等待的时间，默认 DefaultEventPullTimeout
+	PullTimeout time.Duration
+	// 每次拉取的最大数量，默认 DefaultEventMessageLimit
+	MessageLimit int
+	// 失败之后重新订阅的间隔，默认 DefaultEventRetryInterval
+	RetryInterval time.Duration
+	// 通道的缓存大小，满了就丢弃，默认 DefaultEventChanSize
+	ChanSize int
+}
+
+// PullPoint 表示一个 PullPoint 订阅，
+// 在协程中拉取通知，自动续订，失败之后重新订阅，事件写入通道
+type PullPoint struct {
+	d   *Device
+	opt PullPointOption
+	// 通道
+	c *gsync.Chan[*Event]
+	// 订阅地址和本地的过期时间
+	lock        sync.Mutex
+	address     string
+	termination time.Time
+	// 用于退出
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// SubscribeEvents 创建 PullPoint 订阅，启动协程拉取通知
+func (d *Device) SubscribeEvents(ctx context.Context, opt *PullPointOption) (*PullPoint, error) {
+	if !d.IsEventServiceOK() {
+		return nil, ErrEventCapabilityUnsupported
+	}
+	p := new(PullPoint)
+	p.d = d
+	p.opt = *opt
+	if p.opt.TerminationTime <= 0 {
+		p.opt.TerminationTime = DefaultEventTerminationTime
+	}
+	if p.opt.PullTimeout <= 0 {
+		p.opt.PullTimeout = DefaultEventPullTimeout
+	}
+	if p.opt.MessageLimit <= 0 {
+		p.opt.MessageLimit = DefaultEventMessageLimit
+	}
+	if p.opt.RetryInterval <= 0 {
+		p.opt.RetryInterval = DefaultEventRetryInterval
+	}
+	if p.opt.ChanSize <= 0 {
+		p.opt.ChanSize = DefaultEventChanSize
+	}
+	// 第一次订阅
+	if err := p.subscribe(ctx); err != nil {
+		return nil, err
+	}
+	p.c = gsync.NewChan[*Event](p.opt.ChanSize)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.done = make(chan struct{})
+	go p.routine()
+	return p, nil
+}
+
+// C 返回事件的通道，Close 之后关闭
+func (p *PullPoint) C() <-chan *Event {
+	return p.c.C
+}
+
+// Address 返回当前的订阅地址，正在重新订阅时返回空
+func (p *PullPoint) Address() string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.address
+}
+
+// Close 停止拉取，关闭通道，然后取消订阅
+func (p *PullPoint) Close(ctx context.Context) error {
+	p.cancel()
+	<-p.done
+	p.c.Close()
+	address := p.Address()
+	if address == "" {
+		return nil
+	}
+	return p.d.Unsubscribe(ctx, address)
+}
+
+// subscribe 创建订阅
+func (p *PullPoint) subscribe(ctx context.Context) error {
+	res, err := p.d.CreatePullPointSubscription(ctx, p.opt.Filter, p.opt.TerminationTime)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.address = res.SubscriptionReference.Address
+	p.termination = localTermination(res.CurrentTime, res.TerminationTime, p.opt.TerminationTime)
+	p.lock.Unlock()
+	return nil
+}
+
+// localTermination 使用设备的时间差计算本地的过期时间，
+// 设备和本地的时钟可能不一致，解析失败使用 def
+func localTermination(current, termination string, def time.Duration) time.Time {
+	c := parseEventTime(current)
+	t := parseEventTime(termination)
+	if !c.IsZero() && t.After(c) {
+		return time.Now().Add(t.Sub(c))
+	}
+	return time.Now().Add(def)
+}
+
+// routine 在协程中拉取通知
+func (p *PullPoint) routine() {
+	defer func() {
+		close(p.done)
+		log.Recover(recover())
+	}()
+	for p.ctx.Err() == nil {
+		if err := p.pull(); err != nil {
+			if p.ctx.Err() != nil {
+				return
+			}
+			log.Errorf(-1, "", 0, "onvif %s pull point %v", p.d.host, err)
+			// 等待
+			timer := time.NewTimer(p.opt.RetryInterval)
+			select {
+			case <-p.ctx.Done():
+			case <-timer.C:
+			}
+			timer.Stop()
+		}
+	}
+}
+
+// pull 拉取一次，需要时重新订阅或者续订
+func (p *PullPoint) pull() error {
+	// 重新订阅
+	address := p.Address()
+	if address == "" {
+		ctx, cancel := context.WithTimeout(p.ctx, p.opt.PullTimeout)
+		defer cancel()
+		return p.subscribe(ctx)
+	}
+	// 续订，留出一次拉取的时间
+	p.lock.Lock()
+	renew := time.Until(p.termination) < p.opt.PullTimeout*2
+	p.lock.Unlock()
+	if renew {
+		if err := p.renew(address); err != nil {
+			p.reset()
+			return err
+		}
+	}
+	// 拉取，超时要比设备等待的时间长
+	ctx, cancel := context.WithTimeout(p.ctx, p.opt.PullTimeout*2)
+	defer cancel()
+	res, err := p.d.PullMessages(ctx, address, p.opt.PullTimeout, p.opt.MessageLimit)
+	if err != nil {
+		p.reset()
+		return err
+	}
+	for _, msg := range res.NotificationMessage {
+		e := new(Event)
+		e.Init(p.d.host, msg)
+		p.c.Send(e)
+	}
+	return nil
+}
+
+// renew 续订
+func (p *PullPoint) renew(address string) error {
+	ctx, cancel := context.WithTimeout(p.ctx, p.opt.PullTimeout)
+	defer cancel()
+	res, err := p.d.Renew(ctx, address, p.opt.TerminationTime)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.termination = localTermination(res.CurrentTime, res.TerminationTime, p.opt.TerminationTime)
+	p.lock.Unlock()
+	return nil
+}
+
+// reset 丢弃订阅，设备上的订阅会自己过期，
+// 正在退出时保留，用于 Close 取消订阅
+func (p *PullPoint) reset() {
+	if p.ctx.Err() != nil {
+		return
+	}
+	p.lock.Lock()
+	p.address = ""
+	p.lock.Unlock()
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"encoding/xml"
+	"goutil/onvif/wsdl/xs"
+	"goutil/soap"
+	"time"
+)
+
+// CreatePullPointSubscriptionResponse 是 CreatePullPointSubscription 的响应
+type CreatePullPointSubscriptionResponse struct {
+	XMLName xml.Name `xml:"CreatePullPointSubscriptionResponse"`
+	// 订阅地址，之后的接口都发送到这个地址
+	SubscriptionReference EndpointReference `xml:"SubscriptionReference"`
+	// 设备的时间
+	CurrentTime string `xml:"CurrentTime"`
+	// 订阅的过期时间
+	TerminationTime string `xml:"TerminationTime"`
+}
+
+// topicFilter 是订阅的过滤
+type topicFilter struct {
+	TopicExpression struct {
+		Dialect string `xml:"Dialect,attr"`
+		Value   string `xml:",chardata"`
+	} `xml:"wsnt:TopicExpression"`
+}
+
+// CreatePullPointSubscription 创建 PullPoint 订阅，
+// filter 是主题表达式，比如 tns1:VideoSource//. ，空表示全部
+func CreatePullPointSubscription(ctx context.Context, url string, security *soap.Security, filter string, terminationTime time.Duration) (*CreatePullPointSubscriptionResponse, error) {
+	// 请求体
+	var req soap.Envelope[[]any, struct {
+		XMLName                xml.Name     `xml:"tev:CreatePullPointSubscription"`
+		Filter                 *topicFilter `xml:"tev:Filter,omitempty"`
+		InitialTerminationTime string       `xml:"tev:InitialTerminationTime,omitempty"`
+	}]
+	req.SetSoapTag()
+	req.Attr = append(envelopeAttr, soap.NewSecurityNamespaceAttr())
+	req.Attr = append(req.Attr, NewTopicsNamespaceAttr())
+	req.Header.Data = newHeader(security, ActionCreatePullPointSubscription, "")
+	if filter != "" {
+		req.Body.Data.Filter = new(topicFilter)
+		req.Body.Data.Filter.TopicExpression.Dialect = TopicExpressionDialectConcreteSet
+		req.Body.Data.Filter.TopicExpression.Value = filter
+	}
+	if terminationTime > 0 {
+		req.Body.Data.InitialTerminationTime = xs.Duration(terminationTime).String()
+	}
+	// 响应体
+	var res soap.Envelope[any, CreatePullPointSubscriptionResponse]
+	// 发送
+	err := soap.Do(ctx, url, &req, &res)
+	if err != nil {
+		return nil, err
+	}
+	// 成功
+	return &res.Body.Data, nil
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"encoding/xml"
+	"goutil/onvif/wsdl/xs"
+	"goutil/soap"
+	"time"
+)
+
+// PullMessagesResponse 是 PullMessages 的响应
+type PullMessagesResponse struct {
+	XMLName xml.Name `xml:"PullMessagesResponse"`
+	// 设备的时间
+	CurrentTime string `xml:"CurrentTime"`
+	// 订阅的过期时间
+	TerminationTime string `xml:"TerminationTime"`
+	// 通知
+	NotificationMessage []*NotificationMessage `xml:"NotificationMessage"`
+}
+
+// PullMessages 拉取通知，没有通知时设备最多等待 timeout 再响应，
+// url 是订阅地址
+func PullMessages(ctx context.Context, url string, security *soap.Security, timeout time.Duration, messageLimit int) (*PullMessagesResponse, error) {
+	// 请求体
+	var req soap.Envelope[[]any, struct {
+		XMLName      xml.Name `xml:"tev:PullMessages"`
+		Timeout      string   `xml:"tev:Timeout"`
+		MessageLimit int      `xml:"tev:MessageLimit"`
+	}]
+	req.SetSoapTag()
+	req.Attr = append(envelopeAttr, soap.NewSecurityNamespaceAttr())
+	req.Header.Data = newHeader(security, ActionPullMessages, url)
+	req.Body.Data.Timeout = xs.Duration(timeout).String()
+	req.Body.Data.MessageLimit = messageLimit
+	// 响应体
+	var res soap.Envelope[any, PullMessagesResponse]
+	// 发送
+	err := soap.Do(ctx, url, &req, &res)
+	if err != nil {
+		return nil, err
+	}
+	// 成功
+	return &res.Body.Data, nil
+}
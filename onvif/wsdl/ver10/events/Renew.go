@@ -0,0 +1,40 @@
+package events
+
+import (
+	"context"
+	"encoding/xml"
+	"goutil/onvif/wsdl/xs"
+	"goutil/soap"
+	"time"
+)
+
+// RenewResponse 是 Renew 的响应
+type RenewResponse struct {
+	XMLName xml.Name `xml:"RenewResponse"`
+	// 订阅的过期时间
+	TerminationTime string `xml:"TerminationTime"`
+	// 设备的时间
+	CurrentTime string `xml:"CurrentTime"`
+}
+
+// Renew 续订，url 是订阅地址
+func Renew(ctx context.Context, url string, security *soap.Security, terminationTime time.Duration) (*RenewResponse, error) {
+	// 请求体
+	var req soap.Envelope[[]any, struct {
+		XMLName         xml.Name `xml:"wsnt:Renew"`
+		TerminationTime string   `xml:"wsnt:TerminationTime"`
+	}]
+	req.SetSoapTag()
+	req.Attr = append(envelopeAttr, soap.NewSecurityNamespaceAttr())
+	req.Header.Data = newHeader(security, ActionRenew, url)
+	req.Body.Data.TerminationTime = xs.Duration(terminationTime).String()
+	// 响应体
+	var res soap.Envelope[any, RenewResponse]
+	// 发送
+	err := soap.Do(ctx, url, &req, &res)
+	if err != nil {
+		return nil, err
+	}
+	// 成功
+	return &res.Body.Data, nil
+}
@@ -0,0 +1,24 @@
+package events
+
+import (
+	"context"
+	"encoding/xml"
+	"goutil/soap"
+)
+
+// Unsubscribe 取消订阅，url 是订阅地址
+func Unsubscribe(ctx context.Context, url string, security *soap.Security) error {
+	// 请求体
+	var req soap.Envelope[[]any, struct {
+		XMLName xml.Name `xml:"wsnt:Unsubscribe"`
+	}]
+	req.SetSoapTag()
+	req.Attr = append(envelopeAttr, soap.NewSecurityNamespaceAttr())
+	req.Header.Data = newHeader(security, ActionUnsubscribe, url)
+	// 响应体
+	var res soap.Envelope[any, struct {
+		XMLName xml.Name `xml:"UnsubscribeResponse"`
+	}]
+	// 发送
+	return soap.Do(ctx, url, &req, &res)
+}
@@ -0,0 +1,164 @@
+package events
+
+import (
+	"encoding/xml"
+	"goutil/soap"
+)
+
+const (
+	// Namespace 命名空间
+	Namespace = "http://www.onvif.org/ver10/events/wsdl"
+	// NotificationNamespace 是 ws-notification 的命名空间
+	NotificationNamespace = "http://docs.oasis-open.org/wsn/b-2"
+	// AddressingNamespace 是 ws-addressing 的命名空间
+	AddressingNamespace = "http://www.w3.org/2005/08/addressing"
+	// TopicsNamespace 是 onvif 主题的命名空间
+	TopicsNamespace = "http://www.onvif.org/ver10/topics"
+)
+
+// 主题表达式的语法
+const (
+	TopicExpressionDialectConcreteSet = "http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet"
+)
+
+// wsa:Action
+const (
+	ActionCreatePullPointSubscription = Namespace + "/EventPortType/CreatePullPointSubscriptionRequest"
+	ActionPullMessages                = Namespace + "/PullPointSubscription/PullMessagesRequest"
+	ActionRenew                       = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/RenewRequest"
+	ActionUnsubscribe                 = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/UnsubscribeRequest"
+)
+
+var (
+	envelopeAttr = []*xml.Attr{
+		soap.NewNamespaceAttr(),
+		NewNamespaceAttr(),
+		NewNotificationNamespaceAttr(),
+		NewAddressingNamespaceAttr(),
+	}
+)
+
+// NewNamespaceAttr 返回命名空间属性
+func NewNamespaceAttr() *xml.Attr {
+	return &xml.Attr{
+		Name: xml.Name{
+			Local: "xmlns:tev",
+		},
+		Value: Namespace,
+	}
+}
+
+// NewNotificationNamespaceAttr 返回命名空间属性
+func NewNotificationNamespaceAttr() *xml.Attr {
+	return &xml.Attr{
+		Name: xml.Name{
+			Local: "xmlns:wsnt",
+		},
+		Value: NotificationNamespace,
+	}
+}
+
+// NewAddressingNamespaceAttr 返回命名空间属性
+func NewAddressingNamespaceAttr() *xml.Attr {
+	return &xml.Attr{
+		Name: xml.Name{
+			Local: "xmlns:wsa",
+		},
+		Value: AddressingNamespace,
+	}
+}
+
+// NewTopicsNamespaceAttr 返回命名空间属性
+func NewTopicsNamespaceAttr() *xml.Attr {
+	return &xml.Attr{
+		Name: xml.Name{
+			Local: "xmlns:tns1",
+		},
+		Value: TopicsNamespace,
+	}
+}
+
+// addressingAction 表示 Header 的 wsa:Action
+type addressingAction struct {
+	XMLName xml.Name `xml:"wsa:Action"`
+	Value   string   `xml:",chardata"`
+}
+
+// addressingTo 表示 Header 的 wsa:To
+type addressingTo struct {
+	XMLName xml.Name `xml:"wsa:To"`
+	Value   string   `xml:",chardata"`
+}
+
+// newHeader 返回带有 ws-addressing 的 Header ，
+// 订阅的接口发送到订阅地址，设备使用 wsa:To 区分订阅
+func newHeader(security *soap.Security, action, to string) []any {
+	var h []any
+	if security != nil {
+		h = append(h, security)
+	}
+	h = append(h, &addressingAction{Value: action})
+	if to != "" {
+		h = append(h, &addressingTo{Value: to})
+	}
+	return h
+}
+
+// EndpointReference 表示订阅的地址
+type EndpointReference struct {
+	Address string `xml:"Address"`
+}
+
+// SimpleItem 表示消息中的一个键值
+type SimpleItem struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
+}
+
+// ItemList 表示消息的 Source/Key/Data
+type ItemList struct {
+	SimpleItem []*SimpleItem `xml:"SimpleItem"`
+}
+
+// Get 返回名称对应的值
+func (l *ItemList) Get(name string) string {
+	if l == nil {
+		return ""
+	}
+	for _, item := range l.SimpleItem {
+		if item.Name == name {
+			return item.Value
+		}
+	}
+	return ""
+}
+
+// Message 表示 tt:Message
+type Message struct {
+	// 时间
+	UtcTime string `xml:"UtcTime,attr"`
+	// Initialized/Changed/Deleted
+	PropertyOperation string    `xml:"PropertyOperation,attr"`
+	Source            *ItemList `xml:"Source"`
+	Key               *ItemList `xml:"Key"`
+	Data              *ItemList `xml:"Data"`
+}
+
+// 消息的 PropertyOperation
+const (
+	PropertyOperationInitialized = "Initialized"
+	PropertyOperationChanged     = "Changed"
+	PropertyOperationDeleted     = "Deleted"
+)
+
+// NotificationMessage 表示一条通知
+type NotificationMessage struct {
+	Topic struct {
+		Dialect string `xml:"Dialect,attr"`
+		Value   string `xml:",chardata"`
+	} `xml:"Topic"`
+	ProducerReference *EndpointReference `xml:"ProducerReference"`
+	Message           struct {
+		Message Message `xml:"Message"`
+	} `xml:"Message"`
+}
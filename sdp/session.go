@@ -41,6 +41,9 @@ type Session struct {
 	// 媒体信息
 	// m=
 	M []*Media
+	// 国标扩展的 ssrc ，在 m= 之前出现的
+	// y=
+	Y string
 }
 
 // Init 初始化
@@ -118,6 +121,12 @@ func (s *Session) parse(scaner *bufio.Scanner, line string) error {
 		s.C = new(Connection)
 		return s.C.Parse(value)
 	}
+	// y=
+	value = strings.TrimPrefix(line, "y=")
+	if value != line {
+		s.Y = value
+		return nil
+	}
 	// m=
 	value = strings.TrimPrefix(line, "m=")
 	if value != line {
@@ -195,4 +204,8 @@ func (s *Session) FormatTo(buf *bytes.Buffer) {
 	for _, m := range s.M {
 		m.FormatTo(buf)
 	}
+	// y=
+	if s.Y != "" {
+		fmt.Fprintf(buf, "y=%s\r\n", s.Y)
+	}
 }
@@ -1,6 +1,7 @@
 package sip
 
 import (
+	"bytes"
 	"fmt"
 	"goutil/uid"
 	"strconv"
@@ -102,6 +103,30 @@ func (c *Request) NewResponse(status, phrase string) *Message {
 	return msg
 }
 
+// NewBasicResponse 和 NewResponse 一样，但是去掉请求带过来的
+// Contact、Content-Type 和其他字段，用于没有消息体的响应
+func (c *Request) NewBasicResponse(status, phrase string) *Message {
+	msg := c.NewResponse(status, phrase)
+	msg.Header.Contact.Reset()
+	msg.Header.ContentType = ""
+	msg.Header.ResetOther()
+	return msg
+}
+
+// ResponseStatus 发送没有消息体的响应
+func (c *Request) ResponseStatus(status string) error {
+	return c.Response(c.NewBasicResponse(status, StatusPhrase(status)))
+}
+
+// ResponseError 根据 err 发送没有消息体的响应，
+// 不是 *ResponseError 则响应 500
+func (c *Request) ResponseError(err error) error {
+	if e, ok := err.(*ResponseError); ok {
+		return c.Response(c.NewBasicResponse(e.Status, e.Phrase))
+	}
+	return c.ResponseStatus(StatusServerInternalError)
+}
+
 // Response 响应回调上下文
 type Response struct {
 	_Context
@@ -134,6 +159,17 @@ func (c *Response) Finish(err error) {
 	c.f.Abort()
 }
 
+// Write 使用响应的连接直接发送 msg ，不会创建事务，
+// 一般用于 INVITE 成功后发送 ACK
+func (c *Response) Write(msg *Message) error {
+	// 日志
+	c.Ser.logger.Debugf(-1, c.Trace(), 0, "write to %s %s\n%v", c.RemoteNetwork, c.RemoteAddr, msg)
+	// 发送
+	var b bytes.Buffer
+	msg.Enc(&b)
+	return c.conn.write(b.Bytes())
+}
+
 // Status 返回 StartLine[1]
 func (c *Response) Status() string {
 	return c.Message.StartLine[1]
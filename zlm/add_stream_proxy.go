@@ -2,6 +2,7 @@ package zlm
 
 import (
 	"context"
+	"time"
 )
 
 // AddStreamProxyReq 是 AddStreamProxy 参数
@@ -18,6 +19,8 @@ type AddStreamProxyReq struct {
 	Timeout string `query:"timeout_sec"`
 	// 拉流重试次数,不传此参数或传值<=0时，则无限重试
 	RetryCount string `query:"retry_count"`
+	// 无人观看时是否自动关闭
+	AutoClose Boolean `query:"auto_close"`
 	// 是否启动 mp4 录制
 	EnableMP4Record Boolean `query:"enable_mp4"`
 	// 是否转换成 hls 协议
@@ -61,3 +64,41 @@ const (
 func AddStreamProxy(ctx context.Context, ser Server, req *AddStreamProxyReq, res *AddStreamProxyRes) error {
 	return Request(ctx, ser, AddStreamProxyPath, req, res)
 }
+
+// AddStreamProxyRetry 是 AddStreamProxyWithRetry 的重试参数
+type AddStreamProxyRetry struct {
+	// 最多调用的次数，小于 1 表示 1 次
+	Count int
+	// 第一次重试的间隔，之后每次翻倍
+	Interval time.Duration
+	// 最大的重试间隔，0 表示不限制
+	MaxInterval time.Duration
+}
+
+// AddStreamProxyWithRetry 调用 AddStreamProxy ，失败按照 retry 重试，
+// 用于源地址暂时不可用的情况，ctx 结束返回最后一次的错误，
+// retry 为 nil 只调用 1 次
+func AddStreamProxyWithRetry(ctx context.Context, ser Server, req *AddStreamProxyReq, res *AddStreamProxyRes, retry *AddStreamProxyRetry) error {
+	if retry == nil {
+		retry = new(AddStreamProxyRetry)
+	}
+	interval := retry.Interval
+	for i := 1; ; i++ {
+		err := AddStreamProxy(ctx, ser, req, res)
+		if err == nil || i >= retry.Count {
+			return err
+		}
+		// 等待
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		interval *= 2
+		if retry.MaxInterval > 0 && interval > retry.MaxInterval {
+			interval = retry.MaxInterval
+		}
+	}
+}
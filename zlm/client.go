@@ -0,0 +1,322 @@
+package zlm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	ghttp "goutil/http"
+	"goutil/log"
+	gsync "goutil/sync"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetryInterval 是第一次重试的默认间隔，之后每次翻倍
+	DefaultRetryInterval = 200 * time.Millisecond
+	// DefaultBreakerTimeout 是熔断之后的默认恢复时间
+	DefaultBreakerTimeout = 30 * time.Second
+)
+
+var (
+	// ErrCircuitOpen 表示服务已经熔断，errors.Is ErrServerNotAvailable
+	ErrCircuitOpen = fmt.Errorf("circuit open: %w", ErrServerNotAvailable)
+)
+
+// ClientOption 是 NewClient 的参数
+type ClientOption struct {
+	// 默认 http.DefaultClient
+	HTTPClient *http.Client
+	// 每次调用的超时，0 表示使用 ctx
+	Timeout time.Duration
+	// 网络错误和 5xx 的重试次数，0 表示不重试，
+	// 只重试只读的接口，其他接口需要使用 WithRetry 指定
+	Retry int
+	// 第一次重试的间隔，之后每次翻倍，默认 DefaultRetryInterval
+	RetryInterval time.Duration
+	// 最大的重试间隔，0 表示不限制
+	MaxRetryInterval time.Duration
+	// 连续失败多少次熔断，0 表示不熔断
+	BreakerThreshold int
+	// 熔断之后多久允许一次尝试，默认 DefaultBreakerTimeout
+	BreakerTimeout time.Duration
+	// 使用 POST 和 json body 提交参数，新版本的 zlm 支持
+	Post bool
+	// 是否记录每一次调用的日志
+	Log bool
+	// 每一次调用完成的回调，用于统计指标
+	OnRequest func(ser Server, apiPath string, cost time.Duration, err error)
+}
+
+// Client 用于调用 zlm 的接口，支持超时，重试和按服务熔断，
+// 可以替换包的 Request 和 RequestBody
+//
+//	c := zlm.NewClient(&zlm.ClientOption{Retry: 2})
+//	zlm.Request = c.Request
+//	zlm.RequestBody = c.RequestBody
+type Client struct {
+	opt ClientOption
+	// 熔断，key 是 BaseURL
+	breakers gsync.Map[string, *breaker]
+}
+
+// NewClient 返回新的 Client
+func NewClient(opt *ClientOption) *Client {
+	c := new(Client)
+	c.opt = *opt
+	if c.opt.HTTPClient == nil {
+		c.opt.HTTPClient = http.DefaultClient
+	}
+	if c.opt.RetryInterval <= 0 {
+		c.opt.RetryInterval = DefaultRetryInterval
+	}
+	if c.opt.BreakerTimeout <= 0 {
+		c.opt.BreakerTimeout = DefaultBreakerTimeout
+	}
+	c.breakers.Init()
+	return c
+}
+
+// traceKey 是 ctx 中日志追踪的 key
+type traceKey struct{}
+
+// WithTraceID 返回带有日志追踪的 ctx ，Client 记录日志时使用
+func WithTraceID(ctx context.Context, trace string) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceID 返回 ctx 中的日志追踪
+func TraceID(ctx context.Context) string {
+	trace, _ := ctx.Value(traceKey{}).(string)
+	return trace
+}
+
+// retryKey 是 ctx 中是否允许重试的 key
+type retryKey struct{}
+
+// WithRetry 返回允许重试的 ctx ，用于重复调用没有副作用的写接口，
+// 比如使用相同参数的 closeRtpServer
+func WithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryKey{}, true)
+}
+
+// canRetry 返回是否可以重试，只读的接口或者使用了 WithRetry
+func canRetry(ctx context.Context, apiPath string) bool {
+	if ok, _ := ctx.Value(retryKey{}).(bool); ok {
+		return true
+	}
+	name := path.Base(apiPath)
+	return strings.HasPrefix(name, "get") || strings.HasPrefix(name, "list") || strings.HasPrefix(name, "is")
+}
+
+// Request 调用接口，和包的 Request 签名一致
+func (c *Client) Request(ctx context.Context, ser Server, apiPath string, query any, data ResponseData) error {
+	return c.do(ctx, ser, apiPath, func(ctx context.Context) error {
+		return c.request(ctx, ser, apiPath, query, data)
+	})
+}
+
+// RequestBody 调用使用 body 提交数据的接口，比如 webrtc ，
+// 和包的 RequestBody 签名一致
+func (c *Client) RequestBody(ctx context.Context, ser Server, apiPath string, query any, contentType string, body []byte, data ResponseData) error {
+	return c.do(ctx, ser, apiPath, func(ctx context.Context) error {
+		return ghttp.Request(ctx, c.opt.HTTPClient, http.MethodPost,
+			ser.BaseURL()+apiPath, ghttp.Query(query, NewRequestQuery(ser)),
+			map[string]string{"Content-Type": contentType},
+			bytes.NewReader(body), onResponse(data))
+	})
+}
+
+// do 使用超时，重试和熔断调用 request
+func (c *Client) do(ctx context.Context, ser Server, apiPath string, request func(ctx context.Context) error) error {
+	// 熔断
+	b := c.breaker(ser)
+	if b != nil && !b.allow(c.opt.BreakerTimeout) {
+		return ErrCircuitOpen
+	}
+	cost := time.Now()
+	interval := c.opt.RetryInterval
+	retry := 0
+	if canRetry(ctx, apiPath) {
+		retry = c.opt.Retry
+	}
+	var err error
+	for i := 0; ; i++ {
+		err = c.try(ctx, request)
+		if err == nil || !isRetryable(err) || i >= retry || ctx.Err() != nil {
+			break
+		}
+		// 等待
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+		interval *= 2
+		if c.opt.MaxRetryInterval > 0 && interval > c.opt.MaxRetryInterval {
+			interval = c.opt.MaxRetryInterval
+		}
+	}
+	// 熔断只统计服务的错误，取消的不算
+	if b != nil {
+		if ctx.Err() != nil {
+			b.cancel()
+		} else {
+			b.done(err == nil || !isRetryable(err), c.opt.BreakerThreshold)
+		}
+	}
+	// 日志
+	if c.opt.Log {
+		trace := TraceID(ctx)
+		if err != nil {
+			log.Errorf(-1, trace, time.Since(cost), "zlm %s%s %v", ser.BaseURL(), apiPath, err)
+		} else {
+			log.Debug(-1, trace, time.Since(cost), "zlm "+ser.BaseURL()+apiPath)
+		}
+	}
+	if c.opt.OnRequest != nil {
+		c.opt.OnRequest(ser, apiPath, time.Since(cost), err)
+	}
+	return err
+}
+
+// try 使用每次调用的超时调用一次
+func (c *Client) try(ctx context.Context, request func(ctx context.Context) error) error {
+	if c.opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opt.Timeout)
+		defer cancel()
+	}
+	return request(ctx)
+}
+
+// request 调用一次
+func (c *Client) request(ctx context.Context, ser Server, apiPath string, query any, data ResponseData) error {
+	q := ghttp.Query(query, NewRequestQuery(ser))
+	if !c.opt.Post {
+		return ghttp.JSONRequest(ctx, c.opt.HTTPClient, http.MethodGet,
+			ser.BaseURL()+apiPath, q, nil, nil, onResponse(data))
+	}
+	// 参数都放在 body
+	body := make(map[string]string)
+	for k := range q {
+		body[k] = q.Get(k)
+	}
+	return ghttp.JSONRequest(ctx, c.opt.HTTPClient, http.MethodPost,
+		ser.BaseURL()+apiPath, nil, nil, body, onResponse(data))
+}
+
+// onResponse 返回解析响应到 data 的函数
+func onResponse(data ResponseData) func(res *http.Response) error {
+	return func(res *http.Response) error {
+		// 响应码
+		if res.StatusCode != http.StatusOK {
+			return ghttp.StatusError(res.StatusCode)
+		}
+		// 解析
+		if err := json.NewDecoder(res.Body).Decode(data); err != nil {
+			return err
+		}
+		// 错误码
+		if data.GetCode() != CodeOK {
+			return fmt.Errorf("code:%d, msg:%s", data.GetCode(), data.GetMsg())
+		}
+		return nil
+	}
+}
+
+// breaker 返回服务的熔断，不熔断返回 nil
+func (c *Client) breaker(ser Server) *breaker {
+	if c.opt.BreakerThreshold <= 0 {
+		return nil
+	}
+	key := ser.BaseURL()
+	b := c.breakers.Get(key)
+	if b == nil {
+		c.breakers.TrySet(key, new(breaker))
+		b = c.breakers.Get(key)
+	}
+	return b
+}
+
+// IsOpen 返回服务是否熔断
+func (c *Client) IsOpen(ser Server) bool {
+	b := c.breakers.Get(ser.BaseURL())
+	if b == nil {
+		return false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.open
+}
+
+// isRetryable 返回是否网络错误或者 5xx
+func isRetryable(err error) bool {
+	var se ghttp.StatusError
+	if errors.As(err, &se) {
+		return se >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// breaker 是一个服务的熔断状态
+type breaker struct {
+	lock sync.Mutex
+	// 连续失败的次数
+	failures int
+	// 是否熔断
+	open bool
+	// 熔断的时间
+	openTime time.Time
+	// 半开时是否已经有请求在尝试
+	trying bool
+}
+
+// allow 返回是否允许请求，熔断超过 timeout 允许一个请求尝试
+func (b *breaker) allow(timeout time.Duration) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.trying || time.Since(b.openTime) < timeout {
+		return false
+	}
+	b.trying = true
+	return true
+}
+
+// cancel 表示请求被取消，不影响状态
+func (b *breaker) cancel() {
+	b.lock.Lock()
+	b.trying = false
+	b.lock.Unlock()
+}
+
+// done 记录请求的结果
+func (b *breaker) done(ok bool, threshold int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.trying = false
+	if ok {
+		b.failures = 0
+		b.open = false
+		return
+	}
+	b.failures++
+	if b.failures >= threshold {
+		b.open = true
+		b.openTime = time.Now()
+	}
+}
@@ -0,0 +1,346 @@
+package cluster
+
+import (
+	"context"
+	"goutil/log"
+	gsync "goutil/sync"
+	"goutil/zlm"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Strategy 是选择节点的策略
+type Strategy int
+
+const (
+	// StrategyReader 选择观看人数最少的节点
+	StrategyReader Strategy = iota
+	// StrategyBandwidth 选择带宽最小的节点
+	StrategyBandwidth
+	// StrategyHash 使用 app/stream 一致性哈希选择节点，
+	// 节点增减只影响少部分的流
+	StrategyHash
+)
+
+const (
+	// DefaultCheckInterval 是默认的检查间隔
+	DefaultCheckInterval = 10 * time.Second
+	// DefaultCheckTimeout 是默认的检查超时
+	DefaultCheckTimeout = 5 * time.Second
+)
+
+// Option 是 NewCluster 的参数
+type Option struct {
+	// 选择节点的策略
+	Strategy Strategy
+	// 调用 getServerConfig 和 getMediaList 检查节点的间隔，
+	// 默认 DefaultCheckInterval
+	CheckInterval time.Duration
+	// 检查的超时，默认 DefaultCheckTimeout
+	CheckTimeout time.Duration
+	// 大于 0 表示 on_server_keepalive 超过这个时间没有收到，节点就离线，
+	// 需要把 Cluster 设置到 zlm.Hook.ServerKeepalive
+	KeepaliveTimeout time.Duration
+	// 节点上线的回调
+	OnOnline func(n *Node)
+	// 节点离线的回调
+	OnOffline func(n *Node, err error)
+}
+
+// Cluster 管理多个 zlm 节点，定时检查节点的状态和负载，
+// 为新的流选择节点，已经存在的流固定在原来的节点。
+// 实现 zlm.ServerKeepaliveHandler 和 zlm.StreamChangedHandler ，
+// 可以设置到 zlm.Hook 中
+type Cluster struct {
+	opt Option
+	// 节点，key 是服务标识
+	nodes gsync.Map[string, *Node]
+	// 流所在的节点，key 是 app/stream
+	stickyLock sync.Mutex
+	sticky     map[string]*sticky
+	// 用于退出
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// sticky 表示流固定的节点
+type sticky struct {
+	node string
+	time time.Time
+}
+
+// NewCluster 返回新的 Cluster ，启动协程检查节点
+func NewCluster(opt *Option) *Cluster {
+	c := new(Cluster)
+	c.opt = *opt
+	if c.opt.CheckInterval <= 0 {
+		c.opt.CheckInterval = DefaultCheckInterval
+	}
+	if c.opt.CheckTimeout <= 0 {
+		c.opt.CheckTimeout = DefaultCheckTimeout
+	}
+	c.nodes.Init()
+	c.sticky = make(map[string]*sticky)
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	go c.checkRoutine()
+	return c
+}
+
+// Close 停止检查
+func (c *Cluster) Close() {
+	c.cancel()
+}
+
+// Add 添加节点，已经存在则替换，节点在检查通过之后才上线
+func (c *Cluster) Add(opt *NodeOption) *Node {
+	n := new(Node)
+	n.opt = *opt
+	if n.opt.VHost == "" {
+		n.opt.VHost = zlm.VHost
+	}
+	n.err = zlm.ErrServerNotAvailable
+	c.nodes.Set(n.opt.ID, n)
+	go c.checkNode(n)
+	return n
+}
+
+// Del 删除节点，固定在节点上的流也会删除
+func (c *Cluster) Del(id string) {
+	if c.nodes.Take(id) == nil {
+		return
+	}
+	c.stickyLock.Lock()
+	for k, s := range c.sticky {
+		if s.node == id {
+			delete(c.sticky, k)
+		}
+	}
+	c.stickyLock.Unlock()
+}
+
+// Get 返回节点，不存在或者离线返回 zlm.ErrServerNotAvailable
+func (c *Cluster) Get(id string) (*Node, error) {
+	n := c.nodes.Get(id)
+	if n == nil || !n.IsOnline() {
+		return nil, zlm.ErrServerNotAvailable
+	}
+	return n, nil
+}
+
+// Nodes 返回所有的节点
+func (c *Cluster) Nodes() []*Node {
+	return c.nodes.Values()
+}
+
+// Lookup 返回流所在的在线节点，没有返回 nil
+func (c *Cluster) Lookup(app, stream string) *Node {
+	c.stickyLock.Lock()
+	defer c.stickyLock.Unlock()
+	return c.lookup(app + "/" + stream)
+}
+
+// Pick 为流选择节点，流已经存在则返回所在的节点，
+// 没有在线的节点返回 zlm.ErrServerNotAvailable
+func (c *Cluster) Pick(app, stream string) (*Node, error) {
+	key := app + "/" + stream
+	c.stickyLock.Lock()
+	defer c.stickyLock.Unlock()
+	// 固定
+	if n := c.lookup(key); n != nil {
+		return n, nil
+	}
+	// 选择
+	n := c.pick(key)
+	if n == nil {
+		return nil, zlm.ErrServerNotAvailable
+	}
+	c.sticky[key] = &sticky{node: n.opt.ID, time: time.Now()}
+	return n, nil
+}
+
+// lookup 返回 key 固定的在线节点，需要在锁中调用
+func (c *Cluster) lookup(key string) *Node {
+	s := c.sticky[key]
+	if s == nil {
+		return nil
+	}
+	n := c.nodes.Get(s.node)
+	if n == nil || !n.IsOnline() {
+		return nil
+	}
+	return n
+}
+
+// pick 根据策略选择在线的节点
+func (c *Cluster) pick(key string) *Node {
+	var node *Node
+	var min int64
+	var max uint64
+	for _, n := range c.nodes.Search(func(n *Node) bool { return n.IsOnline() }) {
+		switch c.opt.Strategy {
+		case StrategyHash:
+			// rendezvous hashing ，选择哈希值最大的
+			h := fnv.New64a()
+			h.Write([]byte(n.opt.ID))
+			h.Write([]byte(key))
+			v := h.Sum64()
+			if node == nil || v > max {
+				node, max = n, v
+			}
+		default:
+			var v int64
+			n.lock.RLock()
+			if c.opt.Strategy == StrategyBandwidth {
+				v = n.bandwidth
+			} else {
+				v = n.readers
+			}
+			n.lock.RUnlock()
+			if node == nil || v < min {
+				node, min = n, v
+			}
+		}
+	}
+	return node
+}
+
+// OnServerKeepalive 实现 zlm.ServerKeepaliveHandler ，记录节点的心跳，
+// 离线的节点会立即检查
+func (c *Cluster) OnServerKeepalive(ctx context.Context, req *zlm.OnServerKeepaliveReq, res *zlm.CodeMsg) {
+	n := c.nodes.Get(req.MediaServerID)
+	if n == nil {
+		return
+	}
+	n.lock.Lock()
+	n.keepalive = time.Now()
+	online := n.online
+	n.lock.Unlock()
+	if !online {
+		go c.checkNode(n)
+	}
+}
+
+// OnStreamChanged 实现 zlm.StreamChangedHandler ，
+// 注册的流固定在节点上，注销则删除
+func (c *Cluster) OnStreamChanged(ctx context.Context, req *zlm.OnStreamChangedReq, res *zlm.CodeMsg) {
+	if !c.nodes.Has(req.MediaServerID) {
+		return
+	}
+	key := req.App + "/" + req.Stream
+	c.stickyLock.Lock()
+	defer c.stickyLock.Unlock()
+	if req.Regist {
+		c.sticky[key] = &sticky{node: req.MediaServerID, time: time.Now()}
+		return
+	}
+	if s := c.sticky[key]; s != nil && s.node == req.MediaServerID {
+		delete(c.sticky, key)
+	}
+}
+
+// checkRoutine 在协程中定时检查所有的节点
+func (c *Cluster) checkRoutine() {
+	defer func() {
+		log.Recover(recover())
+	}()
+	ticker := time.NewTicker(c.opt.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var wg sync.WaitGroup
+		for _, n := range c.nodes.Values() {
+			wg.Add(1)
+			go func(n *Node) {
+				defer wg.Done()
+				c.checkNode(n)
+			}(n)
+		}
+		wg.Wait()
+	}
+}
+
+// serverConfig 用于检查节点的配置
+type serverConfig struct {
+	MediaServerID string `json:"general.mediaServerId"`
+}
+
+func (m *serverConfig) ServerID() string {
+	return m.MediaServerID
+}
+
+// checkNode 检查节点，更新状态和负载
+func (c *Cluster) checkNode(n *Node) {
+	defer func() {
+		log.Recover(recover())
+	}()
+	ctx, cancel := context.WithTimeout(c.ctx, c.opt.CheckTimeout)
+	defer cancel()
+	// 配置
+	var cfg zlm.GetServerConfigRes[*serverConfig]
+	err := zlm.GetServerConfig(ctx, n, &zlm.GetServerConfigReq{ID: n.opt.ID}, &cfg)
+	if err == nil && cfg.Data == nil {
+		err = zlm.ErrConfig
+	}
+	if err != nil {
+		c.offline(n, err)
+		return
+	}
+	// 心跳
+	if c.opt.KeepaliveTimeout > 0 && n.isKeepaliveTimeout(time.Now(), c.opt.KeepaliveTimeout) {
+		c.offline(n, zlm.ErrServerNotAvailable)
+		return
+	}
+	// 负载
+	var list zlm.GetMediaListRes
+	if err := zlm.GetMediaList(ctx, n, &zlm.GetMediaListReq{}, &list); err != nil {
+		c.offline(n, err)
+		return
+	}
+	n.setLoad(list.Data)
+	c.syncSticky(n, list.Data)
+	//
+	if n.setOnline() && c.opt.OnOnline != nil {
+		c.opt.OnOnline(n)
+	}
+}
+
+// offline 设置节点离线
+func (c *Cluster) offline(n *Node, err error) {
+	if n.setOffline(err) {
+		log.Errorf(-1, "", 0, "zlm cluster %s offline %v", n.opt.ID, err)
+		if c.opt.OnOffline != nil {
+			c.opt.OnOffline(n, err)
+		}
+	}
+}
+
+// syncSticky 使用节点的流列表同步固定的流，
+// 刚选择还没有注册的流保留一个检查间隔
+func (c *Cluster) syncSticky(n *Node, list []*zlm.MediaListData) {
+	keys := make(map[string]struct{})
+	for _, d := range list {
+		keys[d.App+"/"+d.Stream] = struct{}{}
+	}
+	now := time.Now()
+	c.stickyLock.Lock()
+	defer c.stickyLock.Unlock()
+	for k, s := range c.sticky {
+		if s.node != n.opt.ID {
+			continue
+		}
+		if _, ok := keys[k]; !ok && now.Sub(s.time) > c.opt.CheckInterval {
+			delete(c.sticky, k)
+		}
+	}
+	for k := range keys {
+		s := c.sticky[k]
+		if s == nil || s.node != n.opt.ID {
+			c.sticky[k] = &sticky{node: n.opt.ID, time: now}
+		}
+	}
+}
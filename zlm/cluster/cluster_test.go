@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"goutil/zlm"
+	"goutil/zlm/zlmtest"
+	"testing"
+	"time"
+)
+
+// waitFor 等待 f 返回 true ，超时失败
+func waitFor(t *testing.T, msg string, f func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_Pick_Failover(t *testing.T) {
+	sers := map[string]*zlmtest.Server{
+		"a": zlmtest.NewServer(&zlmtest.Option{ID: "a"}),
+		"b": zlmtest.NewServer(&zlmtest.Option{ID: "b"}),
+	}
+	for _, ser := range sers {
+		defer ser.Close()
+	}
+	offline := make(chan string, 2)
+	c := NewCluster(&Option{
+		CheckInterval: 50 * time.Millisecond,
+		OnOffline: func(n *Node, err error) {
+			offline <- n.ID()
+		},
+	})
+	defer c.Close()
+	for id, ser := range sers {
+		c.Add(&NodeOption{ID: id, BaseURL: ser.BaseURL(), Secret: ser.Secret()})
+	}
+	waitFor(t, "nodes not online", func() bool {
+		for id := range sers {
+			if _, err := c.Get(id); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+	// 固定
+	n1, err := c.Pick("live", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := c.Pick("live", "a"); n != n1 {
+		t.Fatalf("pick again got %s, want %s", n.ID(), n1.ID())
+	}
+	// 离线
+	sers[n1.ID()].Close()
+	select {
+	case id := <-offline:
+		if id != n1.ID() {
+			t.Fatalf("offline %s, want %s", id, n1.ID())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("node not offline")
+	}
+	if c.Lookup("live", "a") != nil {
+		t.Fatal("lookup should skip the offline node")
+	}
+	n2, err := c.Pick("live", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n2 == n1 || !n2.IsOnline() {
+		t.Fatalf("pick got %s, want the online node", n2.ID())
+	}
+	if n := c.Lookup("live", "a"); n != n2 {
+		t.Fatal("stream should stick to the new node")
+	}
+	// 全部离线
+	sers[n2.ID()].Close()
+	waitFor(t, "all nodes not offline", func() bool {
+		return !n2.IsOnline()
+	})
+	if _, err := c.Pick("live", "b"); err != zlm.ErrServerNotAvailable {
+		t.Fatalf("pick got %v, want %v", err, zlm.ErrServerNotAvailable)
+	}
+}
@@ -0,0 +1,163 @@
+package cluster
+
+import (
+	"goutil/zlm"
+	"sync"
+	"time"
+)
+
+// NodeOption 是 Cluster.Add 的参数
+type NodeOption struct {
+	// 服务标识，和 zlm 配置的 general.mediaServerId 一致
+	ID string
+	// http://localhost:8080
+	BaseURL string
+	// 访问密钥
+	Secret string
+	// 虚拟主机，默认 zlm.VHost
+	VHost string
+	// 媒体地址，用于 sdp 之类
+	IP string
+}
+
+// Node 表示集群中的一个 zlm 节点，实现 zlm.Server
+type Node struct {
+	opt NodeOption
+	// 状态
+	lock      sync.RWMutex
+	online    bool
+	err       error
+	keepalive time.Time
+	checkTime time.Time
+	// 负载
+	streams   int
+	readers   int64
+	bandwidth int64
+}
+
+// NodeState 是节点的状态
+type NodeState struct {
+	// 服务标识
+	ID string `json:"id"`
+	// 是否在线
+	Online bool `json:"online"`
+	// 离线的原因
+	Error string `json:"error,omitempty"`
+	// 最后一次 on_server_keepalive 的时间
+	Keepalive time.Time `json:"keepalive"`
+	// 最后一次检查的时间
+	CheckTime time.Time `json:"checkTime"`
+	// 流的数量
+	Streams int `json:"streams"`
+	// 观看总人数
+	Readers int64 `json:"readers"`
+	// 带宽，单位 byte/s
+	Bandwidth int64 `json:"bandwidth"`
+}
+
+// ID 返回服务标识
+func (n *Node) ID() string {
+	return n.opt.ID
+}
+
+// BaseURL 实现 zlm.Server
+func (n *Node) BaseURL() string {
+	return n.opt.BaseURL
+}
+
+// Secret 实现 zlm.Server
+func (n *Node) Secret() string {
+	return n.opt.Secret
+}
+
+// VHost 实现 zlm.Server
+func (n *Node) VHost() string {
+	return n.opt.VHost
+}
+
+// GetIP 返回媒体地址
+func (n *Node) GetIP() string {
+	return n.opt.IP
+}
+
+// IsOnline 返回是否在线
+func (n *Node) IsOnline() bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	return n.online
+}
+
+// Err 返回离线的原因，在线返回 nil
+func (n *Node) Err() error {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	if n.online {
+		return nil
+	}
+	return n.err
+}
+
+// State 返回状态
+func (n *Node) State() *NodeState {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	s := &NodeState{
+		ID:        n.opt.ID,
+		Online:    n.online,
+		Keepalive: n.keepalive,
+		CheckTime: n.checkTime,
+		Streams:   n.streams,
+		Readers:   n.readers,
+		Bandwidth: n.bandwidth,
+	}
+	if !n.online && n.err != nil {
+		s.Error = n.err.Error()
+	}
+	return s
+}
+
+// setOnline 设置在线，返回是否从离线变成在线
+func (n *Node) setOnline() bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	ok := !n.online
+	n.online = true
+	n.err = nil
+	return ok
+}
+
+// setOffline 设置离线，返回是否从在线变成离线
+func (n *Node) setOffline(err error) bool {
+	if err == nil {
+		err = zlm.ErrServerNotAvailable
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	ok := n.online
+	n.online = false
+	n.err = err
+	return ok
+}
+
+// setLoad 设置负载
+func (n *Node) setLoad(list []*zlm.MediaListData) {
+	var readers, bandwidth int64
+	for _, d := range list {
+		readers += d.TotalReaderCount
+		bandwidth += d.BytesSpeed
+	}
+	n.lock.Lock()
+	n.streams = len(list)
+	n.readers = readers
+	n.bandwidth = bandwidth
+	n.checkTime = time.Now()
+	n.lock.Unlock()
+}
+
+// isKeepaliveTimeout 返回 on_server_keepalive 是否超时，
+// 没有收到过回调的不算超时
+func (n *Node) isKeepaliveTimeout(now time.Time, timeout time.Duration) bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+	return !n.keepalive.IsZero() && now.Sub(n.keepalive) > timeout
+}
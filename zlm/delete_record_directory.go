@@ -11,6 +11,8 @@ type DeleteRecordDirectoryReq struct {
 	// 流的录像日期，格式为2020-01-01
 	// 如果不是完整的日期，那么会删除失败
 	Period string `query:"period"`
+	// 录像文件名，新版本支持，为空删除整个目录
+	Name string `query:"name"`
 }
 
 // DeleteRecordDirectoryRes 是 DeleteRecordDirectory 返回值
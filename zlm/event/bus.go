@@ -0,0 +1,314 @@
+package event
+
+import (
+	"context"
+	"goutil/log"
+	gsync "goutil/sync"
+	"goutil/zlm"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultChanSize 是订阅通道的默认缓存
+	DefaultChanSize = 16
+	// DefaultWaitPollInterval 是 WaitStream 轮询的默认间隔
+	DefaultWaitPollInterval = time.Second
+)
+
+// Option 是 NewBus 的参数
+type Option struct {
+	// 用于轮询 GetMediaList ，nil 则不轮询
+	Server zlm.Server
+	// 大于 0 表示定时轮询 GetMediaList ，对比出注册和注销的事件，
+	// 用于回调配置错误的服务，只适用于所有的流都在 Server 上
+	PollInterval time.Duration
+	// WaitStream 轮询的间隔，默认 DefaultWaitPollInterval
+	WaitPollInterval time.Duration
+	// 订阅通道的缓存，满了就丢弃，默认 DefaultChanSize
+	ChanSize int
+}
+
+// Bus 是流的事件总线，数据来自 zlm 的回调和轮询，
+// 支持按条件订阅和等待流注册
+type Bus struct {
+	opt Option
+	// 订阅
+	subLock sync.Mutex
+	subID   int64
+	subs    map[int64]*Subscription
+	// 已经注册的流，key 是 schema/app/stream
+	streams gsync.Map[string, *Event]
+	// 用于退出
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Subscription 表示一个订阅
+type Subscription struct {
+	bus    *Bus
+	id     int64
+	filter Filter
+	c      *gsync.Chan[*Event]
+}
+
+// C 返回事件的通道，取消订阅后关闭
+func (s *Subscription) C() <-chan *Event {
+	return s.c.C
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.bus.subLock.Lock()
+	delete(s.bus.subs, s.id)
+	s.bus.subLock.Unlock()
+	s.c.Close()
+}
+
+// NewBus 返回新的 Bus
+func NewBus(opt *Option) *Bus {
+	b := new(Bus)
+	b.opt = *opt
+	if b.opt.WaitPollInterval <= 0 {
+		b.opt.WaitPollInterval = DefaultWaitPollInterval
+	}
+	if b.opt.ChanSize <= 0 {
+		b.opt.ChanSize = DefaultChanSize
+	}
+	b.subs = make(map[int64]*Subscription)
+	b.streams.Init()
+	b.ctx, b.cancel = context.WithCancel(context.Background())
+	if b.opt.Server != nil && b.opt.PollInterval > 0 {
+		go b.pollRoutine()
+	}
+	return b
+}
+
+// Close 停止轮询并关闭所有的订阅
+func (b *Bus) Close() {
+	b.cancel()
+	b.subLock.Lock()
+	subs := b.subs
+	b.subs = make(map[int64]*Subscription)
+	b.subLock.Unlock()
+	for _, s := range subs {
+		s.c.Close()
+	}
+}
+
+// Subscribe 订阅满足 f 的事件
+func (b *Bus) Subscribe(f *Filter) *Subscription {
+	s := new(Subscription)
+	s.bus = b
+	s.filter = *f
+	s.c = gsync.NewChan[*Event](b.opt.ChanSize)
+	b.subLock.Lock()
+	b.subID++
+	s.id = b.subID
+	b.subs[s.id] = s
+	b.subLock.Unlock()
+	return s
+}
+
+// Get 返回已经注册的流，schema 为空表示任意协议，没有返回 nil
+func (b *Bus) Get(schema, app, stream string) *Event {
+	if schema != "" {
+		return b.streams.Get(streamKey(schema, app, stream))
+	}
+	return b.streams.SearchFirst(func(e *Event) bool {
+		return e.App == app && e.Stream == stream
+	})
+}
+
+// WaitStream 使用 Option.Server 等待流注册，见 WaitStreamOn
+func (b *Bus) WaitStream(ctx context.Context, app, stream, schema string) (*Event, error) {
+	return b.WaitStreamOn(ctx, b.opt.Server, app, stream, schema)
+}
+
+// WaitStreamOn 等待流注册，已经注册直接返回，schema 为空表示任意协议，
+// ser 不为 nil 则同时轮询 GetMediaList ，防止回调没有到达
+func (b *Bus) WaitStreamOn(ctx context.Context, ser zlm.Server, app, stream, schema string) (*Event, error) {
+	// 先订阅，防止错过，只订阅这个流，其他流的事件不会占满通道
+	sub := b.Subscribe(&Filter{Schema: schema, App: app, Stream: stream, Types: []Type{TypeRegist}})
+	defer sub.Close()
+	if e := b.Get(schema, app, stream); e != nil {
+		return e, nil
+	}
+	// 轮询
+	var c <-chan time.Time
+	if ser != nil {
+		ticker := time.NewTicker(b.opt.WaitPollInterval)
+		defer ticker.Stop()
+		c = ticker.C
+		if e := b.poll(ctx, ser, app, stream, schema); e != nil {
+			return e, nil
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case e, ok := <-sub.C():
+			if !ok {
+				return nil, zlm.ErrServerNotAvailable
+			}
+			return e, nil
+		case <-c:
+			if e := b.poll(ctx, ser, app, stream, schema); e != nil {
+				return e, nil
+			}
+		}
+	}
+}
+
+// poll 查询流，存在则发布注册事件并返回
+func (b *Bus) poll(ctx context.Context, ser zlm.Server, app, stream, schema string) *Event {
+	var res zlm.GetMediaListRes
+	err := zlm.GetMediaList(ctx, ser, &zlm.GetMediaListReq{
+		Schema: schema,
+		App:    app,
+		Stream: stream,
+	}, &res)
+	if err != nil || len(res.Data) < 1 {
+		return nil
+	}
+	var e *Event
+	for _, d := range res.Data {
+		ee := newRegist("", d, true)
+		if b.streams.TrySet(ee.key(), ee) {
+			b.publish(ee)
+		}
+		if e == nil {
+			e = ee
+		}
+	}
+	return e
+}
+
+// publish 发布事件
+func (b *Bus) publish(e *Event) {
+	b.subLock.Lock()
+	defer b.subLock.Unlock()
+	for _, s := range b.subs {
+		if s.filter.match(e) {
+			s.c.Send(e)
+		}
+	}
+}
+
+// newRegist 返回注册事件
+func newRegist(serverID string, d *zlm.MediaListData, polled bool) *Event {
+	return &Event{
+		Type:     TypeRegist,
+		ServerID: serverID,
+		Schema:   d.Schema,
+		App:      d.App,
+		Stream:   d.Stream,
+		Media:    d,
+		Polled:   polled,
+		Time:     time.Now(),
+	}
+}
+
+// HandleStreamChanged 处理 zlm 的 on_stream_changed ，发布注册和注销事件
+func (b *Bus) HandleStreamChanged(ctx context.Context, req *zlm.OnStreamChangedReq) {
+	if req.Regist {
+		d := req.MediaListData
+		e := newRegist(req.MediaServerID, &d, false)
+		b.streams.Set(e.key(), e)
+		b.publish(e)
+		return
+	}
+	e := &Event{
+		Type:     TypeUnregist,
+		ServerID: req.MediaServerID,
+		Schema:   req.Schema,
+		App:      req.App,
+		Stream:   req.Stream,
+		Time:     time.Now(),
+	}
+	b.streams.Del(e.key())
+	b.publish(e)
+}
+
+// HandleStreamNotFound 处理 zlm 的 on_stream_not_found ，发布事件
+func (b *Bus) HandleStreamNotFound(ctx context.Context, req *zlm.OnStreamNotFoundReq) {
+	b.publish(&Event{
+		Type:     TypeNotFound,
+		ServerID: req.MediaServerID,
+		Schema:   req.Schema,
+		App:      req.App,
+		Stream:   req.Stream,
+		Time:     time.Now(),
+	})
+}
+
+// HandleStreamNoneReader 处理 zlm 的 on_stream_none_reader ，发布事件，
+// 是否关闭流由调用方决定
+func (b *Bus) HandleStreamNoneReader(ctx context.Context, req *zlm.OnStreamNoneReaderReq) {
+	b.publish(&Event{
+		Type:     TypeNoneReader,
+		ServerID: req.MediaServerID,
+		Schema:   req.Schema,
+		App:      req.App,
+		Stream:   req.Stream,
+		Time:     time.Now(),
+	})
+}
+
+// pollRoutine 在协程中定时轮询，对比出注册和注销的流
+func (b *Bus) pollRoutine() {
+	defer func() {
+		log.Recover(recover())
+	}()
+	ticker := time.NewTicker(b.opt.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		b.pollAll()
+	}
+}
+
+// pollAll 查询所有的流，发布变化的事件
+func (b *Bus) pollAll() {
+	ctx, cancel := context.WithTimeout(b.ctx, b.opt.PollInterval)
+	defer cancel()
+	var res zlm.GetMediaListRes
+	if err := zlm.GetMediaList(ctx, b.opt.Server, &zlm.GetMediaListReq{}, &res); err != nil {
+		log.Errorf(-1, "", 0, "zlm event poll %v", err)
+		return
+	}
+	// 注册
+	keys := make(map[string]struct{})
+	for _, d := range res.Data {
+		e := newRegist("", d, true)
+		k := e.key()
+		keys[k] = struct{}{}
+		if b.streams.TrySet(k, e) {
+			b.publish(e)
+		}
+	}
+	// 注销
+	for _, k := range b.streams.Keys() {
+		if _, ok := keys[k]; ok {
+			continue
+		}
+		e := b.streams.Take(k)
+		if e == nil {
+			continue
+		}
+		b.publish(&Event{
+			Type:     TypeUnregist,
+			ServerID: e.ServerID,
+			Schema:   e.Schema,
+			App:      e.App,
+			Stream:   e.Stream,
+			Polled:   true,
+			Time:     time.Now(),
+		})
+	}
+}
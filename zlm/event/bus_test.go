@@ -0,0 +1,88 @@
+package event
+
+import (
+	"context"
+	"goutil/zlm"
+	"goutil/zlm/zlmtest"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_WaitStream_Hook(t *testing.T) {
+	b := NewBus(&Option{})
+	defer b.Close()
+	hook := httptest.NewServer(&zlm.Hook{
+		StreamChanged: zlm.StreamChangedHandlerFunc(func(ctx context.Context, req *zlm.OnStreamChangedReq, res *zlm.CodeMsg) {
+			b.HandleStreamChanged(ctx, req)
+		}),
+	})
+	defer hook.Close()
+	ser := zlmtest.NewServer(&zlmtest.Option{HookURL: hook.URL, Schemas: []string{zlm.RTMP}})
+	defer ser.Close()
+	// 等待
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ser.AddStream("live", "a")
+	}()
+	e, err := b.WaitStream(ctx, "live", "a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != TypeRegist || e.Polled || e.ServerID != ser.ID() || e.Schema != zlm.RTMP {
+		t.Fatalf("event %+v", e)
+	}
+	// 已经注册
+	if e, err := b.WaitStream(ctx, "live", "a", zlm.RTMP); err != nil || e == nil {
+		t.Fatalf("wait registered %v %v", e, err)
+	}
+	// 注销
+	sub := b.Subscribe(&Filter{Pattern: "live/*", Types: []Type{TypeUnregist}})
+	defer sub.Close()
+	ser.RemoveStream("live", "a")
+	select {
+	case e := <-sub.C():
+		if e.App != "live" || e.Stream != "a" {
+			t.Fatalf("event %+v", e)
+		}
+	case <-ctx.Done():
+		t.Fatal("unregist event not received")
+	}
+	if b.Get("", "live", "a") != nil {
+		t.Fatal("stream should be removed")
+	}
+}
+
+func Test_WaitStream_Poll(t *testing.T) {
+	// 没有回调
+	ser := zlmtest.NewServer(&zlmtest.Option{Schemas: []string{zlm.RTMP}})
+	defer ser.Close()
+	b := NewBus(&Option{Server: ser, WaitPollInterval: 20 * time.Millisecond})
+	defer b.Close()
+	// 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	_, err := b.WaitStream(ctx, "live", "a", "")
+	cancel()
+	if err != context.DeadlineExceeded {
+		t.Fatalf("wait got %v, want %v", err, context.DeadlineExceeded)
+	}
+	// 等待
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ser.AddStream("live", "a")
+	}()
+	e, err := b.WaitStream(ctx, "live", "a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != TypeRegist || !e.Polled || e.Media == nil || e.Media.Stream != "a" {
+		t.Fatalf("event %+v", e)
+	}
+	if b.Get(zlm.RTMP, "live", "a") == nil {
+		t.Fatal("polled stream should be saved")
+	}
+}
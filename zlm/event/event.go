@@ -0,0 +1,106 @@
+package event
+
+import (
+	"goutil/zlm"
+	"path"
+	"time"
+)
+
+// Type 是事件的类型
+type Type int
+
+const (
+	// TypeRegist 流注册
+	TypeRegist Type = iota + 1
+	// TypeUnregist 流注销
+	TypeUnregist
+	// TypeNotFound 播放的流不存在
+	TypeNotFound
+	// TypeNoneReader 流没有观看者
+	TypeNoneReader
+)
+
+var typeNames = map[Type]string{
+	TypeRegist:     "regist",
+	TypeUnregist:   "unregist",
+	TypeNotFound:   "not_found",
+	TypeNoneReader: "none_reader",
+}
+
+func (t Type) String() string {
+	return typeNames[t]
+}
+
+// Event 表示一个流的事件
+type Event struct {
+	// 类型
+	Type Type
+	// 服务标识
+	ServerID string
+	// 协议
+	Schema string
+	// 流应用
+	App string
+	// 流标识
+	Stream string
+	// 注册时的媒体信息，其他类型是 nil
+	Media *zlm.MediaListData
+	// 是否来自轮询，而不是回调
+	Polled bool
+	// 发生的时间
+	Time time.Time
+}
+
+// key 返回流的标识
+func (e *Event) key() string {
+	return streamKey(e.Schema, e.App, e.Stream)
+}
+
+// streamKey 返回流的标识
+func streamKey(schema, app, stream string) string {
+	return schema + "/" + app + "/" + stream
+}
+
+// Filter 是订阅的条件
+type Filter struct {
+	// 匹配 app/stream ，path.Match 的格式，比如 rtp/* ，空表示全部
+	Pattern string
+	// 精确匹配的流应用，空表示全部
+	App string
+	// 精确匹配的流标识，空表示全部
+	Stream string
+	// 协议，空表示全部
+	Schema string
+	// 类型，空表示全部
+	Types []Type
+}
+
+// match 返回 e 是否满足条件
+func (f *Filter) match(e *Event) bool {
+	if f.Schema != "" && f.Schema != e.Schema {
+		return false
+	}
+	if f.App != "" && f.App != e.App {
+		return false
+	}
+	if f.Stream != "" && f.Stream != e.Stream {
+		return false
+	}
+	if len(f.Types) > 0 {
+		ok := false
+		for _, t := range f.Types {
+			if t == e.Type {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.Pattern != "" {
+		ok, _ := path.Match(f.Pattern, e.App+"/"+e.Stream)
+		return ok
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package zlm
+
+import (
+	"context"
+)
+
+// GetAllSessionReq 是 GetAllSession 的参数
+type GetAllSessionReq struct {
+	// 筛选本机端口，例如筛选 rtsp 链接：554
+	LocalPort string `query:"local_port"`
+	// 筛选客户端 ip
+	PeerIP string `query:"peer_ip"`
+}
+
+// SessionData 是 GetAllSession 返回的连接信息
+type SessionData struct {
+	// 连接标识，用于 KickSession
+	ID string `json:"id"`
+	// 本机地址
+	LocalIP   string `json:"local_ip"`
+	LocalPort int    `json:"local_port"`
+	// 客户端地址
+	PeerIP   string `json:"peer_ip"`
+	PeerPort int    `json:"peer_port"`
+	// 客户端 TCPSession typeid
+	TypeID string `json:"typeid"`
+}
+
+// GetAllSessionRes 是 GetAllSession 的返回值
+type GetAllSessionRes struct {
+	CodeMsg
+	Data []*SessionData `json:"data"`
+}
+
+const (
+	GetAllSessionPath = apiPathPrefix + "/getAllSession"
+)
+
+// GetAllSession 调用 /index/api/getAllSession ，返回所有的 tcp 连接
+func GetAllSession(ctx context.Context, ser Server, req *GetAllSessionReq, res *GetAllSessionRes) error {
+	return Request(ctx, ser, GetAllSessionPath, req, res)
+}
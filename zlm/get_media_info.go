@@ -0,0 +1,45 @@
+package zlm
+
+import (
+	"context"
+)
+
+// GetMediaInfoReq 是 GetMediaInfo 的参数
+type GetMediaInfoReq struct {
+	// 协议
+	Schema string `query:"schema"`
+	// 流应用
+	App string `query:"app"`
+	// 流标识
+	Stream string `query:"stream"`
+}
+
+// GetMediaInfoRes 是 GetMediaInfo 的返回值，
+// 新版本的数据和 code 在同一层
+type GetMediaInfoRes struct {
+	CodeMsg
+	// 是否在线
+	Online bool `json:"online"`
+	// 媒体信息
+	MediaListData
+	// 存活时间，单位秒
+	AliveSecond int64 `json:"aliveSecond"`
+	// 是否为 rtp 收流
+	IsRTP bool `json:"isRtp"`
+}
+
+const (
+	GetMediaInfoPath = apiPathPrefix + "/getMediaInfo"
+)
+
+// GetMediaInfo 调用 /index/api/getMediaInfo ，返回流的信息，
+// 流不存在返回 ErrMediaNotFound
+func GetMediaInfo(ctx context.Context, ser Server, req *GetMediaInfoReq, res *GetMediaInfoRes) error {
+	if err := Request(ctx, ser, GetMediaInfoPath, req, res); err != nil {
+		return err
+	}
+	if !res.Online {
+		return ErrMediaNotFound
+	}
+	return nil
+}
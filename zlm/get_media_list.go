@@ -50,6 +50,8 @@ type MediaListData struct {
 	TotalReaderCount int64  `json:"totalReaderCount"`
 	OriginType       int64  `json:"originType"`
 	OriginURL        string `json:"originUrl"`
+	// 数据产生速度，单位 byte/s
+	BytesSpeed int64 `json:"bytesSpeed"`
 }
 
 // ParseTrack 区分出音/视频轨道
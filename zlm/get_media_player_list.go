@@ -0,0 +1,44 @@
+package zlm
+
+import (
+	"context"
+)
+
+// GetMediaPlayerListReq 是 GetMediaPlayerList 的参数
+type GetMediaPlayerListReq struct {
+	// 协议
+	Schema string `query:"schema"`
+	// 流应用
+	App string `query:"app"`
+	// 流标识
+	Stream string `query:"stream"`
+}
+
+// MediaPlayerData 是 GetMediaPlayerList 返回的播放者信息
+type MediaPlayerData struct {
+	// 连接标识，用于 KickSession
+	Identifier string `json:"identifier"`
+	// 本机地址
+	LocalIP   string `json:"local_ip"`
+	LocalPort int    `json:"local_port"`
+	// 客户端地址
+	PeerIP   string `json:"peer_ip"`
+	PeerPort int    `json:"peer_port"`
+	// 客户端 TCPSession typeid
+	TypeID string `json:"typeid"`
+}
+
+// GetMediaPlayerListRes 是 GetMediaPlayerList 的返回值
+type GetMediaPlayerListRes struct {
+	CodeMsg
+	Data []*MediaPlayerData `json:"data"`
+}
+
+const (
+	GetMediaPlayerListPath = apiPathPrefix + "/getMediaPlayerList"
+)
+
+// GetMediaPlayerList 调用 /index/api/getMediaPlayerList ，返回流的播放者
+func GetMediaPlayerList(ctx context.Context, ser Server, req *GetMediaPlayerListReq, res *GetMediaPlayerListRes) error {
+	return Request(ctx, ser, GetMediaPlayerListPath, req, res)
+}
@@ -0,0 +1,46 @@
+package zlm
+
+import (
+	"context"
+)
+
+// GetProxyInfoReq 是 GetProxyInfo 的参数
+type GetProxyInfoReq struct {
+	// AddStreamProxy 返回的 key
+	Key string `query:"key"`
+}
+
+// ProxyInfoData 是 GetProxyInfo 返回的拉流代理信息
+type ProxyInfoData struct {
+	// 拉流的源地址
+	URL string `json:"url"`
+	// 拉流状态，0 表示正常
+	Status int `json:"status"`
+	// 拉流成功的存活时间，单位秒
+	LiveSecs int64 `json:"liveSecs"`
+	// 重新拉流的次数
+	RePullCount int `json:"rePullCount"`
+	// 观看总人数
+	TotalReaderCount int64 `json:"totalReaderCount"`
+	// 流的信息
+	Src struct {
+		VHost  string `json:"vhost"`
+		App    string `json:"app"`
+		Stream string `json:"stream"`
+	} `json:"src"`
+}
+
+// GetProxyInfoRes 是 GetProxyInfo 的返回值
+type GetProxyInfoRes struct {
+	CodeMsg
+	Data ProxyInfoData `json:"data"`
+}
+
+const (
+	GetProxyInfoPath = apiPathPrefix + "/getProxyInfo"
+)
+
+// GetProxyInfo 调用 /index/api/getProxyInfo ，返回拉流代理的信息
+func GetProxyInfo(ctx context.Context, ser Server, req *GetProxyInfoReq, res *GetProxyInfoRes) error {
+	return Request(ctx, ser, GetProxyInfoPath, req, res)
+}
@@ -0,0 +1,36 @@
+package zlm
+
+import (
+	"context"
+)
+
+// GetProxyPusherInfoReq 是 GetProxyPusherInfo 的参数
+type GetProxyPusherInfoReq struct {
+	// AddStreamPusherProxy 返回的 key
+	Key string `query:"key"`
+}
+
+// GetProxyPusherInfoRes 是 GetProxyPusherInfo 的返回值
+type GetProxyPusherInfoRes struct {
+	CodeMsg
+	Data struct {
+		// 推流地址
+		URL string `json:"url"`
+		// 推流状态，0 表示正常
+		Status int `json:"status"`
+		// 推流成功的存活时间，单位秒
+		LiveSecs int64 `json:"liveSecs"`
+		// 重新推流的次数
+		RePublishCount int `json:"rePublishCount"`
+	} `json:"data"`
+}
+
+const (
+	GetProxyPusherInfoPath = apiPathPrefix + "/getProxyPusherInfo"
+)
+
+// GetProxyPusherInfo 调用 /index/api/getProxyPusherInfo ，返回推流代理的信息，
+// 推流代理不存在返回错误
+func GetProxyPusherInfo(ctx context.Context, ser Server, req *GetProxyPusherInfoReq, res *GetProxyPusherInfoRes) error {
+	return Request(ctx, ser, GetProxyPusherInfoPath, req, res)
+}
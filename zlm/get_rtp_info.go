@@ -0,0 +1,33 @@
+package zlm
+
+import (
+	"context"
+)
+
+// GetRTPInfoReq 是 GetRTPInfo 的参数
+type GetRTPInfoReq struct {
+	// rtp 推流的 ssrc ，16 进制
+	StreamID string `query:"stream_id"`
+}
+
+// GetRTPInfoRes 是 GetRTPInfo 的返回值
+type GetRTPInfoRes struct {
+	CodeMsg
+	// 是否存在
+	Exist bool `json:"exist"`
+	// 推流客户端地址
+	PeerIP   string `json:"peer_ip"`
+	PeerPort int    `json:"peer_port"`
+	// 本地监听地址
+	LocalIP   string `json:"local_ip"`
+	LocalPort int    `json:"local_port"`
+}
+
+const (
+	GetRTPInfoPath = apiPathPrefix + "/getRtpInfo"
+)
+
+// GetRTPInfo 调用 /index/api/getRtpInfo ，返回 rtp 推流的信息
+func GetRTPInfo(ctx context.Context, ser Server, req *GetRTPInfoReq, res *GetRTPInfoRes) error {
+	return Request(ctx, ser, GetRTPInfoPath, req, res)
+}
@@ -0,0 +1,25 @@
+package zlm
+
+import (
+	"context"
+)
+
+// GetStatisticReq 是 GetStatistic 的参数
+type GetStatisticReq struct {
+}
+
+// GetStatisticRes 是 GetStatistic 的返回值，
+// 数据和 on_server_keepalive 的一样
+type GetStatisticRes struct {
+	CodeMsg
+	Data OnServerKeepaliveDataModel `json:"data"`
+}
+
+const (
+	GetStatisticPath = apiPathPrefix + "/getStatistic"
+)
+
+// GetStatistic 调用 /index/api/getStatistic ，返回主要对象的个数
+func GetStatistic(ctx context.Context, ser Server, req *GetStatisticReq, res *GetStatisticRes) error {
+	return Request(ctx, ser, GetStatisticPath, req, res)
+}
@@ -0,0 +1,38 @@
+package zlm
+
+import (
+	"context"
+)
+
+// GetThreadsLoadReq 是 GetThreadsLoad 和 GetWorkThreadsLoad 的参数
+type GetThreadsLoadReq struct {
+}
+
+// ThreadLoadData 是线程的负载
+type ThreadLoadData struct {
+	// 负载，0-100
+	Load int `json:"load"`
+	// 延时，单位毫秒
+	Delay int `json:"delay"`
+}
+
+// GetThreadsLoadRes 是 GetThreadsLoad 和 GetWorkThreadsLoad 的返回值
+type GetThreadsLoadRes struct {
+	CodeMsg
+	Data []*ThreadLoadData `json:"data"`
+}
+
+const (
+	GetThreadsLoadPath     = apiPathPrefix + "/getThreadsLoad"
+	GetWorkThreadsLoadPath = apiPathPrefix + "/getWorkThreadsLoad"
+)
+
+// GetThreadsLoad 调用 /index/api/getThreadsLoad ，返回网络线程的负载
+func GetThreadsLoad(ctx context.Context, ser Server, req *GetThreadsLoadReq, res *GetThreadsLoadRes) error {
+	return Request(ctx, ser, GetThreadsLoadPath, req, res)
+}
+
+// GetWorkThreadsLoad 调用 /index/api/getWorkThreadsLoad ，返回后台线程的负载
+func GetWorkThreadsLoad(ctx context.Context, ser Server, req *GetThreadsLoadReq, res *GetThreadsLoadRes) error {
+	return Request(ctx, ser, GetWorkThreadsLoadPath, req, res)
+}
@@ -0,0 +1,277 @@
+package zlm
+
+import (
+	"context"
+	"encoding/json"
+	"goutil/log"
+	"goutil/uid"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 回调的名称，是 url 路径的最后一段
+const (
+	HookOnPlay             = "on_play"
+	HookOnPublish          = "on_publish"
+	HookOnStreamChanged    = "on_stream_changed"
+	HookOnStreamNoneReader = "on_stream_none_reader"
+	HookOnStreamNotFound   = "on_stream_not_found"
+	HookOnRecordMP4        = "on_record_mp4"
+	HookOnServerKeepalive  = "on_server_keepalive"
+	HookOnSendRTPStopped   = "on_send_rtp_stopped"
+)
+
+// 回调的错误码
+const (
+	// CodeDeny 拒绝
+	CodeDeny = -1
+	// CodeBadRequest 数据格式错误
+	CodeBadRequest = -2
+)
+
+// PlayHandler 处理 on_play ，res.Code 不为 0 表示拒绝播放
+type PlayHandler interface {
+	OnPlay(ctx context.Context, req *OnPlayReq, res *CodeMsg)
+}
+
+// PublishHandler 处理 on_publish ，res.Code 不为 0 表示拒绝推流
+type PublishHandler interface {
+	OnPublish(ctx context.Context, req *OnPublishReq, res *OnPublishRes)
+}
+
+// StreamChangedHandler 处理 on_stream_changed
+type StreamChangedHandler interface {
+	OnStreamChanged(ctx context.Context, req *OnStreamChangedReq, res *CodeMsg)
+}
+
+// StreamNoneReaderHandler 处理 on_stream_none_reader ，res.Close 表示是否关闭流
+type StreamNoneReaderHandler interface {
+	OnStreamNoneReader(ctx context.Context, req *OnStreamNoneReaderReq, res *OnStreamNoneReaderRes)
+}
+
+// StreamNotFoundHandler 处理 on_stream_not_found
+type StreamNotFoundHandler interface {
+	OnStreamNotFound(ctx context.Context, req *OnStreamNotFoundReq, res *CodeMsg)
+}
+
+// RecordMP4Handler 处理 on_record_mp4
+type RecordMP4Handler interface {
+	OnRecordMP4(ctx context.Context, req *OnRecordMP4Req, res *CodeMsg)
+}
+
+// ServerKeepaliveHandler 处理 on_server_keepalive
+type ServerKeepaliveHandler interface {
+	OnServerKeepalive(ctx context.Context, req *OnServerKeepaliveReq, res *CodeMsg)
+}
+
+// SendRTPStoppedHandler 处理 on_send_rtp_stopped
+type SendRTPStoppedHandler interface {
+	OnSendRTPStopped(ctx context.Context, req *OnSendRTPStoppedReq, res *CodeMsg)
+}
+
+// 函数适配
+type (
+	PlayHandlerFunc             func(ctx context.Context, req *OnPlayReq, res *CodeMsg)
+	PublishHandlerFunc          func(ctx context.Context, req *OnPublishReq, res *OnPublishRes)
+	StreamChangedHandlerFunc    func(ctx context.Context, req *OnStreamChangedReq, res *CodeMsg)
+	StreamNoneReaderHandlerFunc func(ctx context.Context, req *OnStreamNoneReaderReq, res *OnStreamNoneReaderRes)
+	StreamNotFoundHandlerFunc   func(ctx context.Context, req *OnStreamNotFoundReq, res *CodeMsg)
+	RecordMP4HandlerFunc        func(ctx context.Context, req *OnRecordMP4Req, res *CodeMsg)
+	ServerKeepaliveHandlerFunc  func(ctx context.Context, req *OnServerKeepaliveReq, res *CodeMsg)
+	SendRTPStoppedHandlerFunc   func(ctx context.Context, req *OnSendRTPStoppedReq, res *CodeMsg)
+)
+
+func (f PlayHandlerFunc) OnPlay(ctx context.Context, req *OnPlayReq, res *CodeMsg) {
+	f(ctx, req, res)
+}
+
+func (f PublishHandlerFunc) OnPublish(ctx context.Context, req *OnPublishReq, res *OnPublishRes) {
+	f(ctx, req, res)
+}
+
+func (f StreamChangedHandlerFunc) OnStreamChanged(ctx context.Context, req *OnStreamChangedReq, res *CodeMsg) {
+	f(ctx, req, res)
+}
+
+func (f StreamNoneReaderHandlerFunc) OnStreamNoneReader(ctx context.Context, req *OnStreamNoneReaderReq, res *OnStreamNoneReaderRes) {
+	f(ctx, req, res)
+}
+
+func (f StreamNotFoundHandlerFunc) OnStreamNotFound(ctx context.Context, req *OnStreamNotFoundReq, res *CodeMsg) {
+	f(ctx, req, res)
+}
+
+func (f RecordMP4HandlerFunc) OnRecordMP4(ctx context.Context, req *OnRecordMP4Req, res *CodeMsg) {
+	f(ctx, req, res)
+}
+
+func (f ServerKeepaliveHandlerFunc) OnServerKeepalive(ctx context.Context, req *OnServerKeepaliveReq, res *CodeMsg) {
+	f(ctx, req, res)
+}
+
+func (f SendRTPStoppedHandlerFunc) OnSendRTPStopped(ctx context.Context, req *OnSendRTPStoppedReq, res *CodeMsg) {
+	f(ctx, req, res)
+}
+
+// Hook 是 zlm 回调的 http.Handler ，根据 url 路径的最后一段分发，
+// 没有设置的回调使用包内的默认函数，比如 OnPlay
+type Hook struct {
+	Play             PlayHandler
+	Publish          PublishHandler
+	StreamChanged    StreamChangedHandler
+	StreamNoneReader StreamNoneReaderHandler
+	StreamNotFound   StreamNotFoundHandler
+	RecordMP4        RecordMP4Handler
+	ServerKeepalive  ServerKeepaliveHandler
+	SendRTPStopped   SendRTPStoppedHandler
+}
+
+// ServeHTTP 实现 http.Handler
+func (h *Hook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	cost := time.Now()
+	ctx := r.Context()
+	trace := uid.SnowflakeIDString()
+	name := path.Base(r.URL.Path)
+	var res any
+	switch name {
+	case HookOnPlay:
+		var req OnPlayReq
+		if !decodeHook(w, r, trace, &req) {
+			return
+		}
+		req.TraceID = trace
+		var _res CodeMsg
+		if h.Play != nil {
+			h.Play.OnPlay(ctx, &req, &_res)
+		} else {
+			OnPlay(ctx, &req, &_res)
+		}
+		res = &_res
+	case HookOnPublish:
+		var req OnPublishReq
+		if !decodeHook(w, r, trace, &req) {
+			return
+		}
+		req.TraceID = trace
+		var _res OnPublishRes
+		if h.Publish != nil {
+			h.Publish.OnPublish(ctx, &req, &_res)
+		} else {
+			OnPublish(ctx, &req, &_res)
+		}
+		res = &_res
+	case HookOnStreamChanged:
+		var req OnStreamChangedReq
+		if !decodeHook(w, r, trace, &req) {
+			return
+		}
+		req.TraceID = trace
+		var _res CodeMsg
+		if h.StreamChanged != nil {
+			h.StreamChanged.OnStreamChanged(ctx, &req, &_res)
+		} else {
+			OnStreamChanged(ctx, &req, &_res)
+		}
+		res = &_res
+	case HookOnStreamNoneReader:
+		var req OnStreamNoneReaderReq
+		if !decodeHook(w, r, trace, &req) {
+			return
+		}
+		req.TraceID = trace
+		var _res OnStreamNoneReaderRes
+		if h.StreamNoneReader != nil {
+			h.StreamNoneReader.OnStreamNoneReader(ctx, &req, &_res)
+		} else {
+			OnStreamNoneReader(ctx, &req, &_res)
+		}
+		res = &_res
+	case HookOnStreamNotFound:
+		var req OnStreamNotFoundReq
+		if !decodeHook(w, r, trace, &req) {
+			return
+		}
+		req.TraceID = trace
+		var _res CodeMsg
+		if h.StreamNotFound != nil {
+			h.StreamNotFound.OnStreamNotFound(ctx, &req, &_res)
+		} else {
+			OnStreamNotFound(ctx, &req, &_res)
+		}
+		res = &_res
+	case HookOnRecordMP4:
+		var req OnRecordMP4Req
+		if !decodeHook(w, r, trace, &req) {
+			return
+		}
+		req.TraceID = trace
+		var _res CodeMsg
+		if h.RecordMP4 != nil {
+			h.RecordMP4.OnRecordMP4(ctx, &req, &_res)
+		} else {
+			OnRecordMP4(ctx, &req, &_res)
+		}
+		res = &_res
+	case HookOnServerKeepalive:
+		var req OnServerKeepaliveReq
+		if !decodeHook(w, r, trace, &req) {
+			return
+		}
+		req.TraceID = trace
+		var _res CodeMsg
+		if h.ServerKeepalive != nil {
+			h.ServerKeepalive.OnServerKeepalive(ctx, &req, &_res)
+		} else {
+			OnServerKeepalive(ctx, &req, &_res)
+		}
+		res = &_res
+	case HookOnSendRTPStopped:
+		var req OnSendRTPStoppedReq
+		if !decodeHook(w, r, trace, &req) {
+			return
+		}
+		req.TraceID = trace
+		var _res CodeMsg
+		if h.SendRTPStopped != nil {
+			h.SendRTPStopped.OnSendRTPStopped(ctx, &req, &_res)
+		} else {
+			OnSendRTPStopped(ctx, &req, &_res)
+		}
+		res = &_res
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	writeHook(w, trace, res)
+	log.Debug(-1, trace, time.Since(cost), "zlm hook "+name)
+}
+
+// HandleGin 是 ServeHTTP 的 gin 适配，路由需要使用 /:hook 这样的最后一段
+func (h *Hook) HandleGin(ctx *gin.Context) {
+	h.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// decodeHook 解析回调的数据，失败会响应错误
+func decodeHook(w http.ResponseWriter, r *http.Request, trace string, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		log.Errorf(-1, trace, 0, "zlm hook %s decode %v", r.URL.Path, err)
+		writeHook(w, trace, &CodeMsg{Code: CodeBadRequest, Msg: err.Error()})
+		return false
+	}
+	return true
+}
+
+// writeHook 响应回调
+func writeHook(w http.ResponseWriter, trace string, res any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Errorf(-1, trace, 0, "zlm hook encode %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package zlm
+
+import (
+	"context"
+)
+
+// KickSessionReq 是 KickSession 的参数
+type KickSessionReq struct {
+	// 连接标识，SessionData.ID
+	ID string `query:"id"`
+}
+
+// KickSessionRes 是 KickSession 的返回值
+type KickSessionRes struct {
+	CodeMsg
+}
+
+const (
+	KickSessionPath = apiPathPrefix + "/kick_session"
+)
+
+// KickSession 调用 /index/api/kick_session ，断开 tcp 连接
+func KickSession(ctx context.Context, ser Server, req *KickSessionReq, res *KickSessionRes) error {
+	return Request(ctx, ser, KickSessionPath, req, res)
+}
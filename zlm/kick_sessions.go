@@ -0,0 +1,29 @@
+package zlm
+
+import (
+	"context"
+)
+
+// KickSessionsReq 是 KickSessions 的参数
+type KickSessionsReq struct {
+	// 筛选本机端口，例如筛选 rtsp 链接：554
+	LocalPort string `query:"local_port"`
+	// 筛选客户端 ip
+	PeerIP string `query:"peer_ip"`
+}
+
+// KickSessionsRes 是 KickSessions 的返回值
+type KickSessionsRes struct {
+	CodeMsg
+	// 断开的连接个数
+	CountHit int `json:"count_hit"`
+}
+
+const (
+	KickSessionsPath = apiPathPrefix + "/kick_sessions"
+)
+
+// KickSessions 调用 /index/api/kick_sessions ，批量断开 tcp 连接
+func KickSessions(ctx context.Context, ser Server, req *KickSessionsReq, res *KickSessionsRes) error {
+	return Request(ctx, ser, KickSessionsPath, req, res)
+}
@@ -0,0 +1,32 @@
+package zlm
+
+import (
+	"context"
+)
+
+// ListRTPServerReq 是 ListRTPServer 的参数
+type ListRTPServerReq struct {
+}
+
+// RTPServerData 是 ListRTPServer 返回的 rtp server 信息
+type RTPServerData struct {
+	// 绑定的端口
+	Port int `json:"port"`
+	// 流标识
+	StreamID string `json:"stream_id"`
+}
+
+// ListRTPServerRes 是 ListRTPServer 的返回值
+type ListRTPServerRes struct {
+	CodeMsg
+	Data []*RTPServerData `json:"data"`
+}
+
+const (
+	ListRTPServerPath = apiPathPrefix + "/listRtpServer"
+)
+
+// ListRTPServer 调用 /index/api/listRtpServer ，返回所有的 rtp server
+func ListRTPServer(ctx context.Context, ser Server, req *ListRTPServerReq, res *ListRTPServerRes) error {
+	return Request(ctx, ser, ListRTPServerPath, req, res)
+}
@@ -18,6 +18,11 @@ type OnPlayReq struct {
 	Stream string `json:"stream"`
 	// url 查询字符串
 	Params string `json:"params"`
+	// 客户端的地址
+	IP   string `json:"ip"`
+	Port int    `json:"port"`
+	// 客户端的连接标识
+	ID string `json:"id"`
 	// 自定义上下文数据
 	UserData string `json:"userdata"`
 	// 日志追踪
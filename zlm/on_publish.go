@@ -18,6 +18,11 @@ type OnPublishReq struct {
 	Stream string `json:"stream"`
 	// url 查询字符串
 	Params string `json:"params"`
+	// 客户端的地址
+	IP   string `json:"ip"`
+	Port int    `json:"port"`
+	// 客户端的连接标识
+	ID string `json:"id"`
 	// 自定义上下文数据
 	UserData string `json:"userdata"`
 	// 日志追踪
@@ -28,6 +33,8 @@ type OnPublishReq struct {
 type OnPublishRes struct {
 	// 错误代码
 	Code int `json:"code"`
+	// 错误信息
+	Msg string `json:"msg,omitempty"`
 	// 是否转换成 hls 协议
 	EnableHLS bool `json:"enable_hls,omitempty"`
 	// 是否允许 mp4 录制
@@ -0,0 +1,29 @@
+package zlm
+
+import (
+	"context"
+)
+
+// OnSendRTPStoppedReq 表示 on_send_rtp_stopped 提交的数据
+type OnSendRTPStoppedReq struct {
+	// 虚拟主机
+	VHost string `json:"vhost"`
+	// 服务标识
+	MediaServerID string `json:"mediaServerId"`
+	// 流应用
+	App string `json:"app"`
+	// 流标识
+	Stream string `json:"stream"`
+	// 停止的 ssrc
+	SSRC string `json:"ssrc"`
+	// 错误代码
+	Err int `json:"err"`
+	// 错误信息
+	Msg string `json:"msg"`
+	// 日志追踪
+	TraceID string `json:"-"`
+}
+
+// OnSendRTPStopped 处理 zlm 的 on_send_rtp_stopped 回调
+func OnSendRTPStopped(ctx context.Context, req *OnSendRTPStoppedReq, res *CodeMsg) {
+}
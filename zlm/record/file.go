@@ -0,0 +1,215 @@
+package record
+
+import (
+	"context"
+	"goutil/zlm"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PeriodLayout 是录像目录的日期格式
+const PeriodLayout = "2006-01-02"
+
+// File 表示一个 mp4 录像文件，来自 on_record_mp4
+type File struct {
+	ID int64 `json:"-" gorm:"primaryKey"`
+	// 服务标识
+	ServerID string `json:"serverID" gorm:"type:varchar(64)"`
+	// 流应用
+	App string `json:"app" gorm:"type:varchar(64);index:idx_stream_time,priority:1"`
+	// 流标识
+	Stream string `json:"stream" gorm:"type:varchar(128);index:idx_stream_time,priority:2"`
+	// 文件名
+	FileName string `json:"fileName" gorm:"type:varchar(64)"`
+	// 文件的绝对路径
+	FilePath string `json:"filePath" gorm:"type:varchar(255)"`
+	// 文件大小，单位字节
+	FileSize int64 `json:"fileSize"`
+	// 所在目录的日期，PeriodLayout
+	Period string `json:"period" gorm:"type:varchar(10)"`
+	// 开始时间戳，单位秒
+	StartTime int64 `json:"startTime" gorm:"index:idx_stream_time,priority:3"`
+	// 时长，单位秒
+	Duration float64 `json:"duration"`
+	// 点播的相对路径
+	URL string `json:"url" gorm:"type:varchar(255)"`
+}
+
+// Init 使用 req 初始化
+func (f *File) Init(req *zlm.OnRecordMP4Req) {
+	f.ServerID = req.MediaServerID
+	f.App = req.App
+	f.Stream = req.Stream
+	f.FileName = req.FileName
+	f.FilePath = req.FilePath
+	f.FileSize = req.FileSize
+	f.StartTime = req.StartTime
+	f.Duration = req.TimeLen
+	f.URL = req.URL
+	f.Period = time.Unix(req.StartTime, 0).Format(PeriodLayout)
+}
+
+// EndTime 返回结束时间戳，单位秒
+func (f *File) EndTime() int64 {
+	return f.StartTime + int64(f.Duration+0.5)
+}
+
+// Usage 是一个流的录像占用
+type Usage struct {
+	// 流应用
+	App string
+	// 流标识
+	Stream string
+	// 文件个数
+	Count int64
+	// 总大小，单位字节
+	Size int64
+}
+
+// Store 是录像文件的索引
+type Store interface {
+	// Add 添加文件
+	Add(ctx context.Context, f *File) error
+	// Query 返回和 [start, end] 时间戳范围有交集的文件，按开始时间升序，
+	// end 为 0 表示不限制
+	Query(ctx context.Context, app, stream string, start, end int64) ([]*File, error)
+	// Delete 删除文件
+	Delete(ctx context.Context, fs []*File) error
+	// Usages 返回所有流的占用
+	Usages(ctx context.Context) ([]*Usage, error)
+}
+
+// MemoryStore 在内存中保存索引
+type MemoryStore struct {
+	lock sync.RWMutex
+	// key 是 app/stream ，按开始时间升序
+	files map[string][]*File
+}
+
+// NewMemoryStore 返回新的 MemoryStore
+func NewMemoryStore() *MemoryStore {
+	s := new(MemoryStore)
+	s.files = make(map[string][]*File)
+	return s
+}
+
+// Add 实现 Store
+func (s *MemoryStore) Add(ctx context.Context, f *File) error {
+	key := f.App + "/" + f.Stream
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fs := s.files[key]
+	i := sort.Search(len(fs), func(i int) bool { return fs[i].StartTime > f.StartTime })
+	fs = append(fs, nil)
+	copy(fs[i+1:], fs[i:])
+	fs[i] = f
+	s.files[key] = fs
+	return nil
+}
+
+// Query 实现 Store
+func (s *MemoryStore) Query(ctx context.Context, app, stream string, start, end int64) ([]*File, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var fs []*File
+	for _, f := range s.files[app+"/"+stream] {
+		if f.EndTime() < start || (end > 0 && f.StartTime > end) {
+			continue
+		}
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+// Delete 实现 Store
+func (s *MemoryStore) Delete(ctx context.Context, fs []*File) error {
+	del := make(map[*File]struct{})
+	for _, f := range fs {
+		del[f] = struct{}{}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for k, old := range s.files {
+		var keep []*File
+		for _, f := range old {
+			if _, ok := del[f]; !ok {
+				keep = append(keep, f)
+			}
+		}
+		if len(keep) > 0 {
+			s.files[k] = keep
+		} else {
+			delete(s.files, k)
+		}
+	}
+	return nil
+}
+
+// Usages 实现 Store
+func (s *MemoryStore) Usages(ctx context.Context) ([]*Usage, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	us := make([]*Usage, 0, len(s.files))
+	for _, fs := range s.files {
+		u := &Usage{App: fs[0].App, Stream: fs[0].Stream, Count: int64(len(fs))}
+		for _, f := range fs {
+			u.Size += f.FileSize
+		}
+		us = append(us, u)
+	}
+	return us, nil
+}
+
+// GormStore 使用数据库保存索引
+type GormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore 返回新的 GormStore ，会自动迁移 File 表
+func NewGormStore(db *gorm.DB) (*GormStore, error) {
+	if err := db.AutoMigrate(new(File)); err != nil {
+		return nil, err
+	}
+	return &GormStore{db: db}, nil
+}
+
+// Add 实现 Store
+func (s *GormStore) Add(ctx context.Context, f *File) error {
+	return s.db.WithContext(ctx).Create(f).Error
+}
+
+// Query 实现 Store
+func (s *GormStore) Query(ctx context.Context, app, stream string, start, end int64) ([]*File, error) {
+	db := s.db.WithContext(ctx).
+		Where("`App` = ? AND `Stream` = ? AND `StartTime` + `Duration` >= ?", app, stream, start)
+	if end > 0 {
+		db = db.Where("`StartTime` <= ?", end)
+	}
+	var fs []*File
+	err := db.Order("`StartTime`").Find(&fs).Error
+	return fs, err
+}
+
+// Delete 实现 Store
+func (s *GormStore) Delete(ctx context.Context, fs []*File) error {
+	if len(fs) < 1 {
+		return nil
+	}
+	ids := make([]int64, 0, len(fs))
+	for _, f := range fs {
+		ids = append(ids, f.ID)
+	}
+	return s.db.WithContext(ctx).Delete(new(File), ids).Error
+}
+
+// Usages 实现 Store
+func (s *GormStore) Usages(ctx context.Context) ([]*Usage, error) {
+	var us []*Usage
+	err := s.db.WithContext(ctx).Model(new(File)).
+		Select("`App`, `Stream`, COUNT(*) AS `Count`, SUM(`FileSize`) AS `Size`").
+		Group("`App`, `Stream`").Scan(&us).Error
+	return us, err
+}
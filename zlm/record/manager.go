@@ -0,0 +1,281 @@
+package record
+
+import (
+	"context"
+	"errors"
+	"goutil/log"
+	gsync "goutil/sync"
+	"goutil/zlm"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCheckInterval 是检查录制时间段和保存策略的默认间隔
+	DefaultCheckInterval = time.Minute
+)
+
+var (
+	// ErrNoGetServer 表示没有设置 Option.GetServer
+	ErrNoGetServer = errors.New("get server is nil")
+)
+
+// Option 是 NewManager 的参数
+type Option struct {
+	// 索引，默认 NewMemoryStore
+	Store Store
+	// 策略，按顺序匹配第一个
+	Policies []*Policy
+	// 返回服务，用于调用 zlm 的录制和删除
+	GetServer func(serverID string) (zlm.Server, error)
+	// 检查的间隔，默认 DefaultCheckInterval
+	CheckInterval time.Duration
+	// 删除录像文件，默认调用 zlm.DeleteRecordDirectory 删除单个文件
+	Remove func(ctx context.Context, f *File) error
+	// 点播的地址，用于生成 concat 列表，默认 File.URL
+	FileURL func(f *File) string
+}
+
+// Manager 管理 zlm 的 mp4 录像，
+// 按照策略定时录制和清理，保存 on_record_mp4 的索引
+type Manager struct {
+	opt Option
+	// 在线的流，key 是 app/stream
+	streams gsync.Map[string, *stream]
+	// 用于退出
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// stream 是在线的流
+type stream struct {
+	serverID string
+	app      string
+	stream   string
+	// 录制状态，lock 也用于串行调用 zlm
+	lock      sync.Mutex
+	recording bool
+}
+
+// NewManager 返回新的 Manager ，启动协程检查
+func NewManager(opt *Option) *Manager {
+	m := new(Manager)
+	m.opt = *opt
+	if m.opt.Store == nil {
+		m.opt.Store = NewMemoryStore()
+	}
+	if m.opt.CheckInterval <= 0 {
+		m.opt.CheckInterval = DefaultCheckInterval
+	}
+	if m.opt.Remove == nil {
+		m.opt.Remove = m.remove
+	}
+	if m.opt.FileURL == nil {
+		m.opt.FileURL = func(f *File) string { return f.URL }
+	}
+	m.streams.Init()
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	go m.checkRoutine()
+	return m
+}
+
+// Close 停止检查
+func (m *Manager) Close() {
+	m.cancel()
+}
+
+// Policy 返回流匹配的策略，没有返回 nil
+func (m *Manager) Policy(app, stream string) *Policy {
+	for _, p := range m.opt.Policies {
+		if p.match(app, stream) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Query 返回和 [start, end] 时间戳范围有交集的录像文件
+func (m *Manager) Query(ctx context.Context, app, stream string, start, end int64) ([]*File, error) {
+	return m.opt.Store.Query(ctx, app, stream, start, end)
+}
+
+// HandleStreamChanged 处理 zlm 的 on_stream_changed ，
+// 流注册时如果在录制时间段内就开始录制
+func (m *Manager) HandleStreamChanged(ctx context.Context, req *zlm.OnStreamChangedReq) {
+	key := req.App + "/" + req.Stream
+	if !req.Regist {
+		m.streams.Del(key)
+		return
+	}
+	// 每个协议都会回调一次
+	s := &stream{
+		serverID:  req.MediaServerID,
+		app:       req.App,
+		stream:    req.Stream,
+		recording: req.IsRecordingMP4,
+	}
+	if !m.streams.TrySet(key, s) {
+		return
+	}
+	p := m.Policy(req.App, req.Stream)
+	if p == nil || len(p.Windows) < 1 || req.IsRecordingMP4 || !p.InWindow(time.Now()) {
+		return
+	}
+	go func(trace string) {
+		defer func() {
+			log.Recover(recover())
+		}()
+		if err := m.setRecord(m.ctx, s, p, true); err != nil {
+			log.Errorf(-1, trace, 0, "record %s start %v", key, err)
+		}
+	}(req.TraceID)
+}
+
+// HandleRecordMP4 处理 zlm 的 on_record_mp4 ，保存索引
+func (m *Manager) HandleRecordMP4(ctx context.Context, req *zlm.OnRecordMP4Req) {
+	f := new(File)
+	f.Init(req)
+	if err := m.opt.Store.Add(ctx, f); err != nil {
+		log.Errorf(-1, req.TraceID, 0, "record %s/%s add %s %v", req.App, req.Stream, req.FileName, err)
+	}
+}
+
+// setRecord 开始或者停止录制，状态相同直接返回
+func (m *Manager) setRecord(ctx context.Context, s *stream, p *Policy, on bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.recording == on {
+		return nil
+	}
+	if m.opt.GetServer == nil {
+		return ErrNoGetServer
+	}
+	ser, err := m.opt.GetServer(s.serverID)
+	if err != nil {
+		return err
+	}
+	if on {
+		req := &zlm.StartRecordReq{
+			App:            s.app,
+			Stream:         s.stream,
+			Type:           zlm.RecordFileTypeMP4,
+			CustomizedPath: p.CustomizedPath,
+		}
+		if p.MaxSecond > 0 {
+			req.MaxSecond = strconv.Itoa(p.MaxSecond)
+		}
+		var res zlm.StartRecordRes
+		err = zlm.StartRecord(ctx, ser, req, &res)
+	} else {
+		var res zlm.StopRecordRes
+		err = zlm.StopRecord(ctx, ser, &zlm.StopRecordReq{
+			App:    s.app,
+			Stream: s.stream,
+			Type:   zlm.RecordFileTypeMP4,
+		}, &res)
+	}
+	if err != nil {
+		return err
+	}
+	s.recording = on
+	return nil
+}
+
+// remove 调用 zlm 删除单个文件
+func (m *Manager) remove(ctx context.Context, f *File) error {
+	if m.opt.GetServer == nil {
+		return ErrNoGetServer
+	}
+	ser, err := m.opt.GetServer(f.ServerID)
+	if err != nil {
+		return err
+	}
+	var res zlm.DeleteRecordDirectoryRes
+	return zlm.DeleteRecordDirectory(ctx, ser, &zlm.DeleteRecordDirectoryReq{
+		App:    f.App,
+		Stream: f.Stream,
+		Period: f.Period,
+		Name:   f.FileName,
+	}, &res)
+}
+
+// checkRoutine 在协程中定时检查
+func (m *Manager) checkRoutine() {
+	defer func() {
+		log.Recover(recover())
+	}()
+	ticker := time.NewTicker(m.opt.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		m.schedule()
+		m.retain()
+	}
+}
+
+// schedule 按照录制时间段开始或者停止录制
+func (m *Manager) schedule() {
+	now := time.Now()
+	for _, s := range m.streams.Values() {
+		p := m.Policy(s.app, s.stream)
+		if p == nil || len(p.Windows) < 1 {
+			continue
+		}
+		in := p.InWindow(now)
+		if err := m.setRecord(m.ctx, s, p, in); err != nil {
+			log.Errorf(-1, "", 0, "record %s/%s set %v %v", s.app, s.stream, in, err)
+		}
+	}
+}
+
+// retain 按照保存天数和占用删除旧的文件
+func (m *Manager) retain() {
+	us, err := m.opt.Store.Usages(m.ctx)
+	if err != nil {
+		log.Errorf(-1, "", 0, "record usages %v", err)
+		return
+	}
+	for _, u := range us {
+		p := m.Policy(u.App, u.Stream)
+		if p == nil || (p.Days <= 0 && p.Quota <= 0) {
+			continue
+		}
+		if err := m.retainStream(p, u); err != nil {
+			log.Errorf(-1, "", 0, "record %s/%s retain %v", u.App, u.Stream, err)
+		}
+	}
+}
+
+// retainStream 删除一个流的旧文件
+func (m *Manager) retainStream(p *Policy, u *Usage) error {
+	fs, err := m.opt.Store.Query(m.ctx, u.App, u.Stream, 0, 0)
+	if err != nil {
+		return err
+	}
+	var before int64
+	if p.Days > 0 {
+		before = time.Now().AddDate(0, 0, -p.Days).Unix()
+	}
+	size := u.Size
+	var del []*File
+	// 按时间升序，从最旧的开始
+	for _, f := range fs {
+		expired := before > 0 && f.EndTime() < before
+		over := p.Quota > 0 && size > p.Quota
+		if !expired && !over {
+			break
+		}
+		if err := m.opt.Remove(m.ctx, f); err != nil {
+			log.Errorf(-1, "", 0, "record %s/%s remove %s %v", f.App, f.Stream, f.FileName, err)
+			continue
+		}
+		size -= f.FileSize
+		del = append(del, f)
+	}
+	return m.opt.Store.Delete(m.ctx, del)
+}
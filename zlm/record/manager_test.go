@@ -0,0 +1,101 @@
+package record
+
+import (
+	"bytes"
+	"context"
+	"goutil/zlm"
+	"goutil/zlm/zlmtest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// addRecord 模拟 zlm 录制了一个文件，inZLM 为 false 表示文件只在索引中
+func addRecord(m *Manager, ser *zlmtest.Server, stream string, start time.Time, inZLM bool) {
+	name := strconv.FormatInt(start.Unix(), 10) + ".mp4"
+	if inZLM {
+		ser.AddRecordFile("live", stream, start.Format(PeriodLayout), name)
+	}
+	m.HandleRecordMP4(context.Background(), &zlm.OnRecordMP4Req{
+		MediaServerID: ser.ID(),
+		App:           "live",
+		Stream:        stream,
+		FileName:      name,
+		FileSize:      100,
+		StartTime:     start.Unix(),
+		TimeLen:       60,
+	})
+}
+
+func Test_Retain(t *testing.T) {
+	ser := zlmtest.NewServer(nil)
+	defer ser.Close()
+	m := NewManager(&Option{
+		Policies: []*Policy{
+			{Pattern: "live/a", Days: 2},
+			{Pattern: "live/b", Quota: 250},
+		},
+		GetServer: func(serverID string) (zlm.Server, error) {
+			return ser, nil
+		},
+		CheckInterval: 20 * time.Millisecond,
+	})
+	defer m.Close()
+	now := time.Now()
+	// 过期的
+	addRecord(m, ser, "a", now.AddDate(0, 0, -5), true)
+	addRecord(m, ser, "a", now.AddDate(0, 0, -4), true)
+	// zlm 删除失败，保留索引
+	addRecord(m, ser, "a", now.AddDate(0, 0, -3), false)
+	addRecord(m, ser, "a", now.Add(-time.Hour), true)
+	// 超过占用的
+	for i := 4; i > 0; i-- {
+		addRecord(m, ser, "b", now.Add(-time.Duration(i)*time.Minute), true)
+	}
+	// 等待
+	ctx := context.Background()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(ser.RecordFiles()) != 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("zlm files %d, want 3", len(ser.RecordFiles()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	fs, err := m.Query(ctx, "live", "a", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 2 || fs[0].StartTime != now.AddDate(0, 0, -3).Unix() || fs[1].StartTime != now.Add(-time.Hour).Unix() {
+		t.Fatalf("stream a files %d", len(fs))
+	}
+	fs, err = m.Query(ctx, "live", "b", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 2 || fs[0].StartTime != now.Add(-2*time.Minute).Unix() {
+		t.Fatalf("stream b files %d", len(fs))
+	}
+}
+
+func Test_Concat(t *testing.T) {
+	fs := []*File{
+		{StartTime: 100, Duration: 60, URL: "a.mp4"},
+		{StartTime: 160, Duration: 60, URL: "b.mp4"},
+		{StartTime: 300, Duration: 60, URL: "c.mp4"},
+	}
+	rs := NewRanges(fs, 130, 320)
+	if len(rs) != 3 || !rs[2].Discontinuity || rs[1].Discontinuity {
+		t.Fatalf("ranges %d", len(rs))
+	}
+	var buf bytes.Buffer
+	if err := WriteFFConcat(&buf, rs, func(f *File) string { return f.URL }); err != nil {
+		t.Fatal(err)
+	}
+	want := "ffconcat version 1.0\n" +
+		"file 'a.mp4'\ninpoint 30.000\n" +
+		"file 'b.mp4'\n" +
+		"file 'c.mp4'\noutpoint 20.000\n"
+	if buf.String() != want {
+		t.Fatalf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
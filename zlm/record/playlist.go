@@ -0,0 +1,88 @@
+package record
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Range 表示时间范围内的一段录像
+type Range struct {
+	// 文件
+	File *File
+	// 在文件中的开始位置，单位秒
+	Offset float64
+	// 时长，单位秒
+	Duration float64
+	// 和前一段不连续
+	Discontinuity bool
+}
+
+// NewRanges 返回文件在 [start, end] 时间戳范围内的片段，
+// fs 需要按开始时间升序，end 为 0 表示不限制
+func NewRanges(fs []*File, start, end int64) []*Range {
+	var rs []*Range
+	var last int64
+	for _, f := range fs {
+		fStart := float64(f.StartTime)
+		fEnd := fStart + f.Duration
+		s := math.Max(fStart, float64(start))
+		e := fEnd
+		if end > 0 {
+			e = math.Min(fEnd, float64(end))
+		}
+		if e <= s {
+			continue
+		}
+		r := &Range{
+			File:     f,
+			Offset:   s - fStart,
+			Duration: e - s,
+		}
+		// 允许 1 秒的误差
+		if len(rs) > 0 && f.StartTime-last > 1 {
+			r.Discontinuity = true
+		}
+		last = f.EndTime()
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// Ranges 返回流在 [start, end] 时间戳范围内的片段
+func (m *Manager) Ranges(ctx context.Context, app, stream string, start, end int64) ([]*Range, error) {
+	fs, err := m.opt.Store.Query(ctx, app, stream, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return NewRanges(fs, start, end), nil
+}
+
+// WriteFFConcat 写入 ffmpeg concat 列表，使用 inpoint/outpoint 精确裁剪，
+// 用于把多个文件拼接成一个，需要 hls 点播的可以用 ffmpeg 转成 ts 或者 fmp4 分片
+func WriteFFConcat(w io.Writer, rs []*Range, url func(*File) string) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ffconcat version 1.0\n")
+	for _, r := range rs {
+		fmt.Fprintf(bw, "file '%s'\n", url(r.File))
+		if r.Offset > 0 {
+			fmt.Fprintf(bw, "inpoint %.3f\n", r.Offset)
+		}
+		if r.Offset+r.Duration < r.File.Duration {
+			fmt.Fprintf(bw, "outpoint %.3f\n", r.Offset+r.Duration)
+		}
+	}
+	return bw.Flush()
+}
+
+// Concat 写入流在 [start, end] 时间戳范围内的 ffmpeg concat 列表，
+// 文件的地址使用 Option.FileURL
+func (m *Manager) Concat(ctx context.Context, w io.Writer, app, stream string, start, end int64) error {
+	rs, err := m.Ranges(ctx, app, stream, start, end)
+	if err != nil {
+		return err
+	}
+	return WriteFFConcat(w, rs, m.opt.FileURL)
+}
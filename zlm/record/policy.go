@@ -0,0 +1,89 @@
+package record
+
+import (
+	"path"
+	"time"
+)
+
+// Window 是一个录制时间段，比如每天的 08:00~18:00
+type Window struct {
+	// 星期几，空表示每天
+	Weekdays []time.Weekday
+	// 开始和结束，格式 15:04 ，结束小于开始表示跨天
+	Start string
+	End   string
+}
+
+// contains 返回 t 是否在时间段内
+func (w *Window) contains(t time.Time) bool {
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return false
+	}
+	m := t.Hour()*60 + t.Minute()
+	sm := start.Hour()*60 + start.Minute()
+	em := end.Hour()*60 + end.Minute()
+	// 跨天的算在开始那一天
+	day := t.Weekday()
+	in := false
+	if sm <= em {
+		in = m >= sm && m < em
+	} else if m >= sm {
+		in = true
+	} else if m < em {
+		in = true
+		day = (day + 6) % 7
+	}
+	if !in {
+		return false
+	}
+	if len(w.Weekdays) < 1 {
+		return true
+	}
+	for _, d := range w.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy 是流的录像策略
+type Policy struct {
+	// 匹配 app/stream ，path.Match 的格式，比如 rtp/* ，空表示全部
+	Pattern string
+	// 保存的天数，0 表示不限制
+	Days int
+	// 占用的最大字节，超过删除最旧的，0 表示不限制
+	Quota int64
+	// 录制时间段，空表示不自动录制，
+	// 流注册时在时间段内开始录制，之外停止录制
+	Windows []*Window
+	// mp4 录制的保存目录，空使用 zlm 的配置
+	CustomizedPath string
+	// mp4 切片的时长，单位秒，0 使用 zlm 的配置
+	MaxSecond int
+}
+
+// match 返回是否匹配 app/stream
+func (p *Policy) match(app, stream string) bool {
+	if p.Pattern == "" {
+		return true
+	}
+	ok, _ := path.Match(p.Pattern, app+"/"+stream)
+	return ok
+}
+
+// InWindow 返回 t 是否在录制时间段内
+func (p *Policy) InWindow(t time.Time) bool {
+	for _, w := range p.Windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+	return false
+}
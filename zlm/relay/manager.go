@@ -0,0 +1,265 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"goutil/log"
+	gsync "goutil/sync"
+	"goutil/zlm"
+	"time"
+)
+
+const (
+	// DefaultCheckInterval 是轮询检查的默认间隔
+	DefaultCheckInterval = 10 * time.Second
+	// DefaultMinBackoff 是失败后第一次重试的默认间隔，之后每次翻倍
+	DefaultMinBackoff = time.Second
+	// DefaultMaxBackoff 是默认的最大重试间隔
+	DefaultMaxBackoff = time.Minute
+	// DefaultCallTimeout 是调用 zlm 的默认超时
+	DefaultCallTimeout = 5 * time.Second
+)
+
+var (
+	// ErrNotAlive 表示 zlm 上的转发已经不存在
+	ErrNotAlive = errors.New("relay not alive")
+)
+
+// Option 是 NewManager 的参数
+type Option struct {
+	// 轮询检查的间隔，默认 DefaultCheckInterval
+	CheckInterval time.Duration
+	// 失败后第一次重试的间隔，默认 DefaultMinBackoff
+	MinBackoff time.Duration
+	// 最大的重试间隔，默认 DefaultMaxBackoff
+	MaxBackoff time.Duration
+	// 调用 zlm 的超时，默认 DefaultCallTimeout
+	CallTimeout time.Duration
+	// 源流出现过之后又消失，是否删除转发，否则等待源流
+	RemoveOnSourceGone bool
+	// 状态变化的回调
+	OnStatus func(s *Status)
+}
+
+// Manager 维护声明的转发，通过回调和轮询发现失败，
+// 按照退避的间隔重新启动
+type Manager struct {
+	opt Option
+	// key 是 Relay.ID
+	relays gsync.Map[string, *relay]
+}
+
+// NewManager 返回新的 Manager
+func NewManager(opt *Option) *Manager {
+	m := new(Manager)
+	m.opt = *opt
+	if m.opt.CheckInterval <= 0 {
+		m.opt.CheckInterval = DefaultCheckInterval
+	}
+	if m.opt.MinBackoff <= 0 {
+		m.opt.MinBackoff = DefaultMinBackoff
+	}
+	if m.opt.MaxBackoff < m.opt.MinBackoff {
+		m.opt.MaxBackoff = DefaultMaxBackoff
+	}
+	if m.opt.CallTimeout <= 0 {
+		m.opt.CallTimeout = DefaultCallTimeout
+	}
+	m.relays.Init()
+	return m
+}
+
+// Add 添加转发，启动协程维护，已经存在则替换
+func (m *Manager) Add(ctx context.Context, rel *Relay) {
+	if old := m.relays.Get(rel.ID); old != nil {
+		m.remove(ctx, old)
+	}
+	r := new(relay)
+	r.Relay = rel
+	r.m = m
+	r.status.ID = rel.ID
+	r.status.State = StateWaiting
+	r.status.Since = time.Now()
+	r.kick = make(chan struct{}, 1)
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	m.relays.Set(rel.ID, r)
+	go m.routine(r)
+}
+
+// Remove 删除转发，并停止 zlm 上的转发
+func (m *Manager) Remove(ctx context.Context, id string) error {
+	r := m.relays.Get(id)
+	if r == nil {
+		return nil
+	}
+	return m.remove(ctx, r)
+}
+
+// remove 删除转发，等待正在进行的检查结束之后再停止
+func (m *Manager) remove(ctx context.Context, r *relay) error {
+	if m.relays.Take(r.ID) != r {
+		return nil
+	}
+	r.cancel()
+	r.run.Lock()
+	defer r.run.Unlock()
+	var err error
+	if r.getStatus().State == StateRunning {
+		err = r.stop(ctx)
+	} else if r.Kind == KindRTP {
+		// 启动的请求可能已经到达 zlm ，但是被取消了没有收到响应
+		r.stop(ctx)
+	}
+	r.setState(StateStopped, nil)
+	return err
+}
+
+// Close 停止所有的协程，不会停止 zlm 上的转发
+func (m *Manager) Close() {
+	for _, r := range m.relays.TakeAll() {
+		r.cancel()
+	}
+}
+
+// Status 返回转发的状态，不存在返回 nil
+func (m *Manager) Status(id string) *Status {
+	r := m.relays.Get(id)
+	if r == nil {
+		return nil
+	}
+	return r.getStatus()
+}
+
+// Statuses 返回所有转发的状态
+func (m *Manager) Statuses() []*Status {
+	var ss []*Status
+	m.relays.Foreach(func(r *relay) {
+		ss = append(ss, r.getStatus())
+	})
+	return ss
+}
+
+// HandleStreamChanged 处理 zlm 的 on_stream_changed ，
+// 源流注册或者注销时立即检查
+func (m *Manager) HandleStreamChanged(ctx context.Context, req *zlm.OnStreamChangedReq) {
+	for _, r := range m.relays.Search(func(r *relay) bool {
+		return r.App == req.App && r.Stream == req.Stream
+	}) {
+		r.trigger()
+	}
+}
+
+// HandleSendRTPStopped 处理 zlm 的 on_send_rtp_stopped ，立即检查
+func (m *Manager) HandleSendRTPStopped(ctx context.Context, req *zlm.OnSendRTPStoppedReq) {
+	for _, r := range m.relays.Search(func(r *relay) bool {
+		return r.Kind == KindRTP && r.App == req.App && r.Stream == req.Stream && r.SSRC == req.SSRC
+	}) {
+		r.trigger()
+	}
+}
+
+// routine 在协程中维护转发
+func (m *Manager) routine(r *relay) {
+	defer func() {
+		log.Recover(recover())
+	}()
+	ticker := time.NewTicker(m.opt.CheckInterval)
+	defer ticker.Stop()
+	// 源流是否出现过
+	seen := false
+	for {
+		m.sync(r, &seen)
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.kick:
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync 检查一次，根据状态启动或者重启
+func (m *Manager) sync(r *relay, seen *bool) {
+	r.run.Lock()
+	defer r.run.Unlock()
+	// 已经删除
+	if r.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, m.opt.CallTimeout)
+	defer cancel()
+	st := r.getStatus()
+	// 源流
+	ok, err := r.hasSource(ctx)
+	if err != nil {
+		log.Errorf(-1, "", 0, "relay %s check source %v", r.ID, err)
+		return
+	}
+	if !ok {
+		if st.State == StateRunning {
+			r.stop(ctx)
+		}
+		if *seen && m.opt.RemoveOnSourceGone {
+			// 已经停止，不能调用 remove ，会等待自己
+			if m.relays.Take(r.ID) == r {
+				r.cancel()
+				r.setState(StateStopped, nil)
+			}
+			return
+		}
+		r.lock.Lock()
+		r.backoff = 0
+		r.lock.Unlock()
+		r.setState(StateWaiting, nil)
+		return
+	}
+	*seen = true
+	// 检查
+	if st.State == StateRunning {
+		alive, err := r.alive(ctx)
+		if err == nil && alive {
+			return
+		}
+		if err == nil {
+			err = ErrNotAlive
+		}
+		r.stop(ctx)
+		m.fail(r, err)
+		return
+	}
+	// 等待重试
+	if st.State == StateFailed && time.Now().Before(st.Next) {
+		return
+	}
+	// 启动
+	if err := r.start(ctx); err != nil {
+		m.fail(r, err)
+		return
+	}
+	r.lock.Lock()
+	if st.State == StateFailed {
+		r.status.Restarts++
+	}
+	r.backoff = 0
+	r.lock.Unlock()
+	r.setState(StateRunning, nil)
+}
+
+// fail 设置失败，在退避的间隔之后重试
+func (m *Manager) fail(r *relay, err error) {
+	r.lock.Lock()
+	if r.backoff <= 0 {
+		r.backoff = m.opt.MinBackoff
+	} else {
+		r.backoff *= 2
+		if r.backoff > m.opt.MaxBackoff {
+			r.backoff = m.opt.MaxBackoff
+		}
+	}
+	backoff := r.backoff
+	r.status.Next = time.Now().Add(backoff)
+	r.lock.Unlock()
+	log.Errorf(-1, "", 0, "relay %s failed %v, retry after %v", r.ID, err, backoff)
+	r.setState(StateFailed, err)
+	time.AfterFunc(backoff, r.trigger)
+}
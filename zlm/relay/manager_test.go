@@ -0,0 +1,115 @@
+package relay
+
+import (
+	"context"
+	"goutil/zlm"
+	"goutil/zlm/zlmtest"
+	"testing"
+	"time"
+)
+
+// waitStatus 等待转发的状态满足 f ，超时失败
+func waitStatus(t *testing.T, m *Manager, msg string, f func(s *Status) bool) *Status {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		s := m.Status("r")
+		if s != nil && f(s) {
+			return s
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s, status %+v", msg, s)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// stateIs 返回判断状态的函数
+func stateIs(state State) func(s *Status) bool {
+	return func(s *Status) bool {
+		return s.State == state
+	}
+}
+
+func Test_Push_Restart(t *testing.T) {
+	ser := zlmtest.NewServer(nil)
+	defer ser.Close()
+	if err := ser.AddStream("live", "a"); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManager(&Option{
+		CheckInterval: 20 * time.Millisecond,
+		MinBackoff:    100 * time.Millisecond,
+		MaxBackoff:    400 * time.Millisecond,
+	})
+	defer m.Close()
+	ctx := context.Background()
+	rel := &Relay{
+		ID:     "r",
+		Kind:   KindPush,
+		Server: ser,
+		App:    "live",
+		Stream: "a",
+		Schema: zlm.RTMP,
+		DstURL: "rtmp://127.0.0.1/live/a",
+	}
+	m.Add(ctx, rel)
+	s := waitStatus(t, m, "relay not running", stateIs(StateRunning))
+	if ps := ser.Pushers(); len(ps) != 1 || ps[0].Key != s.Key {
+		t.Fatalf("pushers %d", len(ps))
+	}
+	key := s.Key
+	// 推流失败
+	ser.SetPusherStatus(key, -1)
+	s = waitStatus(t, m, "relay not failed", stateIs(StateFailed))
+	if s.Error != ErrNotAlive.Error() {
+		t.Fatalf("error %s, want %v", s.Error, ErrNotAlive)
+	}
+	// 占用推流，重启失败，重试的间隔翻倍，最大 MaxBackoff
+	var add zlm.AddStreamPusherProxyRes
+	if err := zlm.AddStreamPusherProxy(ctx, ser, &zlm.AddStreamPusherProxyReq{
+		Schema: rel.Schema,
+		App:    rel.App,
+		Stream: rel.Stream,
+		DstURL: rel.DstURL,
+	}, &add); err != nil {
+		t.Fatal(err)
+	}
+	next := s.Next
+	for _, want := range []time.Duration{200, 400, 400} {
+		s = waitStatus(t, m, "relay not retried", func(s *Status) bool {
+			return !s.Next.Equal(next)
+		})
+		if s.State != StateFailed {
+			t.Fatalf("state %v, want %v", s.State, StateFailed)
+		}
+		if d := s.Next.Sub(next); d < want*time.Millisecond {
+			t.Fatalf("backoff %v, want at least %v", d, want*time.Millisecond)
+		}
+		next = s.Next
+	}
+	// 恢复
+	var del zlm.DelStreamPusherProxyRes
+	if err := zlm.DelStreamPusherProxy(ctx, ser, &zlm.DelStreamPusherProxyReq{Key: add.Data.Key}, &del); err != nil {
+		t.Fatal(err)
+	}
+	s = waitStatus(t, m, "relay not restarted", stateIs(StateRunning))
+	if s.Restarts != 1 {
+		t.Fatalf("restarts %d, want 1", s.Restarts)
+	}
+	if ps := ser.Pushers(); len(ps) != 1 || ps[0].Status != 0 {
+		t.Fatalf("pushers %d", len(ps))
+	}
+	// 源流注销，等待源流
+	ser.RemoveStream("live", "a")
+	waitStatus(t, m, "relay not waiting", stateIs(StateWaiting))
+	ser.AddStream("live", "a")
+	waitStatus(t, m, "relay not running again", stateIs(StateRunning))
+	// 删除
+	if err := m.Remove(ctx, "r"); err != nil {
+		t.Fatal(err)
+	}
+	if m.Status("r") != nil || len(ser.Pushers()) != 0 {
+		t.Fatal("relay should be removed")
+	}
+}
@@ -0,0 +1,252 @@
+package relay
+
+import (
+	"context"
+	"goutil/zlm"
+	"sync"
+	"time"
+)
+
+// Kind 是转发的类型
+type Kind int
+
+const (
+	// KindPush 使用 addStreamPusherProxy 推 rtmp/rtsp
+	KindPush Kind = iota
+	// KindRTP 使用 startSendRtp 向上级平台发送国标 rtp
+	KindRTP
+)
+
+// State 是转发的状态
+type State int
+
+const (
+	// StateWaiting 等待源流
+	StateWaiting State = iota
+	// StateRunning 正在转发
+	StateRunning
+	// StateFailed 失败，等待重试
+	StateFailed
+	// StateStopped 已经删除
+	StateStopped
+)
+
+var stateNames = map[State]string{
+	StateWaiting: "waiting",
+	StateRunning: "running",
+	StateFailed:  "failed",
+	StateStopped: "stopped",
+}
+
+func (s State) String() string {
+	return stateNames[s]
+}
+
+// Relay 是声明的转发
+type Relay struct {
+	// 唯一标识
+	ID string
+	// 类型
+	Kind Kind
+	// 源流所在的服务
+	Server zlm.Server
+	// 源流
+	App    string
+	Stream string
+	// KindPush 的协议，zlm.RTMP/zlm.RTSP
+	Schema string
+	// KindPush 的推流地址
+	DstURL string
+	// KindPush 的 rtsp 推流方式
+	RTPType zlm.RTSPRTPType
+	// KindRTP 的 ssrc
+	SSRC string
+	// KindRTP 的目标地址
+	DstIP   string
+	DstPort string
+	// KindRTP 是否使用 udp
+	UDP bool
+	// KindRTP 的负载类型，默认 96
+	PT string
+	// KindRTP 的打包方式，默认 ps
+	UsePS zlm.RTPPayloadType
+	// KindRTP 是否只发送音频
+	OnlyAudio zlm.Boolean
+}
+
+// Status 是转发的状态
+type Status struct {
+	// 唯一标识
+	ID string `json:"id"`
+	// 状态
+	State State `json:"state"`
+	// 最后一次的错误
+	Error string `json:"error,omitempty"`
+	// 重新启动的次数
+	Restarts int `json:"restarts"`
+	// 进入当前状态的时间
+	Since time.Time `json:"since"`
+	// 下一次重试的时间，StateFailed 有效
+	Next time.Time `json:"next,omitempty"`
+	// KindPush 的 key
+	Key string `json:"key,omitempty"`
+	// KindRTP 的本地端口
+	LocalPort int `json:"localPort,omitempty"`
+}
+
+// relay 是运行中的转发
+type relay struct {
+	*Relay
+	m *Manager
+	// 状态
+	lock    sync.Mutex
+	status  Status
+	backoff time.Duration
+	// 串行执行检查和删除，保证删除之后不会再启动
+	run sync.Mutex
+	// 触发检查
+	kick chan struct{}
+	// 用于退出
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// trigger 触发一次检查
+func (r *relay) trigger() {
+	select {
+	case r.kick <- struct{}{}:
+	default:
+	}
+}
+
+// getStatus 返回状态的拷贝
+func (r *relay) getStatus() *Status {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	s := r.status
+	return &s
+}
+
+// setState 设置状态，有变化则回调
+func (r *relay) setState(state State, err error) {
+	r.lock.Lock()
+	changed := r.status.State != state
+	if changed {
+		r.status.State = state
+		r.status.Since = time.Now()
+	}
+	r.status.Error = ""
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+	s := r.status
+	r.lock.Unlock()
+	if changed && r.m.opt.OnStatus != nil {
+		r.m.opt.OnStatus(&s)
+	}
+}
+
+// start 开始转发
+func (r *relay) start(ctx context.Context) error {
+	if r.Kind == KindPush {
+		var res zlm.AddStreamPusherProxyRes
+		err := zlm.AddStreamPusherProxy(ctx, r.Server, &zlm.AddStreamPusherProxyReq{
+			Schema:  r.Schema,
+			App:     r.App,
+			Stream:  r.Stream,
+			DstURL:  r.DstURL,
+			RTPType: r.RTPType,
+			// 由 Manager 负责重试
+			RetryCount: "1",
+		}, &res)
+		if err != nil {
+			return err
+		}
+		r.lock.Lock()
+		r.status.Key = res.Data.Key
+		r.lock.Unlock()
+		return nil
+	}
+	req := &zlm.StartSendRTPReq{
+		App:       r.App,
+		Stream:    r.Stream,
+		SSRC:      r.SSRC,
+		DstIP:     r.DstIP,
+		DstPort:   r.DstPort,
+		IsUDP:     zlm.Zero,
+		PT:        r.PT,
+		UsePS:     r.UsePS,
+		OnlyAudio: r.OnlyAudio,
+	}
+	if r.UDP {
+		req.IsUDP = zlm.One
+	}
+	var res zlm.StartSendRTPRes
+	if err := zlm.StartSendRTP(ctx, r.Server, req, &res); err != nil {
+		return err
+	}
+	r.lock.Lock()
+	r.status.LocalPort = res.LocalPort
+	r.lock.Unlock()
+	return nil
+}
+
+// stop 停止转发
+func (r *relay) stop(ctx context.Context) error {
+	if r.Kind == KindPush {
+		r.lock.Lock()
+		key := r.status.Key
+		r.status.Key = ""
+		r.lock.Unlock()
+		if key == "" {
+			return nil
+		}
+		var res zlm.DelStreamPusherProxyRes
+		return zlm.DelStreamPusherProxy(ctx, r.Server, &zlm.DelStreamPusherProxyReq{Key: key}, &res)
+	}
+	var res zlm.StopSendRTPRes
+	return zlm.StopSendRTP(ctx, r.Server, &zlm.StopSendRTPReq{
+		App:    r.App,
+		Stream: r.Stream,
+		SSRC:   r.SSRC,
+	}, &res)
+}
+
+// alive 返回转发是否正常
+func (r *relay) alive(ctx context.Context) (bool, error) {
+	if r.Kind == KindPush {
+		r.lock.Lock()
+		key := r.status.Key
+		r.lock.Unlock()
+		var res zlm.GetProxyPusherInfoRes
+		if err := zlm.GetProxyPusherInfo(ctx, r.Server, &zlm.GetProxyPusherInfoReq{Key: key}, &res); err != nil {
+			return false, err
+		}
+		return res.Data.Status == 0, nil
+	}
+	var res zlm.ListRTPSenderRes
+	if err := zlm.ListRTPSender(ctx, r.Server, &zlm.ListRTPSenderReq{
+		App:    r.App,
+		Stream: r.Stream,
+	}, &res); err != nil {
+		return false, err
+	}
+	for _, ssrc := range res.Data {
+		if ssrc == r.SSRC {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// hasSource 返回源流是否存在
+func (r *relay) hasSource(ctx context.Context) (bool, error) {
+	var res zlm.GetMediaListRes
+	if err := zlm.GetMediaList(ctx, r.Server, &zlm.GetMediaListReq{
+		App:    r.App,
+		Stream: r.Stream,
+	}, &res); err != nil {
+		return false, err
+	}
+	return len(res.Data) > 0, nil
+}
@@ -0,0 +1,40 @@
+package zlm
+
+import (
+	"context"
+)
+
+// StartSendRTPTalkReq 是 StartSendRTPTalk 参数
+type StartSendRTPTalkReq struct {
+	// 流应用
+	App string `query:"app"`
+	// 流标识
+	Stream string `query:"stream"`
+	// ssrc
+	SSRC string `query:"ssrc"`
+	// 对讲的收流标识，使用这个 rtp server 的连接发送
+	RecvStreamID string `query:"recv_stream_id"`
+	// 默认为 96
+	PT string `query:"pt"`
+	// 负载类型，默认为 1
+	UsePS RTPPayloadType `query:"use_ps"`
+	// es 方式打包是否只打包音频
+	OnlyAudio Boolean `query:"only_audio"`
+}
+
+// StartSendRTPTalkRes 是 StartSendRTPTalk 返回值
+type StartSendRTPTalkRes struct {
+	CodeMsg
+	// 使用的本地端口号
+	LocalPort int `json:"local_port"`
+}
+
+const (
+	StartSendRtpTalkPath = apiPathPrefix + "/startSendRtpTalk"
+)
+
+// StartSendRTPTalk 调用 /index/api/startSendRtpTalk ，
+// 使用收流的连接回传 rtp ，用于双向对讲
+func StartSendRTPTalk(ctx context.Context, ser Server, req *StartSendRTPTalkReq, res *StartSendRTPTalkRes) error {
+	return Request(ctx, ser, StartSendRtpTalkPath, req, res)
+}
@@ -0,0 +1,57 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Revoker 保存撤销的签名，集群部署时需要共享
+type Revoker interface {
+	// Revoke 撤销签名，expire 之后可以删除
+	Revoke(ctx context.Context, token string, expire time.Time) error
+	// IsRevoked 返回签名是否已撤销
+	IsRevoked(ctx context.Context, token string) (bool, error)
+}
+
+// MemoryRevoker 是内存的 Revoker
+type MemoryRevoker struct {
+	lock   sync.Mutex
+	tokens map[string]time.Time
+	// 上一次清理的时间
+	sweepTime time.Time
+}
+
+// NewMemoryRevoker 返回新的 MemoryRevoker
+func NewMemoryRevoker() *MemoryRevoker {
+	r := new(MemoryRevoker)
+	r.tokens = make(map[string]time.Time)
+	r.sweepTime = time.Now()
+	return r
+}
+
+// Revoke 实现 Revoker
+func (r *MemoryRevoker) Revoke(ctx context.Context, token string, expire time.Time) error {
+	now := time.Now()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// 清理过期的
+	if now.Sub(r.sweepTime) >= time.Minute {
+		for k, t := range r.tokens {
+			if now.After(t) {
+				delete(r.tokens, k)
+			}
+		}
+		r.sweepTime = now
+	}
+	r.tokens[token] = expire
+	return nil
+}
+
+// IsRevoked 实现 Revoker
+func (r *MemoryRevoker) IsRevoked(ctx context.Context, token string) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, ok := r.tokens[token]
+	return ok, nil
+}
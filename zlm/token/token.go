@@ -0,0 +1,209 @@
+package token
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"goutil/zlm"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 签名的动作，同一个签名不能同时用于播放和推流
+const (
+	ActionPlay    = "play"
+	ActionPublish = "publish"
+)
+
+// url 查询字符串的参数名称
+const (
+	QueryToken  = "token"
+	QueryExpire = "expire"
+)
+
+const (
+	// DefaultExpire 是默认的有效期
+	DefaultExpire = time.Hour
+)
+
+var (
+	// ErrMissing 没有签名
+	ErrMissing = errors.New("token missing")
+	// ErrExpired 签名过期
+	ErrExpired = errors.New("token expired")
+	// ErrInvalid 签名错误
+	ErrInvalid = errors.New("token invalid")
+	// ErrRevoked 签名已撤销
+	ErrRevoked = errors.New("token revoked")
+)
+
+// Policy 是某一个协议的验证策略
+type Policy struct {
+	// 不需要验证
+	Anonymous bool
+	// 签名是否绑定客户端的地址，
+	// 转发/代理的场景下客户端地址会变化，可以关闭
+	BindIP bool
+	// 签名剩余的有效期不能超过这个值，0 表示不限制，
+	// 用于限制长期有效的地址在某些协议上使用
+	MaxExpire time.Duration
+}
+
+// Option 是 New 的参数
+type Option struct {
+	// 签名的密钥
+	Secret string
+	// Sign 的默认有效期，默认 DefaultExpire
+	Expire time.Duration
+	// 协议的策略，key 是 zlm 回调的 schema ，
+	// 比如 zlm.RTSP/zlm.RTMP/zlm.HLS/zlm.RTC ，
+	// 注意 http-flv/ws-flv 的 schema 是 zlm.RTMP
+	Policies map[string]*Policy
+	// 没有设置协议时使用的策略，默认绑定客户端地址
+	DefaultPolicy *Policy
+	// 撤销的签名，默认 NewMemoryRevoker
+	Revoker Revoker
+	// 验证通过之后调用，用于填充 OnPublishRes 之类
+	Play    zlm.PlayHandler
+	Publish zlm.PublishHandler
+}
+
+// Token 生成和验证流地址的签名，
+// 签名是 app/stream/expire/ip 的 HMAC-SHA256 。
+// 实现 zlm.PlayHandler 和 zlm.PublishHandler ，可以设置到 zlm.Hook 中
+type Token struct {
+	opt Option
+}
+
+// New 返回新的 Token
+func New(opt *Option) *Token {
+	t := new(Token)
+	t.opt = *opt
+	if t.opt.Expire <= 0 {
+		t.opt.Expire = DefaultExpire
+	}
+	if t.opt.DefaultPolicy == nil {
+		t.opt.DefaultPolicy = &Policy{BindIP: true}
+	}
+	if t.opt.Revoker == nil {
+		t.opt.Revoker = NewMemoryRevoker()
+	}
+	return t
+}
+
+// Sign 返回 url 查询字符串，比如 expire=1700000000&token=xxx ，
+// ip 为空表示不绑定客户端地址，expire 为 0 使用默认的有效期
+func (t *Token) Sign(action, app, stream, ip string, expire time.Duration) string {
+	if expire <= 0 {
+		expire = t.opt.Expire
+	}
+	exp := strconv.FormatInt(time.Now().Add(expire).Unix(), 10)
+	q := make(url.Values)
+	q.Set(QueryExpire, exp)
+	q.Set(QueryToken, t.sign(action, app, stream, exp, ip))
+	return q.Encode()
+}
+
+// SignPlay 返回播放的 url 查询字符串
+func (t *Token) SignPlay(app, stream, ip string, expire time.Duration) string {
+	return t.Sign(ActionPlay, app, stream, ip, expire)
+}
+
+// SignPublish 返回推流的 url 查询字符串
+func (t *Token) SignPublish(app, stream, ip string, expire time.Duration) string {
+	return t.Sign(ActionPublish, app, stream, ip, expire)
+}
+
+// Revoke 撤销签名，token 是查询字符串中 QueryToken 的值
+func (t *Token) Revoke(ctx context.Context, token string, expire time.Time) error {
+	return t.opt.Revoker.Revoke(ctx, token, expire)
+}
+
+// Verify 验证签名，params 是 url 查询字符串，schema 用于选择策略
+func (t *Token) Verify(ctx context.Context, action, schema, app, stream, ip, params string) error {
+	p := t.opt.Policies[schema]
+	if p == nil {
+		p = t.opt.DefaultPolicy
+	}
+	if p.Anonymous {
+		return nil
+	}
+	// 参数
+	q, _ := url.ParseQuery(params)
+	token := q.Get(QueryToken)
+	exp := q.Get(QueryExpire)
+	if token == "" || exp == "" {
+		return ErrMissing
+	}
+	// 有效期
+	n, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	expire := time.Unix(n, 0)
+	now := time.Now()
+	if now.After(expire) {
+		return ErrExpired
+	}
+	if p.MaxExpire > 0 && expire.Sub(now) > p.MaxExpire {
+		return ErrInvalid
+	}
+	// 签名
+	if !p.BindIP {
+		ip = ""
+	}
+	if !hmac.Equal([]byte(token), []byte(t.sign(action, app, stream, exp, ip))) {
+		return ErrInvalid
+	}
+	// 撤销
+	revoked, err := t.opt.Revoker.IsRevoked(ctx, token)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrRevoked
+	}
+	return nil
+}
+
+// sign 返回签名
+func (t *Token) sign(action, app, stream, expire, ip string) string {
+	h := hmac.New(sha256.New, []byte(t.opt.Secret))
+	h.Write([]byte(action))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(app))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(stream))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(expire))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(ip))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// OnPlay 实现 zlm.PlayHandler
+func (t *Token) OnPlay(ctx context.Context, req *zlm.OnPlayReq, res *zlm.CodeMsg) {
+	if err := t.Verify(ctx, ActionPlay, req.Schema, req.App, req.Stream, req.IP, req.Params); err != nil {
+		res.Code = zlm.CodeDeny
+		res.Msg = err.Error()
+		return
+	}
+	if t.opt.Play != nil {
+		t.opt.Play.OnPlay(ctx, req, res)
+	}
+}
+
+// OnPublish 实现 zlm.PublishHandler
+func (t *Token) OnPublish(ctx context.Context, req *zlm.OnPublishReq, res *zlm.OnPublishRes) {
+	if err := t.Verify(ctx, ActionPublish, req.Schema, req.App, req.Stream, req.IP, req.Params); err != nil {
+		res.Code = zlm.CodeDeny
+		res.Msg = err.Error()
+		return
+	}
+	if t.opt.Publish != nil {
+		t.opt.Publish.OnPublish(ctx, req, res)
+	}
+}
@@ -0,0 +1,89 @@
+package token
+
+import (
+	"context"
+	"goutil/zlm"
+	"goutil/zlm/zlmtest"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_Verify(t *testing.T) {
+	tk := New(&Option{
+		Secret: "test",
+		Policies: map[string]*Policy{
+			zlm.HLS:  {BindIP: true, MaxExpire: time.Minute},
+			zlm.RTSP: {BindIP: false},
+			zlm.RTC:  {Anonymous: true},
+		},
+	})
+	ctx := context.Background()
+	play := tk.SignPlay("live", "a", "10.0.0.1", 0)
+	// 过期，有效期在签名之前检查
+	q, _ := url.ParseQuery(play)
+	q.Set(QueryExpire, strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10))
+	expired := q.Encode()
+	tests := []struct {
+		name   string
+		action string
+		schema string
+		stream string
+		ip     string
+		params string
+		want   error
+	}{
+		{"ok", ActionPlay, zlm.RTMP, "a", "10.0.0.1", play, nil},
+		{"missing", ActionPlay, zlm.RTMP, "a", "10.0.0.1", "", ErrMissing},
+		{"expired", ActionPlay, zlm.RTMP, "a", "10.0.0.1", expired, ErrExpired},
+		{"other ip", ActionPlay, zlm.RTMP, "a", "10.0.0.2", play, ErrInvalid},
+		{"not bind ip", ActionPlay, zlm.RTSP, "a", "10.0.0.2", tk.SignPlay("live", "a", "", 0), nil},
+		{"other stream", ActionPlay, zlm.RTMP, "b", "10.0.0.1", play, ErrInvalid},
+		{"other action", ActionPublish, zlm.RTMP, "a", "10.0.0.1", play, ErrInvalid},
+		{"max expire", ActionPlay, zlm.HLS, "a", "10.0.0.1", play, ErrInvalid},
+		{"short expire", ActionPlay, zlm.HLS, "a", "10.0.0.1", tk.SignPlay("live", "a", "10.0.0.1", time.Minute/2), nil},
+		{"anonymous", ActionPlay, zlm.RTC, "a", "10.0.0.1", "", nil},
+	}
+	for _, tt := range tests {
+		if err := tk.Verify(ctx, tt.action, tt.schema, "live", tt.stream, tt.ip, tt.params); err != tt.want {
+			t.Errorf("%s got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// 撤销
+	q, _ = url.ParseQuery(play)
+	if err := tk.Revoke(ctx, q.Get(QueryToken), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tk.Verify(ctx, ActionPlay, zlm.RTMP, "live", "a", "10.0.0.1", play); err != ErrRevoked {
+		t.Fatalf("revoked got %v, want %v", err, ErrRevoked)
+	}
+}
+
+func Test_Hook(t *testing.T) {
+	tk := New(&Option{Secret: "test"})
+	hook := httptest.NewServer(&zlm.Hook{Play: tk, Publish: tk})
+	defer hook.Close()
+	ser := zlmtest.NewServer(&zlmtest.Option{HookURL: hook.URL})
+	defer ser.Close()
+	// 推流
+	res, err := ser.FirePublish(zlm.RTMP, "live", "a", tk.SignPublish("live", "a", "10.0.0.1", 0), "10.0.0.2")
+	if err != nil || res.Code != zlm.CodeDeny || ser.Stream("live", "a") != nil {
+		t.Fatalf("publish from other ip %v %v", res, err)
+	}
+	res, err = ser.FirePublish(zlm.RTMP, "live", "a", tk.SignPublish("live", "a", "10.0.0.1", 0), "10.0.0.1")
+	if err != nil || res.Code != zlm.CodeOK || ser.Stream("live", "a") == nil {
+		t.Fatalf("publish %v %v", res, err)
+	}
+	// 播放
+	play := tk.SignPlay("live", "a", "10.0.0.3", 0)
+	if res, err := ser.FirePlay(zlm.RTMP, "live", "a", play, "10.0.0.3"); err != nil || res.Code != zlm.CodeOK {
+		t.Fatalf("play %v %v", res, err)
+	}
+	q, _ := url.ParseQuery(play)
+	tk.Revoke(context.Background(), q.Get(QueryToken), time.Now().Add(time.Hour))
+	if res, err := ser.FirePlay(zlm.RTMP, "live", "a", play, "10.0.0.3"); err != nil || res.Code != zlm.CodeDeny {
+		t.Fatalf("play revoked %v %v", res, err)
+	}
+}
@@ -0,0 +1,45 @@
+package zlm
+
+import (
+	"context"
+)
+
+// WebRTC 信令的类型
+const (
+	WebRTCTypePlay = "play"
+	WebRTCTypePush = "push"
+	WebRTCTypeEcho = "echo"
+)
+
+// WebRTCReq 是 WebRTC 的参数
+type WebRTCReq struct {
+	// 流应用
+	App string `query:"app"`
+	// 流标识
+	Stream string `query:"stream"`
+	// 类型，WebRTCTypeXXX
+	Type string `query:"type"`
+	// 客户端的 offer sdp ，作为 body 提交
+	Offer string `query:"-"`
+}
+
+// WebRTCRes 是 WebRTC 的返回值
+type WebRTCRes struct {
+	CodeMsg
+	// 连接标识
+	ID string `json:"id"`
+	// 服务端的 answer sdp
+	SDP string `json:"sdp"`
+	// 固定是 answer
+	Type string `json:"type"`
+}
+
+const (
+	WebRTCPath = apiPathPrefix + "/webrtc"
+)
+
+// WebRTC 调用 /index/api/webrtc ，提交 offer 返回 answer ，
+// 用于 webrtc 播放和推流的信令
+func WebRTC(ctx context.Context, ser Server, req *WebRTCReq, res *WebRTCRes) error {
+	return RequestBody(ctx, ser, WebRTCPath, req, "text/plain;charset=utf-8", []byte(req.Offer), res)
+}
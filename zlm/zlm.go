@@ -1,12 +1,8 @@
 package zlm
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	ghttp "goutil/http"
-	"net/http"
 	"net/url"
 )
 
@@ -28,6 +24,8 @@ const (
 	HLS  = "hls"
 	TS   = "ts"
 	FMP4 = "fmp4"
+	// webrtc
+	RTC = "rtc"
 )
 
 const (
@@ -116,27 +114,13 @@ type Config interface {
 }
 
 var (
-	// Request 请求函数，可以替换
-	Request = func(ctx context.Context, ser Server, apiPath string, query any, data ResponseData) error {
-		return ghttp.JSONRequest(ctx, http.DefaultClient, http.MethodGet,
-			ser.BaseURL()+apiPath, ghttp.Query(query, NewRequestQuery(ser)), nil,
-			func(res *http.Response) error {
-				// 响应码
-				if res.StatusCode != http.StatusOK {
-					return ghttp.StatusError(res.StatusCode)
-				}
-				// 解析
-				if err := json.NewDecoder(res.Body).Decode(data); err != nil {
-					return err
-				}
-				// 错误码
-				if data.GetCode() != CodeOK {
-					return fmt.Errorf("code:%d, msg:%s", data.GetCode(), data.GetMsg())
-				}
-				//
-				return nil
-			}, nil)
-	}
+	// DefaultClient 是默认的 Client ，不重试不熔断
+	DefaultClient = NewClient(&ClientOption{})
+	// Request 请求函数，可以替换，比如使用 NewClient 的 Client.Request
+	Request = DefaultClient.Request
+	// RequestBody 使用 body 提交数据的请求函数，可以替换，
+	// 比如使用 NewClient 的 Client.RequestBody
+	RequestBody = DefaultClient.RequestBody
 )
 
 // NewRequestQuery 填充 secret 和 vhost 返回
@@ -0,0 +1,37 @@
+package zlm_test
+
+import (
+	"context"
+	"goutil/zlm"
+	"goutil/zlm/zlmtest"
+	"testing"
+)
+
+// Request 曾经把响应的回调传给了 JSONRequest 的 data 参数，
+// 每一次调用都会因为无法编码函数而失败
+func Test_Request(t *testing.T) {
+	ser := zlmtest.NewServer(nil)
+	defer ser.Close()
+	if err := ser.AddStream("live", "test"); err != nil {
+		t.Fatal(err)
+	}
+	for _, post := range []bool{false, true} {
+		c := zlm.NewClient(&zlm.ClientOption{Post: post})
+		var res zlm.GetMediaListRes
+		err := c.Request(context.Background(), ser, zlm.GetMediaListPath, &zlm.GetMediaListReq{App: "live"}, &res)
+		if err != nil {
+			t.Fatalf("post %v %v", post, err)
+		}
+		if len(res.Data) < 1 || res.Data[0].Stream != "test" {
+			t.Fatalf("post %v data %v", post, res.Data)
+		}
+	}
+	// 包的 Request
+	var res zlm.GetMediaListRes
+	if err := zlm.Request(context.Background(), ser, zlm.GetMediaListPath, &zlm.GetMediaListReq{}, &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Data) < 1 {
+		t.Fatal("empty data")
+	}
+}
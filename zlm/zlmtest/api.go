@@ -0,0 +1,459 @@
+package zlmtest
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"goutil/zlm"
+	"net/http"
+	"strconv"
+)
+
+// initMux 注册接口
+func (s *Server) initMux() {
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc(zlm.GetServerConfigPath, s.getServerConfig)
+	s.mux.HandleFunc(zlm.GetMediaListPath, s.getMediaList)
+	s.mux.HandleFunc(zlm.GetMediaInfoPath, s.getMediaInfo)
+	s.mux.HandleFunc(zlm.CloseStreamsPath, s.closeStreams)
+	s.mux.HandleFunc(zlm.OpenRTPServerPath, s.openRTPServer)
+	s.mux.HandleFunc(zlm.CloseRtpServerPath, s.closeRTPServer)
+	s.mux.HandleFunc(zlm.ListRTPServerPath, s.listRTPServer)
+	s.mux.HandleFunc(zlm.GetRTPInfoPath, s.getRTPInfo)
+	s.mux.HandleFunc(zlm.StartRecordPath, s.startRecord)
+	s.mux.HandleFunc(zlm.StopRecordPath, s.stopRecord)
+	s.mux.HandleFunc(zlm.DeleteRecordDirectoryPath, s.deleteRecordDirectory)
+	s.mux.HandleFunc(zlm.AddStreamProxyPath, s.addStreamProxy)
+	s.mux.HandleFunc(zlm.AddStreamPusherProxyPath, s.addStreamPusherProxy)
+	s.mux.HandleFunc(zlm.GetProxyPusherInfoPath, s.getProxyPusherInfo)
+	s.mux.HandleFunc(zlm.DelStreamPusherProxyPath, s.delStreamPusherProxy)
+	s.mux.HandleFunc(zlm.StartSendRtpPath, s.startSendRTP)
+	s.mux.HandleFunc(zlm.StartSendRtpPassivePath, s.startSendRTPPassive)
+	s.mux.HandleFunc(zlm.StartSendRtpTalkPath, s.startSendRTPTalk)
+	s.mux.HandleFunc(zlm.StopSendRTPPath, s.stopSendRTP)
+	s.mux.HandleFunc(zlm.ListRTPSenderPath, s.listRTPSender)
+	s.mux.HandleFunc(zlm.GetStatisticPath, s.getStatistic)
+}
+
+// getServerConfig 处理 /index/api/getServerConfig
+func (s *Server) getServerConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"code": zlm.CodeOK,
+		"data": []map[string]string{
+			{
+				"general.mediaServerId": s.opt.ID,
+				"rtp_proxy.port_range":  strconv.Itoa(s.opt.RTPPortMin) + "-" + strconv.Itoa(s.opt.RTPPortMax),
+			},
+		},
+	})
+}
+
+// getMediaList 处理 /index/api/getMediaList
+func (s *Server) getMediaList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	schema, app, stream := q.Get("schema"), q.Get("app"), q.Get("stream")
+	data := make([]map[string]any, 0)
+	s.lock.Lock()
+	for _, st := range s.streams {
+		if (app != "" && app != st.App) || (stream != "" && stream != st.Stream) {
+			continue
+		}
+		for _, sc := range s.opt.Schemas {
+			if schema == "" || schema == sc {
+				data = append(data, s.mediaData(st, sc))
+			}
+		}
+	}
+	s.lock.Unlock()
+	writeJSON(w, map[string]any{"code": zlm.CodeOK, "data": data})
+}
+
+// getMediaInfo 处理 /index/api/getMediaInfo
+func (s *Server) getMediaInfo(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	schema := q.Get("schema")
+	if schema == "" {
+		schema = s.opt.Schemas[0]
+	}
+	s.lock.Lock()
+	st := s.streams[streamKey(q.Get("app"), q.Get("stream"))]
+	var data map[string]any
+	if st != nil {
+		data = s.mediaData(st, schema)
+	}
+	s.lock.Unlock()
+	if data == nil {
+		writeJSON(w, map[string]any{"code": zlm.CodeOK, "online": false})
+		return
+	}
+	data["code"] = zlm.CodeOK
+	data["online"] = true
+	writeJSON(w, data)
+}
+
+// closeStreams 处理 /index/api/close_streams
+func (s *Server) closeStreams(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	app, stream := q.Get("app"), q.Get("stream")
+	var closed []*Stream
+	s.lock.Lock()
+	for _, st := range s.streams {
+		if (app == "" || app == st.App) && (stream == "" || stream == st.Stream) {
+			closed = append(closed, st)
+		}
+	}
+	s.lock.Unlock()
+	for _, st := range closed {
+		s.RemoveStream(st.App, st.Stream)
+	}
+	writeJSON(w, &zlm.CloseStreamsRes{
+		CountHit:    len(closed),
+		CountClosed: len(closed),
+	})
+}
+
+// openRTPServer 处理 /index/api/openRtpServer
+func (s *Server) openRTPServer(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	id := q.Get("stream_id")
+	if id == "" {
+		writeCode(w, CodeInvalidArgs, "stream_id is empty")
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.rtps[id]; ok {
+		writeCode(w, CodeInvalidArgs, "该stream_id已存在")
+		return
+	}
+	// 已经有流了，和 zlm 一样返回错误
+	if _, ok := s.streams[streamKey("rtp", id)]; ok {
+		writeCode(w, CodeInvalidArgs, "该stream_id已存在")
+		return
+	}
+	// 端口
+	port := queryInt(r, "port")
+	if port > 0 {
+		if _, ok := s.ports[port]; ok {
+			writeCode(w, CodeOtherFailed, "port in use")
+			return
+		}
+		s.ports[port] = id
+	} else {
+		port = s.allocPort(id)
+		if port == 0 {
+			writeCode(w, CodeOtherFailed, "no available port")
+			return
+		}
+	}
+	s.rtps[id] = &RTPServer{
+		StreamID: id,
+		Port:     port,
+		TCPMode:  q.Get("tcp_mode"),
+		SSRC:     q.Get("ssrc"),
+	}
+	writeJSON(w, &zlm.OpenRTPServerRes{Port: port})
+}
+
+// closeRTPServer 处理 /index/api/closeRtpServer
+func (s *Server) closeRTPServer(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	ok := s.closeRTP(r.URL.Query().Get("stream_id"))
+	s.lock.Unlock()
+	var res zlm.CloseRTPServerRes
+	if ok {
+		res.Hit = 1
+	}
+	writeJSON(w, &res)
+}
+
+// listRTPServer 处理 /index/api/listRtpServer
+func (s *Server) listRTPServer(w http.ResponseWriter, r *http.Request) {
+	var res zlm.ListRTPServerRes
+	res.Data = make([]*zlm.RTPServerData, 0)
+	s.lock.Lock()
+	for _, v := range s.rtps {
+		res.Data = append(res.Data, &zlm.RTPServerData{Port: v.Port, StreamID: v.StreamID})
+	}
+	s.lock.Unlock()
+	writeJSON(w, &res)
+}
+
+// getRTPInfo 处理 /index/api/getRtpInfo
+func (s *Server) getRTPInfo(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("stream_id")
+	var res zlm.GetRTPInfoRes
+	s.lock.Lock()
+	if v := s.rtps[id]; v != nil {
+		if _, ok := s.streams[streamKey("rtp", id)]; ok {
+			res.Exist = true
+			res.LocalIP = "127.0.0.1"
+			res.LocalPort = v.Port
+			res.PeerIP = "127.0.0.1"
+		}
+	}
+	s.lock.Unlock()
+	writeJSON(w, &res)
+}
+
+// setRecord 设置录制状态
+func (s *Server) setRecord(w http.ResponseWriter, r *http.Request, on bool) {
+	q := r.URL.Query()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.streams[streamKey(q.Get("app"), q.Get("stream"))]
+	if st == nil {
+		writeCode(w, CodeOtherFailed, "can not find the stream")
+		return
+	}
+	if zlm.RecordFileType(q.Get("type")) == zlm.RecordFileTypeHLS {
+		st.RecordingHLS = on
+	} else {
+		st.RecordingMP4 = on
+	}
+	writeJSON(w, &zlm.StartRecordRes{Result: true})
+}
+
+// startRecord 处理 /index/api/startRecord
+func (s *Server) startRecord(w http.ResponseWriter, r *http.Request) {
+	s.setRecord(w, r, true)
+}
+
+// stopRecord 处理 /index/api/stopRecord
+func (s *Server) stopRecord(w http.ResponseWriter, r *http.Request) {
+	s.setRecord(w, r, false)
+}
+
+// deleteRecordDirectory 处理 /index/api/deleteRecordDirectory ，
+// name 为空删除整个日期目录，没有删除任何文件和 zlm 一样返回 -1
+func (s *Server) deleteRecordDirectory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	app, stream, period, name := q.Get("app"), q.Get("stream"), q.Get("period"), q.Get("name")
+	if app == "" || stream == "" || period == "" {
+		writeCode(w, CodeInvalidArgs, "app/stream/period is empty")
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := 0
+	for k, f := range s.records {
+		if f.App == app && f.Stream == stream && f.Period == period &&
+			(name == "" || f.Name == name) {
+			delete(s.records, k)
+			n++
+		}
+	}
+	if n < 1 {
+		writeCode(w, CodeOtherFailed, "delete record directory failed")
+		return
+	}
+	writeJSON(w, &zlm.DeleteRecordDirectoryRes{})
+}
+
+// addStreamProxy 处理 /index/api/addStreamProxy ，直接注册流
+func (s *Server) addStreamProxy(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	app, stream, u := q.Get("app"), q.Get("stream"), q.Get("url")
+	if app == "" || stream == "" || u == "" {
+		writeCode(w, CodeInvalidArgs, "app/stream/url is empty")
+		return
+	}
+	key := streamKey(app, stream)
+	s.lock.Lock()
+	_, ok := s.streams[key]
+	if !ok {
+		s.proxies[zlm.VHost+"/"+key] = key
+	}
+	s.lock.Unlock()
+	if ok {
+		writeCode(w, CodeOtherFailed, "This stream already exists")
+		return
+	}
+	if err := s.addStream(&Stream{
+		App:        app,
+		Stream:     stream,
+		OriginType: zlm.OriginTypePull,
+		OriginURL:  u,
+	}); err != nil {
+		writeCode(w, CodeOtherFailed, err.Error())
+		return
+	}
+	var res zlm.AddStreamProxyRes
+	res.Data.Key = zlm.VHost + "/" + key
+	writeJSON(w, &res)
+}
+
+// addStreamPusherProxy 处理 /index/api/addStreamPusherProxy ，源流需要存在
+func (s *Server) addStreamPusherProxy(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	p := &Pusher{
+		Schema: q.Get("schema"),
+		App:    q.Get("app"),
+		Stream: q.Get("stream"),
+		DstURL: q.Get("dst_url"),
+	}
+	if p.Schema == "" || p.App == "" || p.Stream == "" || p.DstURL == "" {
+		writeCode(w, CodeInvalidArgs, "schema/app/stream/dst_url is empty")
+		return
+	}
+	sum := md5.Sum([]byte(p.DstURL))
+	p.Key = p.Schema + "/" + zlm.VHost + "/" + streamKey(p.App, p.Stream) + "/" + hex.EncodeToString(sum[:])
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.streams[streamKey(p.App, p.Stream)]; !ok {
+		writeCode(w, CodeOtherFailed, "can not find the source stream")
+		return
+	}
+	if _, ok := s.pushers[p.Key]; ok {
+		writeCode(w, CodeOtherFailed, "This stream already exists")
+		return
+	}
+	s.pushers[p.Key] = p
+	var res zlm.AddStreamPusherProxyRes
+	res.Data.Key = p.Key
+	writeJSON(w, &res)
+}
+
+// getProxyPusherInfo 处理 /index/api/getProxyPusherInfo
+func (s *Server) getProxyPusherInfo(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	p := s.pushers[r.URL.Query().Get("key")]
+	var res zlm.GetProxyPusherInfoRes
+	if p != nil {
+		res.Data.URL = p.DstURL
+		res.Data.Status = p.Status
+	}
+	s.lock.Unlock()
+	if p == nil {
+		writeCode(w, CodeOtherFailed, "can not find pusher")
+		return
+	}
+	writeJSON(w, &res)
+}
+
+// delStreamPusherProxy 处理 /index/api/delStreamPusherProxy
+func (s *Server) delStreamPusherProxy(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	s.lock.Lock()
+	_, ok := s.pushers[key]
+	delete(s.pushers, key)
+	s.lock.Unlock()
+	var res zlm.DelStreamPusherProxyRes
+	res.Data.Flag = ok
+	writeJSON(w, &res)
+}
+
+// addSender 添加发送，返回本地端口，失败会响应错误
+func (s *Server) addSender(w http.ResponseWriter, sd *Sender) bool {
+	key := streamKey(sd.App, sd.Stream) + "/" + sd.SSRC
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.streams[streamKey(sd.App, sd.Stream)]; !ok {
+		writeCode(w, CodeOtherFailed, "can not find the source stream")
+		return false
+	}
+	if _, ok := s.senders[key]; ok {
+		writeCode(w, CodeOtherFailed, "ssrc already exists")
+		return false
+	}
+	sd.LocalPort = s.allocPort(key)
+	if sd.LocalPort == 0 {
+		writeCode(w, CodeOtherFailed, "no available port")
+		return false
+	}
+	s.senders[key] = sd
+	return true
+}
+
+// startSendRTP 处理 /index/api/startSendRtp
+func (s *Server) startSendRTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sd := &Sender{
+		App:     q.Get("app"),
+		Stream:  q.Get("stream"),
+		SSRC:    q.Get("ssrc"),
+		DstIP:   q.Get("dst_url"),
+		DstPort: q.Get("dst_port"),
+	}
+	if s.addSender(w, sd) {
+		writeJSON(w, &zlm.StartSendRTPRes{LocalPort: sd.LocalPort})
+	}
+}
+
+// startSendRTPPassive 处理 /index/api/startSendRtpPassive
+func (s *Server) startSendRTPPassive(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sd := &Sender{
+		App:     q.Get("app"),
+		Stream:  q.Get("stream"),
+		SSRC:    q.Get("ssrc"),
+		Passive: true,
+	}
+	if s.addSender(w, sd) {
+		writeJSON(w, &zlm.StartSendRTPPassiveRes{LocalPort: sd.LocalPort})
+	}
+}
+
+// startSendRTPTalk 处理 /index/api/startSendRtpTalk
+func (s *Server) startSendRTPTalk(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.lock.Lock()
+	_, ok := s.rtps[q.Get("recv_stream_id")]
+	s.lock.Unlock()
+	if !ok {
+		writeCode(w, CodeOtherFailed, "can not find the recv stream")
+		return
+	}
+	sd := &Sender{
+		App:    q.Get("app"),
+		Stream: q.Get("stream"),
+		SSRC:   q.Get("ssrc"),
+	}
+	if s.addSender(w, sd) {
+		writeJSON(w, &zlm.StartSendRTPTalkRes{LocalPort: sd.LocalPort})
+	}
+}
+
+// stopSendRTP 处理 /index/api/stopSendRtp ，ssrc 为空停止所有的
+func (s *Server) stopSendRTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	app, stream, ssrc := q.Get("app"), q.Get("stream"), q.Get("ssrc")
+	hit := 0
+	s.lock.Lock()
+	for k, sd := range s.senders {
+		if sd.App == app && sd.Stream == stream && (ssrc == "" || ssrc == sd.SSRC) {
+			delete(s.senders, k)
+			delete(s.ports, sd.LocalPort)
+			hit++
+		}
+	}
+	s.lock.Unlock()
+	if hit < 1 {
+		writeCode(w, CodeOtherFailed, "该流不存在")
+		return
+	}
+	writeJSON(w, &zlm.CodeMsg{})
+}
+
+// listRTPSender 处理 /index/api/listRtpSender
+func (s *Server) listRTPSender(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var res zlm.ListRTPSenderRes
+	res.Data = make([]string, 0)
+	s.lock.Lock()
+	for _, sd := range s.senders {
+		if sd.App == q.Get("app") && sd.Stream == q.Get("stream") {
+			res.Data = append(res.Data, sd.SSRC)
+		}
+	}
+	s.lock.Unlock()
+	writeJSON(w, &res)
+}
+
+// getStatistic 处理 /index/api/getStatistic
+func (s *Server) getStatistic(w http.ResponseWriter, r *http.Request) {
+	var res zlm.GetStatisticRes
+	s.lock.Lock()
+	res.Data.MediaSource = len(s.streams)
+	s.lock.Unlock()
+	writeJSON(w, &res)
+}
@@ -0,0 +1,127 @@
+package zlmtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	ghttp "goutil/http"
+	"goutil/zlm"
+	"net/http"
+)
+
+// fire 提交回调，res 是回调的响应，HookURL 为空直接返回
+func (s *Server) fire(name string, data, res any) error {
+	if s.opt.HookURL == "" {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		return err
+	}
+	return ghttp.Request(context.Background(), http.DefaultClient, http.MethodPost,
+		s.opt.HookURL+"/"+name, nil,
+		map[string]string{"Content-Type": ghttp.ContentTypeJSON}, &buf,
+		func(r *http.Response) error {
+			if r.StatusCode != http.StatusOK {
+				return ghttp.StatusError(r.StatusCode)
+			}
+			if res == nil {
+				return nil
+			}
+			return json.NewDecoder(r.Body).Decode(res)
+		})
+}
+
+// fireStreamChanged 触发每一个协议的 on_stream_changed
+func (s *Server) fireStreamChanged(st *Stream, regist bool) error {
+	for _, schema := range s.opt.Schemas {
+		data := s.mediaData(st, schema)
+		data["mediaServerId"] = s.opt.ID
+		data["regist"] = regist
+		var res zlm.CodeMsg
+		if err := s.fire(zlm.HookOnStreamChanged, data, &res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FireStreamNoneReader 触发 on_stream_none_reader ，
+// 响应 close 则注销流
+func (s *Server) FireStreamNoneReader(app, stream string) (*zlm.OnStreamNoneReaderRes, error) {
+	var res zlm.OnStreamNoneReaderRes
+	err := s.fire(zlm.HookOnStreamNoneReader, &zlm.OnStreamNoneReaderReq{
+		VHost:         zlm.VHost,
+		MediaServerID: s.opt.ID,
+		Schema:        s.opt.Schemas[0],
+		App:           app,
+		Stream:        stream,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Close {
+		err = s.RemoveStream(app, stream)
+	}
+	return &res, err
+}
+
+// FireStreamNotFound 触发 on_stream_not_found
+func (s *Server) FireStreamNotFound(schema, app, stream, params string) (*zlm.CodeMsg, error) {
+	var res zlm.CodeMsg
+	err := s.fire(zlm.HookOnStreamNotFound, &zlm.OnStreamNotFoundReq{
+		VHost:         zlm.VHost,
+		MediaServerID: s.opt.ID,
+		Schema:        schema,
+		App:           app,
+		Stream:        stream,
+		Params:        params,
+	}, &res)
+	return &res, err
+}
+
+// FirePlay 触发 on_play ，模拟客户端 ip 播放
+func (s *Server) FirePlay(schema, app, stream, params, ip string) (*zlm.CodeMsg, error) {
+	var res zlm.CodeMsg
+	err := s.fire(zlm.HookOnPlay, &zlm.OnPlayReq{
+		VHost:         zlm.VHost,
+		MediaServerID: s.opt.ID,
+		Schema:        schema,
+		App:           app,
+		Stream:        stream,
+		Params:        params,
+		IP:            ip,
+	}, &res)
+	return &res, err
+}
+
+// FirePublish 触发 on_publish ，模拟客户端 ip 推流，
+// 允许则注册流
+func (s *Server) FirePublish(schema, app, stream, params, ip string) (*zlm.OnPublishRes, error) {
+	var res zlm.OnPublishRes
+	err := s.fire(zlm.HookOnPublish, &zlm.OnPublishReq{
+		VHost:         zlm.VHost,
+		MediaServerID: s.opt.ID,
+		Schema:        schema,
+		App:           app,
+		Stream:        stream,
+		Params:        params,
+		IP:            ip,
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+	if res.Code == zlm.CodeOK {
+		err = s.AddStream(app, stream)
+	}
+	return &res, err
+}
+
+// FireServerKeepalive 触发 on_server_keepalive
+func (s *Server) FireServerKeepalive() error {
+	var res zlm.CodeMsg
+	return s.fire(zlm.HookOnServerKeepalive, &zlm.OnServerKeepaliveReq{
+		MediaServerID: s.opt.ID,
+		Data:          &zlm.OnServerKeepaliveDataModel{},
+	}, &res)
+}
@@ -0,0 +1,458 @@
+package zlmtest
+
+import (
+	"encoding/json"
+	"goutil/zlm"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 错误码，和 zlm 的一致
+const (
+	CodeException    = -400
+	CodeInvalidArgs  = -300
+	CodeUnauthorized = -100
+	CodeOtherFailed  = -1
+)
+
+const (
+	// DefaultID 是默认的服务标识
+	DefaultID = "zlmtest"
+	// DefaultSecret 是默认的访问密钥
+	DefaultSecret = "zlmtest"
+	// DefaultRTPPortMin 是默认的 rtp 端口范围
+	DefaultRTPPortMin = 30000
+	DefaultRTPPortMax = 30500
+)
+
+// Option 是 NewServer 的参数
+type Option struct {
+	// 服务标识，默认 DefaultID
+	ID string
+	// 访问密钥，默认 DefaultSecret
+	Secret string
+	// 回调的地址，比如 http://127.0.0.1:8080/zlm ，
+	// 会在后面加上 /on_stream_changed 之类，空则不回调
+	HookURL string
+	// 流注册的协议，默认 zlm.RTSP/zlm.RTMP
+	Schemas []string
+	// rtp 端口的范围，默认 DefaultRTPPortMin/DefaultRTPPortMax
+	RTPPortMin int
+	RTPPortMax int
+}
+
+// Server 是进程内的假 zlm ，实现 zlm.Server ，
+// 用于离线测试调用 zlm 接口和处理回调的逻辑
+type Server struct {
+	opt Option
+	ser *httptest.Server
+	// 接口
+	mux *http.ServeMux
+	// 数据
+	lock    sync.Mutex
+	streams map[string]*Stream
+	rtps    map[string]*RTPServer
+	ports   map[int]string
+	senders map[string]*Sender
+	pushers map[string]*Pusher
+	proxies map[string]string
+	// key 是 app/stream/period/name
+	records map[string]*RecordFile
+	// 调用的次数，key 是接口路径
+	calls map[string]int
+}
+
+// Stream 是一个流
+type Stream struct {
+	App    string
+	Stream string
+	// 观看人数
+	Readers int64
+	// 来源
+	OriginType int64
+	OriginURL  string
+	// 录制状态
+	RecordingMP4 bool
+	RecordingHLS bool
+	// 创建时间
+	CreateStamp int64
+}
+
+// RTPServer 是 openRtpServer 打开的端口
+type RTPServer struct {
+	StreamID string
+	Port     int
+	TCPMode  string
+	SSRC     string
+}
+
+// Sender 是 startSendRtp 之类的发送
+type Sender struct {
+	App       string
+	Stream    string
+	SSRC      string
+	DstIP     string
+	DstPort   string
+	LocalPort int
+	Passive   bool
+}
+
+// Pusher 是 addStreamPusherProxy 添加的推流
+type Pusher struct {
+	Key    string
+	Schema string
+	App    string
+	Stream string
+	DstURL string
+	// 推流状态，0 表示正常
+	Status int
+}
+
+// RecordFile 是一个录像文件
+type RecordFile struct {
+	App    string
+	Stream string
+	// 日期，比如 2020-01-01
+	Period string
+	// 文件名
+	Name string
+}
+
+// NewServer 启动并返回新的 Server
+func NewServer(opt *Option) *Server {
+	s := new(Server)
+	if opt != nil {
+		s.opt = *opt
+	}
+	if s.opt.ID == "" {
+		s.opt.ID = DefaultID
+	}
+	if s.opt.Secret == "" {
+		s.opt.Secret = DefaultSecret
+	}
+	if len(s.opt.Schemas) < 1 {
+		s.opt.Schemas = []string{zlm.RTSP, zlm.RTMP}
+	}
+	if s.opt.RTPPortMin <= 0 || s.opt.RTPPortMax < s.opt.RTPPortMin {
+		s.opt.RTPPortMin = DefaultRTPPortMin
+		s.opt.RTPPortMax = DefaultRTPPortMax
+	}
+	s.streams = make(map[string]*Stream)
+	s.rtps = make(map[string]*RTPServer)
+	s.ports = make(map[int]string)
+	s.senders = make(map[string]*Sender)
+	s.pushers = make(map[string]*Pusher)
+	s.proxies = make(map[string]string)
+	s.records = make(map[string]*RecordFile)
+	s.calls = make(map[string]int)
+	s.initMux()
+	s.ser = httptest.NewServer(s)
+	return s
+}
+
+// Close 关闭
+func (s *Server) Close() {
+	s.ser.Close()
+}
+
+// ID 返回服务标识
+func (s *Server) ID() string {
+	return s.opt.ID
+}
+
+// BaseURL 实现 zlm.Server
+func (s *Server) BaseURL() string {
+	return s.ser.URL
+}
+
+// Secret 实现 zlm.Server
+func (s *Server) Secret() string {
+	return s.opt.Secret
+}
+
+// VHost 实现 zlm.Server
+func (s *Server) VHost() string {
+	return zlm.VHost
+}
+
+// Calls 返回接口调用的次数，path 比如 zlm.OpenRTPServerPath
+func (s *Server) Calls(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.calls[path]
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	s.calls[r.URL.Path]++
+	s.lock.Unlock()
+	// 新版本支持 json body 提交参数，合并到查询字符串
+	if r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeCode(w, CodeInvalidArgs, err.Error())
+			return
+		}
+		q := r.URL.Query()
+		for k, v := range body {
+			q.Set(k, v)
+		}
+		r.URL.RawQuery = q.Encode()
+	}
+	// 密钥
+	if r.URL.Query().Get("secret") != s.opt.Secret {
+		writeJSON(w, &zlm.CodeMsg{Code: CodeUnauthorized, Msg: "secret错误"})
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// writeJSON 响应 json
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeCode 响应错误码
+func writeCode(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, &zlm.CodeMsg{Code: code, Msg: msg})
+}
+
+// streamKey 返回流的标识
+func streamKey(app, stream string) string {
+	return app + "/" + stream
+}
+
+// recordKey 返回录像文件的标识
+func recordKey(app, stream, period, name string) string {
+	return app + "/" + stream + "/" + period + "/" + name
+}
+
+// Stream 返回流的拷贝，不存在返回 nil
+func (s *Server) Stream(app, stream string) *Stream {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.streams[streamKey(app, stream)]
+	if st == nil {
+		return nil
+	}
+	c := *st
+	return &c
+}
+
+// Streams 返回所有流的拷贝
+func (s *Server) Streams() []*Stream {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ss := make([]*Stream, 0, len(s.streams))
+	for _, st := range s.streams {
+		c := *st
+		ss = append(ss, &c)
+	}
+	return ss
+}
+
+// RTPServer 返回 rtp 端口的拷贝，不存在返回 nil
+func (s *Server) RTPServer(streamID string) *RTPServer {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r := s.rtps[streamID]
+	if r == nil {
+		return nil
+	}
+	c := *r
+	return &c
+}
+
+// Senders 返回所有发送的拷贝
+func (s *Server) Senders() []*Sender {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ss := make([]*Sender, 0, len(s.senders))
+	for _, sd := range s.senders {
+		c := *sd
+		ss = append(ss, &c)
+	}
+	return ss
+}
+
+// Pushers 返回所有推流的拷贝
+func (s *Server) Pushers() []*Pusher {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ps := make([]*Pusher, 0, len(s.pushers))
+	for _, p := range s.pushers {
+		c := *p
+		ps = append(ps, &c)
+	}
+	return ps
+}
+
+// SetPusherStatus 设置推流的状态，用于模拟推流失败
+func (s *Server) SetPusherStatus(key string, status int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if p := s.pushers[key]; p != nil {
+		p.Status = status
+	}
+}
+
+// AddRecordFile 添加录像文件，用于测试 deleteRecordDirectory
+func (s *Server) AddRecordFile(app, stream, period, name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.records[recordKey(app, stream, period, name)] = &RecordFile{
+		App:    app,
+		Stream: stream,
+		Period: period,
+		Name:   name,
+	}
+}
+
+// RecordFiles 返回所有录像文件的拷贝
+func (s *Server) RecordFiles() []*RecordFile {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	fs := make([]*RecordFile, 0, len(s.records))
+	for _, f := range s.records {
+		c := *f
+		fs = append(fs, &c)
+	}
+	return fs
+}
+
+// SetReaders 设置流的观看人数
+func (s *Server) SetReaders(app, stream string, n int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if st := s.streams[streamKey(app, stream)]; st != nil {
+		st.Readers = n
+	}
+}
+
+// AddStream 注册流，并触发 on_stream_changed 回调
+func (s *Server) AddStream(app, stream string) error {
+	return s.addStream(&Stream{
+		App:        app,
+		Stream:     stream,
+		OriginType: zlm.OriginTypeRtmpPush,
+	})
+}
+
+// PushRTP 模拟设备向 openRtpServer 打开的端口推流，
+// 注册 rtp/streamID 并触发回调，端口不存在返回 false
+func (s *Server) PushRTP(streamID string) (bool, error) {
+	s.lock.Lock()
+	_, ok := s.rtps[streamID]
+	s.lock.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, s.addStream(&Stream{
+		App:        "rtp",
+		Stream:     streamID,
+		OriginType: zlm.OriginTypeRtpPush,
+	})
+}
+
+// RemoveStream 注销流，并触发 on_stream_changed 回调，
+// 同时清理流相关的 rtp 端口和发送
+func (s *Server) RemoveStream(app, stream string) error {
+	st := s.removeStream(app, stream)
+	if st == nil {
+		return nil
+	}
+	return s.fireStreamChanged(st, false)
+}
+
+// addStream 注册流，触发回调
+func (s *Server) addStream(st *Stream) error {
+	st.CreateStamp = time.Now().Unix()
+	s.lock.Lock()
+	s.streams[streamKey(st.App, st.Stream)] = st
+	s.lock.Unlock()
+	return s.fireStreamChanged(st, true)
+}
+
+// removeStream 删除流，返回删除的
+func (s *Server) removeStream(app, stream string) *Stream {
+	key := streamKey(app, stream)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	st := s.streams[key]
+	if st == nil {
+		return nil
+	}
+	delete(s.streams, key)
+	if app == "rtp" {
+		s.closeRTP(stream)
+	}
+	for k, sd := range s.senders {
+		if sd.App == app && sd.Stream == stream {
+			delete(s.senders, k)
+			delete(s.ports, sd.LocalPort)
+		}
+	}
+	for k, p := range s.pushers {
+		if p.App == app && p.Stream == stream {
+			delete(s.pushers, k)
+		}
+	}
+	for k, v := range s.proxies {
+		if v == key {
+			delete(s.proxies, k)
+		}
+	}
+	return st
+}
+
+// allocPort 分配 rtp 端口，没有返回 0 ，需要在锁中调用
+func (s *Server) allocPort(streamID string) int {
+	for p := s.opt.RTPPortMin; p <= s.opt.RTPPortMax; p++ {
+		if _, ok := s.ports[p]; !ok {
+			s.ports[p] = streamID
+			return p
+		}
+	}
+	return 0
+}
+
+// closeRTP 关闭 rtp 端口，需要在锁中调用
+func (s *Server) closeRTP(streamID string) bool {
+	r := s.rtps[streamID]
+	if r == nil {
+		return false
+	}
+	delete(s.rtps, streamID)
+	delete(s.ports, r.Port)
+	return true
+}
+
+// mediaData 返回 getMediaList 格式的数据
+func (s *Server) mediaData(st *Stream, schema string) map[string]any {
+	return map[string]any{
+		"vhost":            zlm.VHost,
+		"schema":           schema,
+		"app":              st.App,
+		"stream":           st.Stream,
+		"createStamp":      st.CreateStamp,
+		"isRecordingHLS":   st.RecordingHLS,
+		"isRecordingMP4":   st.RecordingMP4,
+		"totalReaderCount": st.Readers,
+		"originType":       st.OriginType,
+		"originUrl":        st.OriginURL,
+		"bytesSpeed":       0,
+		"tracks":           []any{},
+	}
+}
+
+// queryInt 返回整数参数，失败返回 0
+func queryInt(r *http.Request, name string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(r.URL.Query().Get(name)))
+	return n
+}
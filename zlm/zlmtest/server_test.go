@@ -0,0 +1,247 @@
+package zlmtest
+
+import (
+	"context"
+	"goutil/zlm"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// wrongSecret 使用错误的密钥
+type wrongSecret struct {
+	*Server
+}
+
+func (s wrongSecret) Secret() string {
+	return s.Server.Secret() + "x"
+}
+
+func Test_Secret(t *testing.T) {
+	ser := NewServer(&Option{Secret: "abc"})
+	defer ser.Close()
+	ctx := context.Background()
+	var res zlm.GetMediaListRes
+	if err := zlm.GetMediaList(ctx, ser, &zlm.GetMediaListReq{}, &res); err != nil {
+		t.Fatal(err)
+	}
+	err := zlm.GetMediaList(ctx, wrongSecret{ser}, &zlm.GetMediaListReq{}, &res)
+	if err == nil || res.Code != CodeUnauthorized {
+		t.Fatalf("wrong secret err %v code %d", err, res.Code)
+	}
+	if n := ser.Calls(zlm.GetMediaListPath); n != 2 {
+		t.Fatalf("calls %d", n)
+	}
+}
+
+func Test_OpenRTPServer(t *testing.T) {
+	ser := NewServer(&Option{RTPPortMin: 40000, RTPPortMax: 40001})
+	defer ser.Close()
+	ctx := context.Background()
+	open := func(stream, port string) (int, error) {
+		var res zlm.OpenRTPServerRes
+		err := zlm.OpenRTPServer(ctx, ser, &zlm.OpenRTPServerReq{Stream: stream, Port: port, SSRC: "0100000001"}, &res)
+		return res.Port, err
+	}
+	// 分配
+	port, err := open("a", "0")
+	if err != nil || port != 40000 {
+		t.Fatalf("open a port %d err %v", port, err)
+	}
+	if r := ser.RTPServer("a"); r == nil || r.Port != 40000 || r.SSRC != "0100000001" {
+		t.Fatalf("rtp server %v", r)
+	}
+	// 重复
+	if _, err := open("a", "0"); err == nil {
+		t.Fatal("open a again should fail")
+	}
+	// 指定端口被占用
+	if _, err := open("b", "40000"); err == nil {
+		t.Fatal("open b on used port should fail")
+	}
+	if port, err := open("b", "0"); err != nil || port != 40001 {
+		t.Fatalf("open b port %d err %v", port, err)
+	}
+	// 端口用完
+	if _, err := open("c", "0"); err == nil {
+		t.Fatal("open c should fail")
+	}
+	// 推流
+	if ok, err := ser.PushRTP("a"); !ok || err != nil {
+		t.Fatalf("push a %v %v", ok, err)
+	}
+	if ser.Stream("rtp", "a") == nil {
+		t.Fatal("stream rtp/a not found")
+	}
+	// 流已经存在
+	if err := ser.AddStream("rtp", "d"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := open("d", "0"); err == nil {
+		t.Fatal("open d with an existing stream should fail")
+	}
+	// 关闭
+	var res zlm.CloseRTPServerRes
+	if err := zlm.CloseRTPServer(ctx, ser, &zlm.CloseRTPServerReq{Stream: "b"}, &res); err != nil || res.Hit != 1 {
+		t.Fatalf("close b hit %d err %v", res.Hit, err)
+	}
+	if port, err := open("c", "0"); err != nil || port != 40001 {
+		t.Fatalf("open c port %d err %v", port, err)
+	}
+	if ser.RemoveStream("rtp", "a"); ser.RTPServer("a") != nil {
+		t.Fatal("rtp server a should be closed with the stream")
+	}
+	if ok, _ := ser.PushRTP("a"); ok {
+		t.Fatal("push a should fail after close")
+	}
+}
+
+func Test_GetMediaList(t *testing.T) {
+	ser := NewServer(nil)
+	defer ser.Close()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := ser.AddStream("live", strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ser.AddStream("vod", "0"); err != nil {
+		t.Fatal(err)
+	}
+	ser.SetReaders("live", "1", 5)
+	tests := []struct {
+		req  zlm.GetMediaListReq
+		want int
+	}{
+		// 每个流有 rtsp 和 rtmp 两个协议
+		{zlm.GetMediaListReq{}, 8},
+		{zlm.GetMediaListReq{App: "live"}, 6},
+		{zlm.GetMediaListReq{App: "live", Schema: zlm.RTMP}, 3},
+		{zlm.GetMediaListReq{App: "live", Stream: "1", Schema: zlm.RTSP}, 1},
+		{zlm.GetMediaListReq{App: "live", Stream: "9"}, 0},
+		{zlm.GetMediaListReq{Schema: zlm.HLS}, 0},
+	}
+	for _, tt := range tests {
+		var res zlm.GetMediaListRes
+		if err := zlm.GetMediaList(ctx, ser, &tt.req, &res); err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Data) != tt.want {
+			t.Errorf("%+v got %d, want %d", tt.req, len(res.Data), tt.want)
+		}
+	}
+	var res zlm.GetMediaListRes
+	zlm.GetMediaList(ctx, ser, &zlm.GetMediaListReq{App: "live", Stream: "1", Schema: zlm.RTSP}, &res)
+	if d := res.Data[0]; d.TotalReaderCount != 5 || d.VHost != zlm.VHost {
+		t.Errorf("data %+v", d)
+	}
+}
+
+func Test_DeleteRecordDirectory(t *testing.T) {
+	ser := NewServer(nil)
+	defer ser.Close()
+	ctx := context.Background()
+	ser.AddRecordFile("live", "a", "2020-01-01", "1.mp4")
+	ser.AddRecordFile("live", "a", "2020-01-01", "2.mp4")
+	ser.AddRecordFile("live", "a", "2020-01-02", "1.mp4")
+	ser.AddRecordFile("live", "b", "2020-01-01", "1.mp4")
+	del := func(stream, period, name string) error {
+		var res zlm.DeleteRecordDirectoryRes
+		return zlm.DeleteRecordDirectory(ctx, ser, &zlm.DeleteRecordDirectoryReq{
+			App: "live", Stream: stream, Period: period, Name: name,
+		}, &res)
+	}
+	// 单个文件
+	if err := del("a", "2020-01-01", "1.mp4"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(ser.RecordFiles()); n != 3 {
+		t.Fatalf("files %d, want 3", n)
+	}
+	// 不存在
+	if err := del("a", "2020-01-01", "1.mp4"); err == nil {
+		t.Fatal("delete a missing file should fail")
+	}
+	// 整个目录
+	if err := del("a", "2020-01-02", ""); err != nil {
+		t.Fatal(err)
+	}
+	fs := ser.RecordFiles()
+	if len(fs) != 2 {
+		t.Fatalf("files %d, want 2", len(fs))
+	}
+	for _, f := range fs {
+		if f.Period == "2020-01-02" {
+			t.Fatalf("file %+v should be deleted", f)
+		}
+	}
+}
+
+// hookRecorder 记录收到的回调
+type hookRecorder struct {
+	lock    sync.Mutex
+	changed []*zlm.OnStreamChangedReq
+}
+
+func (h *hookRecorder) OnStreamChanged(ctx context.Context, req *zlm.OnStreamChangedReq, res *zlm.CodeMsg) {
+	h.lock.Lock()
+	h.changed = append(h.changed, req)
+	h.lock.Unlock()
+}
+
+func Test_Hook(t *testing.T) {
+	rec := new(hookRecorder)
+	hook := httptest.NewServer(&zlm.Hook{
+		StreamChanged: rec,
+		Publish: zlm.PublishHandlerFunc(func(ctx context.Context, req *zlm.OnPublishReq, res *zlm.OnPublishRes) {
+			if req.IP != "127.0.0.1" {
+				res.Code = zlm.CodeDeny
+			}
+		}),
+		StreamNoneReader: zlm.StreamNoneReaderHandlerFunc(func(ctx context.Context, req *zlm.OnStreamNoneReaderReq, res *zlm.OnStreamNoneReaderRes) {
+			res.Close = req.Stream == "close"
+		}),
+	})
+	defer hook.Close()
+	ser := NewServer(&Option{HookURL: hook.URL, Schemas: []string{zlm.RTSP}})
+	defer ser.Close()
+	// 拒绝推流
+	res, err := ser.FirePublish(zlm.RTMP, "live", "deny", "", "10.0.0.1")
+	if err != nil || res.Code != zlm.CodeDeny || ser.Stream("live", "deny") != nil {
+		t.Fatalf("publish deny %v %v", res, err)
+	}
+	// 允许推流，注册流
+	for _, stream := range []string{"keep", "close"} {
+		res, err = ser.FirePublish(zlm.RTMP, "live", stream, "", "127.0.0.1")
+		if err != nil || res.Code != zlm.CodeOK || ser.Stream("live", stream) == nil {
+			t.Fatalf("publish %s %v %v", stream, res, err)
+		}
+	}
+	// 无人观看
+	for _, stream := range []string{"keep", "close"} {
+		nr, err := ser.FireStreamNoneReader("live", stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nr.Close != (stream == "close") || (ser.Stream("live", stream) == nil) == !nr.Close {
+			t.Fatalf("none reader %s close %v", stream, nr.Close)
+		}
+	}
+	// 注册两次，注销一次
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	if len(rec.changed) != 3 {
+		t.Fatalf("stream changed %d", len(rec.changed))
+	}
+	want := []struct {
+		stream string
+		regist bool
+	}{{"keep", true}, {"close", true}, {"close", false}}
+	for i, w := range want {
+		c := rec.changed[i]
+		if c.Stream != w.stream || c.Regist != w.regist || c.MediaServerID != DefaultID || c.Schema != zlm.RTSP {
+			t.Errorf("stream changed %d %+v", i, c)
+		}
+	}
+}